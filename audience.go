@@ -0,0 +1,223 @@
+package strut
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/modfin/strut/schema"
+	"github.com/modfin/strut/swag"
+)
+
+// SpecForAudience returns the spec with only the operations for the audience, see with.Audience,
+// leaving out paths and webhooks without any, and the components and tags only they use. The operations and
+// components of the returned Definition are copies, for it to be encoded while the spec is prepared again, but it
+// shares everything else, e.g. the info and servers, with s.Definition and is not to be modified
+func (s *Strut) SpecForAudience(name string) *swag.Definition {
+	specMu.Lock()
+	defer specMu.Unlock()
+	prepared := s.prepareSpec()
+
+	d := *prepared
+	d.Paths = pathsForAudience(prepared.Paths, name)
+	d.Webhooks = pathsForAudience(prepared.Webhooks, name)
+	d.Components = componentsUsed(&d)
+	d.Tags = tagsUsed(&d)
+	return &d
+}
+
+// componentsUsed returns the components of d referenced by its paths and webhooks, and by the components they
+// reference in turn
+func componentsUsed(d *swag.Definition) *swag.Components {
+	if d.Components == nil {
+		return nil
+	}
+	used := &swag.Components{
+		Schemas:         map[string]*schema.JSON{},
+		Responses:       map[string]*swag.OpResponse{},
+		Parameters:      map[string]*swag.Param{},
+		SecuritySchemes: map[string]*swag.SecurityScheme{},
+	}
+	var visit func(s *schema.JSON)
+	ref := func(ref string) {
+		name, ok := strings.CutPrefix(ref, "#/components/schemas/")
+		if s := d.Components.Schemas[name]; ok && s != nil && used.Schemas[name] == nil {
+			used.Schemas[name] = s.Clone()
+			visit(s)
+		}
+	}
+	visit = func(s *schema.JSON) {
+		schema.Walk(s, func(s *schema.JSON) {
+			ref(s.Ref)
+			if s.Discriminator != nil {
+				for _, mapped := range s.Discriminator.Mapping {
+					ref(mapped)
+				}
+			}
+		})
+	}
+	security := func(requirements []swag.SecurityRequirement) {
+		for _, requirement := range requirements {
+			for name := range requirement {
+				if scheme := d.Components.SecuritySchemes[name]; scheme != nil {
+					used.SecuritySchemes[name] = scheme
+				}
+			}
+		}
+	}
+
+	security(d.Security)
+	for _, paths := range []map[string]*swag.Path{d.Paths, d.Webhooks} {
+		for _, item := range paths {
+			for _, op := range item.Operations() {
+				for _, param := range append(slices.Clone(item.Parameters), op.Parameters...) {
+					name, ok := strings.CutPrefix(param.Ref, "#/components/parameters/")
+					if param := d.Components.Parameters[name]; ok && param != nil {
+						clone := *param
+						clone.Schema = param.Schema.Clone()
+						used.Parameters[name] = &clone
+					}
+				}
+				for _, res := range op.Responses {
+					if res == nil {
+						continue
+					}
+					name, ok := strings.CutPrefix(res.Ref, "#/components/responses/")
+					if res := d.Components.Responses[name]; ok && res != nil {
+						used.Responses[name] = cloneResponse(res)
+					}
+				}
+				if op.Security != nil {
+					security(*op.Security)
+				}
+			}
+		}
+	}
+	// the schemas of the operations and of the parameters and responses of the components they use
+	(&swag.Definition{Paths: d.Paths, Webhooks: d.Webhooks, Components: &swag.Components{
+		Responses:  used.Responses,
+		Parameters: used.Parameters,
+	}}).EachSchema(visit)
+	return used
+}
+
+// tagsUsed returns the tags of d that its operations are tagged by
+func tagsUsed(d *swag.Definition) []swag.Tag {
+	if d.Tags == nil {
+		return nil
+	}
+	tagged := map[string]bool{}
+	for _, paths := range []map[string]*swag.Path{d.Paths, d.Webhooks} {
+		for _, item := range paths {
+			for _, op := range item.Operations() {
+				for _, tag := range op.Tags {
+					tagged[tag] = true
+				}
+			}
+		}
+	}
+	tags := []swag.Tag{}
+	for _, tag := range d.Tags {
+		if tagged[tag.Name] {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+func pathsForAudience(paths map[string]*swag.Path, name string) map[string]*swag.Path {
+	if paths == nil {
+		return nil
+	}
+	filtered := map[string]*swag.Path{}
+	for key, path := range paths {
+		p := *path
+		p.Parameters = cloneParams(path.Parameters)
+		for method, op := range path.All() {
+			p.SetOperation(method, cloneOperation(forAudience(op, name)))
+		}
+		if len(p.Operations()) > 0 {
+			filtered[key] = &p
+		}
+	}
+	return filtered
+}
+
+// forAudience returns op if it is for the audience, nil otherwise
+func forAudience(op *swag.Operation, name string) *swag.Operation {
+	if op == nil || len(op.Audiences) == 0 || slices.Contains(op.Audiences, name) {
+		return op
+	}
+	return nil
+}
+
+// cloneOperation copies op with its own schemas, parameters and responses, for the copy not to change as the spec
+// is prepared, e.g. schemas set to the dialect of another version, or statuses documented as they are responded
+func cloneOperation(op *swag.Operation) *swag.Operation {
+	if op == nil {
+		return nil
+	}
+	c := *op
+	c.Parameters = cloneParams(op.Parameters)
+	if op.RequestBody != nil {
+		body := *op.RequestBody
+		body.Content = cloneContent(op.RequestBody.Content)
+		c.RequestBody = &body
+	}
+	if op.Responses != nil {
+		c.Responses = make(map[string]*swag.OpResponse, len(op.Responses))
+		for status, res := range op.Responses {
+			c.Responses[status] = cloneResponse(res)
+		}
+	}
+	return &c
+}
+
+func cloneParams(params []swag.Param) []swag.Param {
+	params = slices.Clone(params)
+	for i := range params {
+		params[i].Schema = params[i].Schema.Clone()
+	}
+	return params
+}
+
+func cloneResponse(res *swag.OpResponse) *swag.OpResponse {
+	if res == nil {
+		return nil
+	}
+	c := *res
+	c.Content = cloneContent(res.Content)
+	c.Headers = cloneHeaders(res.Headers)
+	return &c
+}
+
+func cloneContent(content map[string]swag.MediaType) map[string]swag.MediaType {
+	if content == nil {
+		return nil
+	}
+	c := make(map[string]swag.MediaType, len(content))
+	for contentType, mediaType := range content {
+		mediaType.Schema = mediaType.Schema.Clone()
+		if mediaType.Encoding != nil {
+			encodings := make(map[string]swag.Encoding, len(mediaType.Encoding))
+			for name, encoding := range mediaType.Encoding {
+				encoding.Headers = cloneHeaders(encoding.Headers)
+				encodings[name] = encoding
+			}
+			mediaType.Encoding = encodings
+		}
+		c[contentType] = mediaType
+	}
+	return c
+}
+
+func cloneHeaders(headers map[string]swag.Header) map[string]swag.Header {
+	if headers == nil {
+		return nil
+	}
+	c := make(map[string]swag.Header, len(headers))
+	for name, header := range headers {
+		header.Schema = header.Schema.Clone()
+		c[name] = header
+	}
+	return c
+}
@@ -0,0 +1,142 @@
+package strut
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/modfin/strut/schema"
+)
+
+// paramLocations are the struct tags BindParams binds fields by, naming where the parameter is found
+var paramLocations = []string{"path", "query", "header", "cookie"}
+
+// BindParams fills the fields of T tagged path, query, header or cookie, e.g. `query:"page"`, from the parameters
+// of the request, converting them to the types of the fields and validating them against their json- tags,
+// e.g. json-minimum. Fields are scalars, types implementing encoding.TextUnmarshaler such as time.Time, or
+// pointers to or slices of them. Missing parameters get the default documented for them, if any. Parameters
+// tagged required, e.g. `query:"page,required"`, must be given, others leave their fields untouched when missing.
+// It fails with an HTTPError, 400 for missing or malformed parameters, so it can be returned as is by e.g. a GetE
+// handler, and with a plain error for fields of types it can't bind. The parameters are still declared by
+// with.QueryParam etc.
+func BindParams[T any](ctx context.Context) (T, error) {
+	var params T
+	r := HTTPRequest(ctx)
+	if r == nil {
+		return params, errors.New("strut: BindParams needs the context of a request")
+	}
+	v := reflect.ValueOf(&params).Elem()
+	if v.Kind() != reflect.Struct {
+		return params, fmt.Errorf("strut: BindParams binds structs, not %s", v.Type())
+	}
+
+	s := schema.From(params)
+	var violations []schema.ValidationError
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		in, name, required, ok := paramTag(field)
+		if !ok || !field.IsExported() {
+			continue
+		}
+		if !canSetFormValue(field.Type) {
+			return params, fmt.Errorf("strut: BindParams can't bind %s parameter %s to a %s", in, name, field.Type)
+		}
+
+		values := paramValues(ctx, r, in, name)
+		if def, ok := paramDefault(ctx, in, name); ok && len(values) == 0 {
+			values = defaultValues(def)
+		}
+		if len(values) == 0 {
+			if required {
+				violations = append(violations, schema.ValidationError{Path: name, Message: "required"})
+			}
+			continue
+		}
+		if err := setFormValue(v.Field(i), values); err != nil {
+			return params, NewError(http.StatusBadRequest, fmt.Sprintf("%s parameter %s: %v", in, name, err))
+		}
+
+		for _, violation := range schema.Validate(s.Properties[jsonName(field)], decoded(v.Field(i))) {
+			violations = append(violations, schema.ValidationError{Path: name + violation.Path, Message: violation.Message})
+		}
+	}
+	if len(violations) > 0 {
+		return params, &HTTPError{StatusCode: http.StatusBadRequest, Message: violationsMessage(violations)}
+	}
+	return params, nil
+}
+
+// paramTag returns where the field is bound from, the name of the parameter and whether it is required
+func paramTag(field reflect.StructField) (in string, name string, required bool, ok bool) {
+	for _, in := range paramLocations {
+		tag, ok := field.Tag.Lookup(in)
+		if !ok {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = field.Name
+		}
+		return in, name, options == "required", true
+	}
+	return "", "", false, false
+}
+
+// paramValues returns the values of the parameter, none if it is missing
+func paramValues(ctx context.Context, r *http.Request, in string, name string) []string {
+	switch in {
+	case "path":
+		if value := pathValue(ctx, name); value != "" {
+			return []string{value}
+		}
+	case "query":
+		return r.URL.Query()[name]
+	case "header":
+		return r.Header.Values(name)
+	case "cookie":
+		if cookie, err := r.Cookie(name); err == nil {
+			return []string{cookie.Value}
+		}
+	}
+	return nil
+}
+
+// defaultValues returns the default of a parameter as the values it would have been given as
+func defaultValues(def any) []string {
+	v := reflect.ValueOf(def)
+	if v.Kind() != reflect.Slice {
+		return []string{fmt.Sprint(def)}
+	}
+	values := make([]string, v.Len())
+	for i := range values {
+		values[i] = fmt.Sprint(v.Index(i).Interface())
+	}
+	return values
+}
+
+// jsonName is the name of the property of the field in the schema of its struct
+func jsonName(field reflect.StructField) string {
+	name := strings.Split(field.Tag.Get("json"), ",")[0]
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
+
+// decoded returns v as decoded from JSON into an interface{}, as schema.Validate expects it
+func decoded(v reflect.Value) any {
+	data, err := json.Marshal(v.Interface())
+	if err != nil {
+		return nil
+	}
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil
+	}
+	return value
+}
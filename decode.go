@@ -0,0 +1,46 @@
+package strut
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/modfin/strut/schema"
+)
+
+// DecodeInto decodes the JSON object of the request body, coercing and validating it against target,
+// for endpoints whose body is shaped at runtime rather than by a Go type. It fails with an
+// HTTPError, 400 for bodies not matching target, so it can be returned as is by e.g. a PostE handler
+func DecodeInto(ctx context.Context, target *schema.JSON) (map[string]any, error) {
+	r := HTTPRequest(ctx)
+	if r == nil || r.Body == nil {
+		return nil, errors.New("strut: DecodeInto needs the context of a request")
+	}
+
+	reader := r.Body
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		gz, err := gzip.NewReader(r.Body)
+		if err != nil {
+			return nil, decodeError(err)
+		}
+		reader = gz
+	}
+
+	var body any
+	if err := json.NewDecoder(reader).Decode(&body); err != nil && !errors.Is(err, io.EOF) {
+		return nil, decodeError(err)
+	}
+	body = schema.Coerce(target, body)
+	if violations := schema.Validate(target, body); len(violations) > 0 {
+		return nil, &HTTPError{StatusCode: http.StatusBadRequest, Message: violationsMessage(violations)}
+	}
+
+	obj, ok := body.(map[string]any)
+	if !ok {
+		return nil, &HTTPError{StatusCode: http.StatusBadRequest, Message: "request body must be a JSON object"}
+	}
+	return obj, nil
+}
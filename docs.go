@@ -0,0 +1,99 @@
+package strut
+
+import (
+	"html/template"
+	"net/http"
+)
+
+const (
+	defaultSwaggerUIBaseURL = "https://cdn.jsdelivr.net/npm/swagger-ui-dist@5.17.14"
+	defaultRedocScriptURL   = "https://cdn.jsdelivr.net/npm/redoc@2.1.5/bundles/redoc.standalone.js"
+)
+
+var swaggerUITemplate = template.Must(template.New("swagger-ui").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8" />
+  <meta name="viewport" content="width=device-width, initial-scale=1" />
+  <title>{{.Title}}</title>
+  <link rel="stylesheet" href="{{.AssetURL}}/swagger-ui.css" />
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="{{.AssetURL}}/swagger-ui-bundle.js" crossorigin></script>
+  <script>
+    window.onload = function () {
+      window.ui = SwaggerUIBundle({
+        url: {{.SpecURL}},
+        dom_id: "#swagger-ui",
+      });
+    };
+  </script>
+</body>
+</html>
+`))
+
+var redocTemplate = template.Must(template.New("redoc").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8" />
+  <meta name="viewport" content="width=device-width, initial-scale=1" />
+  <title>{{.Title}}</title>
+  <style>body { margin: 0; padding: 0; }</style>
+</head>
+<body>
+  <redoc spec-url="{{.SpecURL}}"></redoc>
+  <script src="{{.AssetURL}}"></script>
+</body>
+</html>
+`))
+
+// SwaggerUIAssets sets where the swagger-ui-dist assets are loaded from, e.g. a path
+// serving them locally for air-gapped deployments. Defaults to a pinned CDN version
+func (s *Strut) SwaggerUIAssets(baseURL string) *Strut {
+	s.swaggerUIBaseURL = baseURL
+	return s
+}
+
+// RedocScript sets the URL of the ReDoc standalone bundle, e.g. a path serving
+// it locally for air-gapped deployments. Defaults to a pinned CDN version
+func (s *Strut) RedocScript(url string) *Strut {
+	s.redocScriptURL = url
+	return s
+}
+
+// SwaggerUIHandler serves a Swagger UI page rendering the spec found at specURL
+//
+//	r.Get("/docs", s.SwaggerUIHandler("/.well-known/openapi.json"))
+func (s *Strut) SwaggerUIHandler(specURL string) http.HandlerFunc {
+	assetURL := s.swaggerUIBaseURL
+	if assetURL == "" {
+		assetURL = defaultSwaggerUIBaseURL
+	}
+	return s.docsHandler(swaggerUITemplate, assetURL, specURL)
+}
+
+// RedocHandler serves a ReDoc page rendering the spec found at specURL, JSON or YAML
+//
+//	r.Get("/reference", s.RedocHandler("/.well-known/openapi.yaml"))
+func (s *Strut) RedocHandler(specURL string) http.HandlerFunc {
+	assetURL := s.redocScriptURL
+	if assetURL == "" {
+		assetURL = defaultRedocScriptURL
+	}
+	return s.docsHandler(redocTemplate, assetURL, specURL)
+}
+
+func (s *Strut) docsHandler(tmpl *template.Template, assetURL string, specURL string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		err := tmpl.Execute(w, map[string]string{
+			"Title":    s.Definition.Info.Title,
+			"AssetURL": assetURL,
+			"SpecURL":  specURL,
+		})
+		if err != nil {
+			s.log.Error("error rendering docs", "error", err)
+		}
+	}
+}
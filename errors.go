@@ -0,0 +1,51 @@
+package strut
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/modfin/strut/swag"
+)
+
+// HTTPError is an error carrying the status code to respond with, returned by the handlers of GetE, PostE,
+// PutE and DeleteE. It is written as an Error body, which can't implement error itself
+type HTTPError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *HTTPError) Error() string {
+	return e.Message
+}
+
+// NewError creates an error responded with the status code and message
+func NewError(statusCode int, message string) error {
+	return &HTTPError{StatusCode: statusCode, Message: message}
+}
+
+// respondE turns the result of a handler returning (RES, error) into a Response. A nil error responds res
+// with the success code of the operation, 200 by default, an HTTPError with its status code and any other
+// error with 500, without exposing it to the client
+func respondE[RES any](s *Strut, ctx context.Context, res RES, err error) Response[RES] {
+	if err == nil {
+		op, _ := ctx.Value(operationKey).(*swag.Operation)
+		if _, noBody := any(res).(NoBody); noBody || successCode(op) == http.StatusNoContent {
+			return respondEmpty[RES](successCode(op))
+		}
+		if r := HTTPRequest(ctx); op != nil && len(op.ResponseContentTypes) > 0 && r != nil {
+			if contentType := negotiateContentType(r.Header.Get("Accept"), op.ResponseContentTypes); isXML(contentType) {
+				return respondXML(successCode(op), contentType, res)
+			}
+		}
+		return RespondTyped(successCode(op), res)
+	}
+
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return RespondError[RES](httpErr.StatusCode, httpErr.Message)
+	}
+
+	s.log.ErrorContext(ctx, "error handling request", "error", err)
+	return RespondError[RES](http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+}
@@ -0,0 +1,211 @@
+package strut
+
+import (
+	"context"
+	"encoding"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/modfin/strut/swag"
+)
+
+// maxMemory is how much of a multipart form is kept in memory, the rest of the files being stored on disk
+const maxMemory = 32 << 20
+
+var (
+	fileHeaderType      = reflect.TypeOf((*multipart.FileHeader)(nil))
+	fileHeadersType     = reflect.TypeOf([]*multipart.FileHeader(nil))
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// FormFile returns the first file of the field of a multipart/form-data request,
+// see with.RequestContentType
+func FormFile(ctx context.Context, field string) (multipart.File, *multipart.FileHeader, error) {
+	r := HTTPRequest(ctx)
+	if r == nil {
+		return nil, nil, http.ErrMissingFile
+	}
+	return r.FormFile(field)
+}
+
+// removeForm deletes the files of the multipart form parsed for the request of ctx, which net/http only does for
+// forms parsed on the request it served, not on the copies of it handlers get
+func removeForm(ctx context.Context) {
+	if r := HTTPRequest(ctx); r != nil && r.MultipartForm != nil {
+		_ = r.MultipartForm.RemoveAll()
+	}
+}
+
+// isForm reports whether the media type is decoded as a form rather than as JSON
+func isForm(mediaType string) bool {
+	return mediaType == "multipart/form-data" || mediaType == "application/x-www-form-urlencoded"
+}
+
+// decodeForm parses the form of the request into the fields of REQ, named by their json tags.
+// Fields of type *multipart.FileHeader and []*multipart.FileHeader get the files of multipart forms
+func decodeForm[REQ any](r *http.Request) (req REQ, err error) {
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/") {
+		err = r.ParseMultipartForm(maxMemory)
+	} else {
+		err = r.ParseForm()
+	}
+	if err != nil {
+		return req, err
+	}
+
+	v := reflect.ValueOf(&req).Elem()
+	if v.Kind() != reflect.Struct {
+		return req, fmt.Errorf("form requests must be decoded into a struct, not %s", v.Type())
+	}
+
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		switch field.Type {
+		case fileHeaderType:
+			if r.MultipartForm != nil && len(r.MultipartForm.File[name]) > 0 {
+				v.Field(i).Set(reflect.ValueOf(r.MultipartForm.File[name][0]))
+			}
+			continue
+		case fileHeadersType:
+			if r.MultipartForm != nil {
+				v.Field(i).Set(reflect.ValueOf(r.MultipartForm.File[name]))
+			}
+			continue
+		}
+
+		values := r.Form[name]
+		if len(values) == 0 {
+			continue
+		}
+		if err := setFormValue(v.Field(i), values); err != nil {
+			return req, fmt.Errorf("field %s: %w", name, err)
+		}
+	}
+	return req, nil
+}
+
+// checkFiles enforces the file limits of the operation on the files of the parsed multipart form
+func checkFiles(op *swag.Operation, r *http.Request) error {
+	if r.MultipartForm == nil {
+		return nil
+	}
+	for field, limit := range op.FileLimits {
+		for _, file := range r.MultipartForm.File[field] {
+			if limit.MaxBytes > 0 && file.Size > limit.MaxBytes {
+				return NewError(http.StatusRequestEntityTooLarge, fmt.Sprintf("file %s is larger than %d bytes", field, limit.MaxBytes))
+			}
+			if len(limit.ContentTypes) > 0 && !matchesContentType(file.Header.Get("Content-Type"), limit.ContentTypes) {
+				return NewError(http.StatusUnsupportedMediaType, fmt.Sprintf("file %s must be one of %s", field, strings.Join(limit.ContentTypes, ", ")))
+			}
+		}
+	}
+	return nil
+}
+
+// matchesContentType reports whether the content type is one of the allowed, which may be ranges like image/*
+func matchesContentType(contentType string, allowed []string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, a := range allowed {
+		if prefix, ok := strings.CutSuffix(a, "/*"); ok && strings.HasPrefix(mediaType, prefix+"/") || a == mediaType {
+			return true
+		}
+	}
+	return false
+}
+
+// setFormValue sets v, a scalar or encoding.TextUnmarshaler, a pointer to one or a slice of them, from the values
+// of a form field
+func setFormValue(v reflect.Value, values []string) error {
+	if v.CanAddr() {
+		if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+			return u.UnmarshalText([]byte(values[0]))
+		}
+	}
+	switch v.Kind() {
+	case reflect.Ptr:
+		elem := reflect.New(v.Type().Elem())
+		if err := setFormValue(elem.Elem(), values); err != nil {
+			return err
+		}
+		v.Set(elem)
+		return nil
+	case reflect.Slice:
+		slice := reflect.MakeSlice(v.Type(), len(values), len(values))
+		for i, value := range values {
+			if err := setFormValue(slice.Index(i), []string{value}); err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+		return nil
+	}
+
+	value := values[0]
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	default:
+		return fmt.Errorf("unsupported form field type %s", v.Type())
+	}
+	return nil
+}
+
+// canSetFormValue reports whether setFormValue sets values of type t
+func canSetFormValue(t reflect.Type) bool {
+	if reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Slice:
+		return canSetFormValue(t.Elem())
+	case reflect.String, reflect.Bool, reflect.Float32, reflect.Float64,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
@@ -3,9 +3,13 @@ package strut
 import (
 	"context"
 	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
 	"net/http"
+	"strings"
 
-	"github.com/go-chi/chi/v5"
+	"github.com/modfin/strut/swag"
 )
 
 type Response[T any] interface {
@@ -13,33 +17,143 @@ type Response[T any] interface {
 }
 type responseHandler[T any] struct {
 	handler func(w http.ResponseWriter, r *http.Request) error
+
+	status int      // the status responded with, if known up front
+	body   bodyKind // what the body is, for the status to be documented
 }
 
 func (r *responseHandler[T]) Respond(wri http.ResponseWriter, req *http.Request) error {
 	return r.handler(wri, req)
 }
 
+// StatusCode is the status the response is written with, 0 if it isn't known before responding
+func (r *responseHandler[T]) StatusCode() int {
+	return r.status
+}
+
+func (r *responseHandler[T]) bodyKind() bodyKind {
+	return r.body
+}
+
+// bodyKind tells what the body of a response is, for responses of undeclared statuses to be documented
+type bodyKind int
+
+const (
+	bodyUnknown bodyKind = iota
+	bodyTyped            // T, documented like the 200 response
+	bodyError            // an Error
+	bodyNone
+)
+
+// Respond writes response as JSON with the given status, prefer RespondTyped
+// unless the body intentionally differs from T, e.g. for errors
 func Respond[T any](status int, response any) Response[T] {
 	return &responseHandler[T]{
 		handler: func(w http.ResponseWriter, r *http.Request) error {
-			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Content-Type", contentType(r))
 			w.WriteHeader(status)
 			return json.NewEncoder(w).Encode(response)
 		},
+		status: status,
+	}
+}
+
+// RespondTyped writes body as JSON with the given status, keeping the body typed to T
+// so it can't drift from the type documented in the spec
+func RespondTyped[T any](status int, body T) Response[T] {
+	return withBody[T](Respond[T](status, body), bodyTyped)
+}
+
+// RespondXML writes body as XML, encoded by encoding/xml, with 200 OK. Document the response as XML by
+// with.ResponseContentTypes
+func RespondXML[T any](body T) Response[T] {
+	return respondXML(http.StatusOK, "application/xml", body)
+}
+
+// respondXML writes body as XML of the media type with the given status
+func respondXML[T any](status int, mediaType string, body T) Response[T] {
+	return &responseHandler[T]{
+		handler: func(w http.ResponseWriter, r *http.Request) error {
+			w.Header().Set("Content-Type", mediaType)
+			w.WriteHeader(status)
+			if _, err := io.WriteString(w, xml.Header); err != nil {
+				return err
+			}
+			return xml.NewEncoder(w).Encode(body)
+		},
+		status: status,
+		body:   bodyTyped,
+	}
+}
+
+// RespondCreated writes body as JSON with 201 Created
+func RespondCreated[T any](body T) Response[T] {
+	return RespondTyped(http.StatusCreated, body)
+}
+
+// RespondAccepted writes body as JSON with 202 Accepted, e.g. for work that is yet to be done
+func RespondAccepted[T any](body T) Response[T] {
+	return RespondTyped(http.StatusAccepted, body)
+}
+
+// NoBody is the response type of operations responding without a body, e.g. DELETE endpoints. Their response is
+// documented without content, under 204 unless with.SuccessCode says otherwise, and E handlers respond no body
+type NoBody struct{}
+
+// RespondNoContent writes a 204 No Content response, without a body
+func RespondNoContent[T any]() Response[T] {
+	return respondEmpty[T](http.StatusNoContent)
+}
+
+// respondEmpty writes the status without a body
+func respondEmpty[T any](status int) Response[T] {
+	return &responseHandler[T]{
+		handler: func(w http.ResponseWriter, r *http.Request) error {
+			w.WriteHeader(status)
+			return nil
+		},
+		status: status,
+		body:   bodyNone,
 	}
 }
 
+func withBody[T any](res Response[T], body bodyKind) Response[T] {
+	res.(*responseHandler[T]).body = body
+	return res
+}
+
 type Error struct {
 	StatusCode int    `json:"status_code" json-description:"Error code"`
 	Error      string `json:"error" json-description:"Error message"`
 }
 
 func RespondOk[T any](response T) Response[T] {
-	return Respond[T](http.StatusOK, response)
+	return RespondTyped(http.StatusOK, response)
 }
 
 func RespondError[T any](statusCode int, message string) Response[T] {
-	return Respond[T](statusCode, Error{StatusCode: statusCode, Error: message})
+	return withBody[T](Respond[T](statusCode, Error{StatusCode: statusCode, Error: message}), bodyError)
+}
+
+// BatchResult is the outcome of one item of a batch operation, Index being its position in the request
+type BatchResult struct {
+	Index      int    `json:"index" json-description:"Position of the item in the request"`
+	StatusCode int    `json:"status_code" json-description:"Status of the item, as if it had been requested on its own"`
+	Error      string `json:"error,omitempty" json-description:"Error message, if the item failed"`
+}
+
+// BatchResponse is the body of a 207 Multi-Status response to a batch operation
+type BatchResponse struct {
+	Results []BatchResult `json:"results" json-description:"The outcome of each item"`
+}
+
+// RespondBatchErrors writes the per item results of a batch operation as a 207 Multi-Status response,
+// document it with with.BatchResponse
+func RespondBatchErrors[T any](results []BatchResult) Response[T] {
+	if results == nil {
+		results = []BatchResult{}
+	}
+	return Respond[T](http.StatusMultiStatus, BatchResponse{Results: results})
 }
 
 func RespondFunc[T any](handler func(w http.ResponseWriter, r *http.Request) error) Response[T] {
@@ -48,31 +162,104 @@ func RespondFunc[T any](handler func(w http.ResponseWriter, r *http.Request) err
 	}
 }
 
+// RespondStream writes the body produced by write with the given content type, e.g. application/pdf or text/csv,
+// streaming it to the client rather than buffering it. Document the media type with with.ResponseContentType
+func RespondStream[T any](contentType string, write func(w io.Writer) error) Response[T] {
+	return &responseHandler[T]{
+		handler: func(w http.ResponseWriter, r *http.Request) error {
+			w.Header().Set("Content-Type", contentType)
+			w.WriteHeader(http.StatusOK)
+			return write(w)
+		},
+	}
+}
+
 // PathParam returns the value of the path parameter
-// expects that chi is being used
+// routed by chi or by http.ServeMux
 func PathParam(ctx context.Context, param string) string {
-	return chi.URLParamFromCtx(ctx, param)
+	return pathValue(ctx, param)
 }
 
+// QueryParam returns the value of the query parameter, or the default documented for it if it is missing
 func QueryParam(ctx context.Context, param string) string {
 	r := HTTPRequest(ctx)
 	if r == nil {
 		return ""
 	}
-	return r.URL.Query().Get(param)
+	query := r.URL.Query()
+	if !query.Has(param) {
+		if def, ok := paramDefault(ctx, "query", param); ok {
+			return fmt.Sprint(def)
+		}
+	}
+	return query.Get(param)
+}
+
+// paramDefault returns the default of the parameter as documented by the operation of the request, if any
+func paramDefault(ctx context.Context, in string, name string) (any, bool) {
+	op, _ := ctx.Value(operationKey).(*swag.Operation)
+	d, _ := ctx.Value(definitionKey).(*swag.Definition)
+	if op == nil {
+		return nil, false
+	}
+	for _, param := range op.Parameters {
+		if ref, ok := strings.CutPrefix(param.Ref, "#/components/parameters/"); ok && d != nil && d.Components != nil && d.Components.Parameters[ref] != nil {
+			param = *d.Components.Parameters[ref]
+		}
+		if param.In == in && param.Name == name && param.Schema != nil && param.Schema.Default != nil {
+			return param.Schema.Default, true
+		}
+	}
+	return nil, false
+}
+
+// Prefer returns the preferences of the Prefer header of the request (RFC 7240), e.g. "return" => "minimal"
+// for "Prefer: return=minimal". Preferences without a value map to "", parameters are ignored and the
+// first occurrence of a preference wins
+func Prefer(ctx context.Context) map[string]string {
+	prefs := map[string]string{}
+	r := HTTPRequest(ctx)
+	if r == nil {
+		return prefs
+	}
+	for _, header := range r.Header.Values("Prefer") {
+		for _, pref := range strings.Split(header, ",") {
+			pref, _, _ = strings.Cut(pref, ";")
+			name, value, _ := strings.Cut(pref, "=")
+			name = strings.ToLower(strings.TrimSpace(name))
+			if name == "" {
+				continue
+			}
+			if _, ok := prefs[name]; !ok {
+				prefs[name] = strings.Trim(strings.TrimSpace(value), `"`)
+			}
+		}
+	}
+	return prefs
+}
+
+// contentType is the content type of response bodies, configured by Strut.DefaultContentType
+func contentType(r *http.Request) string {
+	if r == nil {
+		return "application/json"
+	}
+	if ct, ok := r.Context().Value(contentTypeKey).(string); ok && ct != "" {
+		return ct
+	}
+	return "application/json"
 }
 
 func HTTPRequest(ctx context.Context) *http.Request {
-	r := ctx.Value("http-request")
+	r := ctx.Value(requestKey)
 	if r == nil {
 		return nil
 	}
 	return r.(*http.Request)
 }
 func HTTPResponseWriter(ctx context.Context) http.ResponseWriter {
-	w := ctx.Value("http-response-writer")
+	w := ctx.Value(responseWriterKey)
 	if w == nil {
 		return nil
 	}
-	return ctx.Value("http-response-writer").(http.ResponseWriter)
+	return ctx.Value(responseWriterKey).(http.ResponseWriter)
 }
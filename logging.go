@@ -0,0 +1,97 @@
+package strut
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// maxLoggedBody is how much of a request body RequestLogger reads to log it
+const maxLoggedBody = 64 << 10
+
+// redacted replaces the values of redacted fields in logged bodies
+const redacted = "[REDACTED]"
+
+// RedactFields masks the JSON fields of the given names, e.g. password or token, wherever they occur
+// in the request bodies logged by RequestLogger. Names are matched case-insensitively
+func (s *Strut) RedactFields(names ...string) *Strut {
+	for _, name := range names {
+		s.redactFields = append(s.redactFields, strings.ToLower(name))
+	}
+	return s
+}
+
+// RequestLogger is middleware logging each request with its status, duration and JSON body,
+// the fields registered by RedactFields being masked
+func (s *Strut) RequestLogger(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		var body []byte
+		if r.Body != nil {
+			body, _ = io.ReadAll(io.LimitReader(r.Body, maxLoggedBody))
+			r.Body = struct {
+				io.Reader
+				io.Closer
+			}{io.MultiReader(bytes.NewReader(body), r.Body), r.Body}
+		}
+
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r)
+
+		attrs := []any{"method", r.Method, "path", r.URL.Path, "status", ww.Status(), "duration", time.Since(start)}
+		if logged, ok := s.redactBody(body); ok {
+			attrs = append(attrs, "body", logged)
+		}
+		s.log.InfoContext(r.Context(), "request", attrs...)
+	})
+}
+
+// redactBody masks the redacted fields of a JSON body, reporting false for bodies that aren't JSON
+func (s *Strut) redactBody(body []byte) (string, bool) {
+	if len(body) == 0 {
+		return "", false
+	}
+	var v any
+	if err := json.Unmarshal(body, &v); err != nil {
+		return "", false
+	}
+	masked, err := json.Marshal(s.redact(v))
+	if err != nil {
+		return "", false
+	}
+	return string(masked), true
+}
+
+func (s *Strut) redact(v any) any {
+	switch val := v.(type) {
+	case map[string]any:
+		for name, item := range val {
+			if s.isRedacted(name) {
+				val[name] = redacted
+				continue
+			}
+			val[name] = s.redact(item)
+		}
+	case []any:
+		for i, item := range val {
+			val[i] = s.redact(item)
+		}
+	}
+	return v
+}
+
+func (s *Strut) isRedacted(name string) bool {
+	name = strings.ToLower(name)
+	for _, field := range s.redactFields {
+		if field == name {
+			return true
+		}
+	}
+	return false
+}
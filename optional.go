@@ -0,0 +1,48 @@
+package strut
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+)
+
+// Optional is a request field telling an absent value from an explicit null and from a zero value, e.g. for
+// PATCH endpoints where omitting a field leaves it unchanged and null clears it. Declare such fields omitzero,
+// e.g. `json:"description,omitzero"`, for them to be left out of the required properties and of responses
+// while unset. It is documented as a nullable T
+type Optional[T any] struct {
+	Set   bool // the field was present, possibly as null
+	Null  bool // the field was present as null
+	Value T    // the value of the field, if present and not null
+}
+
+// Some returns an Optional set to value
+func Some[T any](value T) Optional[T] {
+	return Optional[T]{Set: true, Value: value}
+}
+
+// UnmarshalJSON records that the field was present and whether it was null, it isn't called for absent fields
+func (o *Optional[T]) UnmarshalJSON(data []byte) error {
+	o.Set = true
+	if bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		o.Null = true
+		var zero T
+		o.Value = zero
+		return nil
+	}
+	o.Null = false
+	return json.Unmarshal(data, &o.Value)
+}
+
+// MarshalJSON writes the value, or null if it is unset or null
+func (o Optional[T]) MarshalJSON() ([]byte, error) {
+	if !o.Set || o.Null {
+		return []byte("null"), nil
+	}
+	return json.Marshal(o.Value)
+}
+
+// WrappedType documents the Optional as a nullable T, see schema.Wrapper
+func (Optional[T]) WrappedType() reflect.Type {
+	return reflect.TypeFor[*T]()
+}
@@ -0,0 +1,91 @@
+package strut
+
+import (
+	"context"
+	"net/http"
+	"slices"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Router is what endpoints are registered on, implemented by chi routers and, through ServeMux, by the
+// http.ServeMux of the standard library. Groups, routes and mounts of other routers are not supported
+type Router interface {
+	http.Handler
+	Use(middlewares ...func(http.Handler) http.Handler)
+	Get(pattern string, h http.HandlerFunc)
+	Post(pattern string, h http.HandlerFunc)
+	Put(pattern string, h http.HandlerFunc)
+	Delete(pattern string, h http.HandlerFunc)
+	Method(method, pattern string, h http.Handler)
+}
+
+// ServeMux adapts mux to a Router, for endpoints to be registered with its patterns, e.g. /people/{id}, and
+// path parameters to be read by r.PathValue. Middleware added by Use applies to the handlers registered after it
+func ServeMux(mux *http.ServeMux) Router {
+	return &serveMux{mux: mux}
+}
+
+type serveMux struct {
+	mux        *http.ServeMux
+	prefix     string // of the patterns registered, see Strut.Route
+	middleware []func(http.Handler) http.Handler
+}
+
+func (m *serveMux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.mux.ServeHTTP(w, r)
+}
+
+func (m *serveMux) Use(middlewares ...func(http.Handler) http.Handler) {
+	m.middleware = append(m.middleware, middlewares...)
+}
+
+func (m *serveMux) Get(pattern string, h http.HandlerFunc) {
+	m.Method(http.MethodGet, pattern, h)
+}
+
+func (m *serveMux) Post(pattern string, h http.HandlerFunc) {
+	m.Method(http.MethodPost, pattern, h)
+}
+
+func (m *serveMux) Put(pattern string, h http.HandlerFunc) {
+	m.Method(http.MethodPut, pattern, h)
+}
+
+func (m *serveMux) Delete(pattern string, h http.HandlerFunc) {
+	m.Method(http.MethodDelete, pattern, h)
+}
+
+func (m *serveMux) Method(method, pattern string, h http.Handler) {
+	m.mux.Handle(method+" "+joinPath(m.prefix, pattern), chain(m.middleware, h))
+}
+
+// route returns a router of the patterns under the prefix, whose middleware adds to that of m
+func (m *serveMux) route(prefix string) *serveMux {
+	return &serveMux{mux: m.mux, prefix: joinPath(m.prefix, prefix), middleware: slices.Clone(m.middleware)}
+}
+
+// mount serves h under the prefix, which is stripped from the path of the requests h serves
+func (m *serveMux) mount(prefix string, h http.Handler) {
+	prefix = joinPath(m.prefix, prefix)
+	m.mux.Handle(prefix+"/", http.StripPrefix(prefix, chain(m.middleware, h)))
+}
+
+// chain wraps h by the middleware, the first being the outermost
+func chain(middleware []func(http.Handler) http.Handler, h http.Handler) http.Handler {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		h = middleware[i](h)
+	}
+	return h
+}
+
+// pathValue returns the value of the path parameter, as routed by chi or by http.ServeMux
+func pathValue(ctx context.Context, name string) string {
+	if value := chi.URLParamFromCtx(ctx, name); value != "" {
+		return value
+	}
+	if r := HTTPRequest(ctx); r != nil {
+		return r.PathValue(name)
+	}
+	return ""
+}
@@ -0,0 +1,153 @@
+package strut
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/modfin/strut/schema"
+	"github.com/modfin/strut/swag"
+)
+
+// maxSampleDepth bounds how deep sample bodies follow nested and recursive schemas
+const maxSampleDepth = 8
+
+// GenerateCurlSamples adds a curl x-codeSamples entry to every operation when the spec is served,
+// calling the first server of the operation with example values for the path parameters and the request body
+func (s *Strut) GenerateCurlSamples(enabled bool) *Strut {
+	s.mustBeMutable()
+	s.spec.curlSamples = enabled
+	return s
+}
+
+// withCurlSamples returns a copy of d in which every operation has a curl sample, leaving the operations of d as
+// they are
+func withCurlSamples(d *swag.Definition) *swag.Definition {
+	sampled := *d
+	sampled.Paths = make(map[string]*swag.Path, len(d.Paths))
+	for path, item := range d.Paths {
+		p := *item
+		for method, op := range item.All() {
+			server := "http://localhost"
+			for _, servers := range [][]swag.Server{op.Servers, item.Servers, d.Servers} {
+				if len(servers) > 0 {
+					server = strings.TrimSuffix(servers[0].URL, "/")
+					break
+				}
+			}
+			params := append(append([]swag.Param{}, item.Parameters...), op.Parameters...)
+			sampledOp := *op
+			sampledOp.CodeSamples = []swag.CodeSample{{
+				Lang:   "Shell",
+				Label:  "curl",
+				Source: curlSample(d, method, server+path, params, op.RequestBody),
+			}}
+			p.SetOperation(method, &sampledOp)
+		}
+		sampled.Paths[path] = &p
+	}
+	return &sampled
+}
+
+func curlSample(d *swag.Definition, method string, target string, params []swag.Param, body *swag.RequestBody) string {
+	query := url.Values{}
+	for _, param := range params {
+		if name, ok := strings.CutPrefix(param.Ref, "#/components/parameters/"); ok && d.Components != nil && d.Components.Parameters[name] != nil {
+			param = *d.Components.Parameters[name]
+		}
+		value := fmt.Sprint(sampleValue(d, param.Schema, 0))
+		switch param.In {
+		case "path":
+			target = strings.ReplaceAll(target, "{"+param.Name+"}", url.PathEscape(value))
+		case "query":
+			if param.Required {
+				query.Set(param.Name, value)
+			}
+		}
+	}
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	lines := []string{fmt.Sprintf("curl -X %s %s", method, shellQuote(target))}
+	if body != nil && len(body.Content) > 0 {
+		mediaTypes := make([]string, 0, len(body.Content))
+		for mediaType := range body.Content {
+			mediaTypes = append(mediaTypes, mediaType)
+		}
+		sort.Strings(mediaTypes) // not to change between encodings of the spec
+		mediaType := mediaTypes[0]
+
+		lines = append(lines, "-H "+shellQuote("Content-Type: "+mediaType))
+		if data, err := json.Marshal(sampleValue(d, body.Content[mediaType].Schema, 0)); err == nil && isJSON(mediaType) {
+			lines = append(lines, "-d "+shellQuote(string(data)))
+		}
+	}
+	return strings.Join(lines, " \\\n  ")
+}
+
+// sampleValue makes up a value matching s, preferring its example, default or first enum value
+func sampleValue(d *swag.Definition, s *schema.JSON, depth int) any {
+	if s == nil || depth > maxSampleDepth {
+		return nil
+	}
+	if name, ok := strings.CutPrefix(s.Ref, "#/components/schemas/"); ok {
+		if d.Components == nil {
+			return nil
+		}
+		return sampleValue(d, d.Components.Schemas[name], depth+1)
+	}
+	switch {
+	case s.Example != nil:
+		return s.Example
+	case s.Default != nil:
+		return s.Default
+	case s.Const != nil:
+		return s.Const
+	case len(s.Enum) > 0:
+		return s.Enum[0]
+	case len(s.OneOf) > 0:
+		return sampleValue(d, s.OneOf[0], depth+1)
+	case len(s.AnyOf) > 0:
+		return sampleValue(d, s.AnyOf[0], depth+1)
+	}
+
+	switch s.Type {
+	case schema.Object:
+		obj := map[string]any{}
+		for name, prop := range s.Properties {
+			obj[name] = sampleValue(d, prop, depth+1)
+		}
+		return obj
+	case schema.Array:
+		return []any{sampleValue(d, s.Items, depth+1)}
+	case schema.String:
+		if s.Format != nil {
+			switch *s.Format {
+			case "date-time":
+				return "2024-01-01T00:00:00Z"
+			case "date":
+				return "2024-01-01"
+			case "email":
+				return "user@example.com"
+			case "uuid":
+				return "00000000-0000-0000-0000-000000000000"
+			case "uri":
+				return "https://example.com"
+			}
+		}
+		return "string"
+	case schema.Integer, schema.Number:
+		return 0
+	case schema.Boolean:
+		return false
+	}
+	return nil
+}
+
+// shellQuote quotes v for a POSIX shell
+func shellQuote(v string) string {
+	return "'" + strings.ReplaceAll(v, "'", `'\''`) + "'"
+}
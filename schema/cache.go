@@ -0,0 +1,52 @@
+package schema
+
+import (
+	"reflect"
+	"sync"
+)
+
+// cache holds the schemas From has built, keyed by type and options, along with the problems met building
+// them. Schemas are copied both in and out, so that callers adjusting the returned schema don't affect each other
+var cache = schemaCache{schemas: map[cacheKey]cached{}}
+
+type cacheKey struct {
+	t       reflect.Type
+	options options
+}
+
+type cached struct {
+	schema *JSON
+	err    error
+}
+
+type schemaCache struct {
+	sync.RWMutex
+	schemas    map[cacheKey]cached
+	generation uint64 // bumped on reset, so schemas built before it aren't cached after it
+}
+
+func (c *schemaCache) get(key cacheKey) (*JSON, error, uint64, bool) {
+	c.RLock()
+	defer c.RUnlock()
+	entry, ok := c.schemas[key]
+	if !ok {
+		return nil, nil, c.generation, false
+	}
+	return entry.schema.Clone(), entry.err, c.generation, true
+}
+
+func (c *schemaCache) put(key cacheKey, s *JSON, err error, generation uint64) {
+	c.Lock()
+	defer c.Unlock()
+	if c.generation == generation {
+		c.schemas[key] = cached{schema: s.Clone(), err: err}
+	}
+}
+
+// reset drops every cached schema, registrations change how types are converted
+func (c *schemaCache) reset() {
+	c.Lock()
+	defer c.Unlock()
+	c.schemas = map[cacheKey]cached{}
+	c.generation++
+}
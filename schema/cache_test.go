@@ -0,0 +1,98 @@
+package schema_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/modfin/strut/schema"
+)
+
+type CachedLine struct {
+	SKU      string   `json:"sku" json-pattern:"^[A-Z]+$" json-max-length:"12"`
+	Quantity int      `json:"quantity" json-minimum:"1" json-multiple-of:"1"`
+	Tags     []string `json:"tags" json-enum:"new,sale" json-min-items:"1"`
+}
+
+type CachedOrder struct {
+	ID    string            `json:"id" json-format:"uuid"`
+	Lines []CachedLine      `json:"lines"`
+	Meta  map[string]string `json:"meta,omitempty"`
+}
+
+func TestFrom_CachedCopies(t *testing.T) {
+	first := schema.From(CachedOrder{})
+	expected := schema.From(CachedOrder{})
+	if !reflect.DeepEqual(first, expected) {
+		t.Fatalf("Expected %+v, got %+v", expected, first)
+	}
+
+	// Adjusting a returned schema must not leak into later calls
+	line := first.Properties["lines"].Items
+	line.Properties["sku"].Description = "changed"
+	*line.Properties["sku"].Pattern = "changed"
+	*line.Properties["quantity"].Minimum = 42
+	line.Properties["tags"].Items.Enum[0] = "changed"
+	line.Required[0] = "changed"
+	*first.Properties["id"].Format = "changed"
+	first.Properties["meta"].AdditionalProperties.Type = schema.Integer
+	delete(first.Properties, "id")
+
+	if result := schema.From(CachedOrder{}); !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, result)
+	}
+}
+
+func TestFrom_CacheReset(t *testing.T) {
+	type Shade string
+	type Palette struct {
+		Primary Shade `json:"primary"`
+	}
+
+	if result := schema.From(Palette{}); result.Properties["primary"].Enum != nil {
+		t.Fatalf("Expected no enum before registering, got %+v", result.Properties["primary"].Enum)
+	}
+
+	schema.RegisterEnum[Shade]("light", "dark")
+	expected := []interface{}{"light", "dark"}
+	if result := schema.From(Palette{}); !reflect.DeepEqual(result.Properties["primary"].Enum, expected) {
+		t.Errorf("Expected %+v after registering, got %+v", expected, result.Properties["primary"].Enum)
+	}
+}
+
+type BenchmarkOrder struct {
+	ID       string                `json:"id" json-format:"uuid" json-description:"Order ID"`
+	Customer BenchmarkCustomer     `json:"customer"`
+	Lines    []CachedLine          `json:"lines" json-min-items:"1"`
+	Totals   map[string]float64    `json:"totals"`
+	Notes    []string              `json:"notes,omitempty"`
+	Shipping *BenchmarkCustomer    `json:"shipping"`
+	Extra    map[string]CachedLine `json:"extra"`
+}
+
+type BenchmarkCustomer struct {
+	Name    string `json:"name" json-min-length:"1"`
+	Email   string `json:"email" json-format:"email"`
+	Address struct {
+		Street string `json:"street"`
+		City   string `json:"city"`
+		Zip    string `json:"zip" json-pattern:"^[0-9]{5}$"`
+	} `json:"address"`
+}
+
+// BenchmarkFrom_Cold measures building a schema from scratch, the cache being reset for every iteration
+func BenchmarkFrom_Cold(b *testing.B) {
+	type cold string
+	for i := 0; i < b.N; i++ {
+		schema.RegisterEnum[cold]() // resets the cache
+		schema.From(BenchmarkOrder{})
+	}
+}
+
+// BenchmarkFrom_Warm measures returning a copy of a cached schema
+func BenchmarkFrom_Warm(b *testing.B) {
+	schema.From(BenchmarkOrder{})
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		schema.From(BenchmarkOrder{})
+	}
+}
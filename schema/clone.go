@@ -0,0 +1,91 @@
+package schema
+
+import "reflect"
+
+// Clone deep copies s, so that the copy can be adjusted without affecting s. Default, Example, Const and
+// the Enum values are copied as is, being values rather than schemas
+func (s *JSON) Clone() *JSON {
+	if s == nil {
+		return nil
+	}
+
+	c := *s
+	if s.Defs != nil {
+		c.Defs = make(map[string]*JSON, len(s.Defs))
+		for name, def := range s.Defs {
+			c.Defs[name] = def.Clone()
+		}
+	}
+	if s.Properties != nil {
+		c.Properties = make(map[string]*JSON, len(s.Properties))
+		for name, prop := range s.Properties {
+			c.Properties[name] = prop.Clone()
+		}
+	}
+	if s.PropertyOrdering != nil {
+		c.PropertyOrdering = append([]string{}, s.PropertyOrdering...)
+	}
+	c.AdditionalProperties = s.AdditionalProperties.Clone()
+	c.AdditionalPropertiesBool = clonePtr(s.AdditionalPropertiesBool)
+	c.Items = s.Items.Clone()
+	c.OneOf = cloneAll(s.OneOf)
+	c.AnyOf = cloneAll(s.AnyOf)
+	if s.Discriminator != nil {
+		d := *s.Discriminator
+		if s.Discriminator.Mapping != nil {
+			d.Mapping = make(map[string]string, len(s.Discriminator.Mapping))
+			for k, v := range s.Discriminator.Mapping {
+				d.Mapping[k] = v
+			}
+		}
+		c.Discriminator = &d
+	}
+
+	if s.Enum != nil {
+		c.Enum = append([]interface{}{}, s.Enum...)
+	}
+	if s.Required != nil {
+		c.Required = append([]string{}, s.Required...)
+	}
+
+	c.Maximum = clonePtr(s.Maximum)
+	c.Minimum = clonePtr(s.Minimum)
+	c.ExclusiveMaximum = clonePtr(s.ExclusiveMaximum)
+	c.ExclusiveMinimum = clonePtr(s.ExclusiveMinimum)
+	c.MultipleOf = clonePtr(s.MultipleOf)
+	c.MaxLength = clonePtr(s.MaxLength)
+	c.MinLength = clonePtr(s.MinLength)
+	c.Pattern = clonePtr(s.Pattern)
+	c.Format = clonePtr(s.Format)
+	c.MaxItems = clonePtr(s.MaxItems)
+	c.MinItems = clonePtr(s.MinItems)
+	c.XML = clonePtr(s.XML)
+	return &c
+}
+
+// Equal reports whether s and other are the same schema, whichever dialect they are encoded in
+func (s *JSON) Equal(other *JSON) bool {
+	a, b := s.Clone(), other.Clone()
+	SetDialect(a, JSONSchema)
+	SetDialect(b, JSONSchema)
+	return reflect.DeepEqual(a, b)
+}
+
+func cloneAll(schemas []*JSON) []*JSON {
+	if schemas == nil {
+		return nil
+	}
+	c := make([]*JSON, len(schemas))
+	for i, s := range schemas {
+		c[i] = s.Clone()
+	}
+	return c
+}
+
+func clonePtr[T any](p *T) *T {
+	if p == nil {
+		return nil
+	}
+	v := *p
+	return &v
+}
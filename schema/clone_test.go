@@ -0,0 +1,77 @@
+package schema_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/modfin/strut/schema"
+)
+
+func TestJSON_Clone(t *testing.T) {
+	type Item struct {
+		Name  string  `json:"name" json-pattern:"^[a-z]+$" json-min-length:"1"`
+		Price float64 `json:"price" json-minimum:"0" json-exclusive-maximum:"1000"`
+	}
+	type Order struct {
+		Items  []Item            `json:"items" json-min-items:"1" json-max-items:"10"`
+		Status string            `json:"status" json-enum:"open,closed" json-format:"word"`
+		Meta   map[string]string `json:"meta"`
+	}
+
+	original := schema.From(Order{})
+	original.OneOf = []*schema.JSON{{Type: schema.String}}
+	original.Discriminator = &schema.Discriminator{PropertyName: "status", Mapping: map[string]string{"open": "#/open"}}
+	closed := false
+	original.AdditionalPropertiesBool = &closed
+	expected := schema.From(Order{})
+	expected.OneOf = []*schema.JSON{{Type: schema.String}}
+	expected.Discriminator = &schema.Discriminator{PropertyName: "status", Mapping: map[string]string{"open": "#/open"}}
+	expected.AdditionalPropertiesBool = &closed
+
+	clone := original.Clone()
+	if !reflect.DeepEqual(clone, original) {
+		t.Fatalf("Expected %+v, got %+v", original, clone)
+	}
+
+	item := clone.Properties["items"].Items
+	*item.Properties["name"].Pattern = "changed"
+	*item.Properties["name"].MinLength = 5
+	*item.Properties["price"].Minimum = 5
+	*item.Properties["price"].ExclusiveMaximum = 5
+	item.Required[0] = "changed"
+	*clone.Properties["items"].MinItems = 5
+	*clone.Properties["status"].Format = "changed"
+	clone.Properties["status"].Enum[0] = "changed"
+	clone.Properties["meta"].AdditionalProperties.Type = schema.Integer
+	clone.OneOf[0].Type = schema.Integer
+	clone.Discriminator.Mapping["open"] = "changed"
+	*clone.AdditionalPropertiesBool = true
+	delete(clone.Properties, "meta")
+
+	if !reflect.DeepEqual(original, expected) {
+		t.Errorf("Expected the original to be unaffected %+v, got %+v", expected, original)
+	}
+
+	var nilSchema *schema.JSON
+	if nilSchema.Clone() != nil {
+		t.Errorf("Expected a nil clone of a nil schema")
+	}
+}
+
+func TestEqual(t *testing.T) {
+	type Rating struct {
+		Rate float64 `json:"rate" json-exclusive-maximum:"5"`
+	}
+
+	s := schema.From(Rating{})
+	other := schema.From(Rating{})
+	schema.SetDialect(other, schema.OpenAPI30)
+	if !s.Equal(other) {
+		t.Errorf("Expected schemas differing only by dialect to be equal")
+	}
+
+	other.Properties["rate"].Description = "changed"
+	if s.Equal(other) {
+		t.Errorf("Expected schemas of different descriptions to differ")
+	}
+}
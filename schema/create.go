@@ -1,78 +1,302 @@
 package schema
 
 import (
+	"encoding"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"log/slog"
 	"reflect"
+	"slices"
 	"strconv"
 	"strings"
 )
 
-// From converts a struct to a JSON using reflection and struct tags
-func From(v any) *JSON {
-	t := reflect.TypeOf(v)
+var (
+	rawMessageType    = reflect.TypeOf(json.RawMessage{})
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// Schemaer is implemented by types that provide their own schema, which From then uses verbatim
+// instead of reflecting into the type. When the type is used as a struct field, the json-* tags
+// of the field are applied on top of the provided schema
+type Schemaer interface {
+	JSONSchema() *JSON
+}
+
+// Wrapper is implemented by types encoding as a value of another type, e.g. generic optional values, which From
+// then converts in place of the wrapper, with the options From was called with. A pointer type documents the
+// wrapper as nullable
+type Wrapper interface {
+	WrappedType() reflect.Type
+}
+
+// Option adjusts how From converts types, e.g. WithPropertyOrdering
+type Option func(o *options)
+
+// options are compared to cache schemas per type and options, so they must stay comparable
+type options struct {
+	propertyOrdering bool
+	strict           bool
+	maxDepth         int // 0 for no limit
+}
+
+// WithPropertyOrdering lists the properties of structs in the order of their fields as propertyOrdering,
+// which e.g. Gemini generates structured output in
+func WithPropertyOrdering() Option {
+	return func(o *options) {
+		o.propertyOrdering = true
+	}
+}
+
+// Strict closes the objects of structs with additionalProperties false, which e.g. OpenAI structured outputs
+// and strict validators require. Maps keep their value schema as additionalProperties, since their keys
+// are not known up front, and schemas of Schemaer types are left as provided
+func Strict() Option {
+	return func(o *options) {
+		o.strict = true
+	}
+}
+
+// MaxDepth limits how deeply objects and arrays are nested, e.g. for structured output bounding the nesting of
+// schemas. Objects and arrays nested deeper than depth levels are left unconstrained, a depth of 1 keeping only
+// the properties of the outermost object. Recursive types are inlined down to where they recur regardless
+func MaxDepth(depth int) Option {
+	return func(o *options) {
+		o.maxDepth = depth
+	}
+}
+
+// From converts a struct to a JSON using reflection and struct tags. Schemas are cached per type and
+// options, and registrations such as RegisterEnum and RegisterFormat don't affect schemas already
+// returned, so they should happen before first use. Problems converting the type are logged, see FromE
+func From(v any, opts ...Option) *JSON {
+	s, err := FromE(v, opts...)
+	if err != nil {
+		slog.Warn("schema: converted best-effort", "type", fmt.Sprint(reflect.TypeOf(v)), "error", err)
+	}
+	return s
+}
+
+// FromE converts like From, but returns the problems met converting the type rather than logging them:
+// types encoding/json can't encode, e.g. channels, recursive types, tags that can't be parsed and tags
+// conflicting with each other. The best-effort schema is returned along with the error, e.g. for tests
+// to fail on modeling mistakes
+func FromE(v any, opts ...Option) (*JSON, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	key := cacheKey{t: reflect.TypeOf(v), options: o}
+	cached, err, generation, ok := cache.get(key)
+	if ok {
+		return cached, err
+	}
+	s, err := from(key.t, o)
+	cache.put(key, s, err, generation)
+	return s, err
+}
+
+func from(t reflect.Type, o options) (*JSON, error) {
+	if t == nil { // a nil interface, anything goes
+		return &JSON{}, nil
+	}
+
 	var nullable bool
 	if t.Kind() == reflect.Ptr {
 		nullable = true
 		t = t.Elem()
 	}
-	schema := typeToSchema(t)
+	c := &converter{options: o, visiting: map[reflect.Type]bool{}}
+	schema := c.typeToSchema(t)
 	schema.Nullable = nullable
-	return schema
+	return schema, errors.Join(c.errs...)
 }
 
-func typeToSchema(t reflect.Type) *JSON {
+// converter converts a type to a schema, collecting the problems met on the way
+type converter struct {
+	options
+	visiting map[reflect.Type]bool // structs being converted, to tell recursive types
+	depth    int                   // how many objects and arrays the type being converted is nested in
+	errs     []error
+}
+
+func (c *converter) errorf(format string, args ...any) {
+	c.errs = append(c.errs, fmt.Errorf(format, args...))
+}
+
+// OneOf creates a schema matching exactly one of the schemas of the given values, e.g. for tagged unions
+func OneOf(types ...any) *JSON {
+	return &JSON{OneOf: fromAll(types)}
+}
+
+// AnyOf creates a schema matching at least one of the schemas of the given values
+func AnyOf(types ...any) *JSON {
+	return &JSON{AnyOf: fromAll(types)}
+}
+
+// WithDiscriminator sets the property telling which of the OneOf or AnyOf schemas of s a value conforms to and
+// returns s, e.g. schema.OneOf(OrderPlaced{}, OrderShipped{}).WithDiscriminator("kind", nil). The mapping maps
+// values of the property to schema $refs, when they aren't the names of the components. As generators pick
+// the schema by the property, every object schema of s must require it, which is panicked on otherwise
+func (s *JSON) WithDiscriminator(propertyName string, mapping map[string]string) *JSON {
+	for kind, branches := range map[string][]*JSON{"oneOf": s.OneOf, "anyOf": s.AnyOf} {
+		for i, branch := range branches {
+			if branch.Type == Object && !slices.Contains(branch.Required, propertyName) {
+				panic(fmt.Sprintf("schema: discriminator %s is not required by %s[%d]", propertyName, kind, i))
+			}
+		}
+	}
+	s.Discriminator = &Discriminator{PropertyName: propertyName, Mapping: mapping}
+	return s
+}
+
+func fromAll(types []any) []*JSON {
+	schemas := make([]*JSON, len(types))
+	for i, v := range types {
+		schemas[i] = From(v)
+	}
+	return schemas
+}
+
+func (c *converter) typeToSchema(t reflect.Type) *JSON {
 	schema := &JSON{}
 
 	if t.Kind() == reflect.Ptr {
 		t = t.Elem()
 		schema.Nullable = true
 	}
+
+	if registered, ok := registeredType(t); ok {
+		registered.Nullable = registered.Nullable || schema.Nullable
+		return registered
+	}
+
+	if custom, ok := reflect.New(t).Interface().(Schemaer); ok {
+		if result := custom.JSONSchema(); result != nil {
+			result = result.Clone() // adjusted by the field and dialect, while the method may return a shared schema
+			result.Nullable = result.Nullable || schema.Nullable
+			return result
+		}
+	}
+
+	if wrapper, ok := reflect.New(t).Interface().(Wrapper); ok {
+		wrapped := c.typeToSchema(wrapper.WrappedType())
+		wrapped.Nullable = wrapped.Nullable || schema.Nullable
+		return wrapped
+	}
+
+	if value, ok := sqlNullValue(t); ok { // e.g. struct{ sql.NullString } marshaled as the value it wraps
+		schema := c.typeToSchema(value)
+		schema.Nullable = true
+		return schema
+	}
+
+	if t == rawMessageType { // arbitrary JSON, leave it unconstrained
+		return schema
+	}
+
+	if format, ok := registeredFormat(t); ok {
+		schema.Type = String
+		schema.Format = &format
+		return schema
+	}
+
+	if isMarshaler(t) { // custom scalars, e.g. time.Time, marshal to a string rather than their struct layout
+		schema.Type = String
+		return schema
+	}
+
+	isBytes := t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 // []byte is base64 encoded by encoding/json
+	switch t.Kind() {
+	case reflect.Map, reflect.Struct, reflect.Slice, reflect.Array:
+		if isBytes {
+			break
+		}
+		if c.maxDepth > 0 && c.depth >= c.maxDepth { // nested too deeply, leave it unconstrained
+			return schema
+		}
+		c.depth++
+		defer func() { c.depth-- }()
+	}
+
 	switch t.Kind() {
 	case reflect.Map:
 		schema.Type = Object
 		schema.Properties = make(map[string]*JSON)
-		schema.AdditionalProperties = typeToSchema(t.Elem()) // The value type of the map, key is at t.Key()
+		if !isMapKey(t.Key()) { // encoding/json fails on such maps, so leave the object unconstrained rather than guess
+			c.errorf("%s: map keys can't be encoded as JSON object keys", t)
+			break
+		}
+		schema.AdditionalProperties = c.typeToSchema(t.Elem()) // The value type of the map, keys being strings in JSON
 
 	case reflect.Struct:
 		schema.Type = Object
 		schema.Properties = make(map[string]*JSON)
+		if c.visiting[t] { // a recursive type can't be inlined, so leave the object unconstrained rather than recurse forever
+			c.errorf("%s: recursive type", t)
+			break
+		}
+		c.visiting[t] = true
+		defer delete(c.visiting, t)
 		schema.Required = []string{}
+		xmlFields := describesXML(t)
+		if field, ok := t.FieldByName("XMLName"); ok && field.Type == xmlNameType { // the element name of the struct
+			if space, name := xmlName(field.Tag.Get("xml")); name != "" {
+				schema.XML = &XMLObject{Name: name, Namespace: space}
+			}
+		}
 
-		for i := 0; i < t.NumField(); i++ {
-			field := t.Field(i)
-
-			// Skip unexported fields
-			if !field.IsExported() {
+		for _, field := range jsonFields(t) {
+			if unsupported(field.Type) { // encoding/json fails on the struct, so leave the field out rather than guess
+				c.errorf("%s.%s: %s can't be encoded as JSON", t, field.Name, field.Type)
 				continue
 			}
 
-			// Get the JSON field name from the json tag
-			jsonTag := field.Tag.Get("json")
-			name := strings.Split(jsonTag, ",")[0]
-			if name == "-" {
-				continue
+			// Check if this field is required, fields promoted through a pointer are left out while it is nil.
+			// A json-required tag overrides it, e.g. for omitempty fields that must still be sent in requests
+			tag := field.Tag.Get("json")
+			required := !strings.Contains(tag, "omitempty") && !strings.Contains(tag, "omitzero") && !field.viaPointer
+			if tagged := getBoolFromField(field.StructField, "json-required"); tagged != nil {
+				required = *tagged
 			}
-			if name == "" {
-				name = field.Name
+			if required {
+				schema.Required = append(schema.Required, field.name)
 			}
 
-			// Check if this field is required
-			if !strings.Contains(jsonTag, "omitempty") {
-				schema.Required = append(schema.Required, name)
+			fieldSchema := c.fieldToSchema(field.StructField)
+			c.checkTags(t, field.StructField, fieldSchema)
+			if fieldSchema != nil && xmlFields {
+				applyXMLTag(fieldSchema, field.StructField)
 			}
-
-			fieldSchema := fieldToSchema(field)
 			if fieldSchema != nil {
-				schema.Properties[name] = fieldSchema
+				schema.Properties[field.name] = fieldSchema
+				if c.propertyOrdering {
+					schema.PropertyOrdering = append(schema.PropertyOrdering, field.name)
+				}
 			}
 		}
 
 		if len(schema.Required) == 0 {
 			schema.Required = nil
 		}
+		if c.strict {
+			closed := false
+			schema.AdditionalPropertiesBool = &closed
+		}
 
 	case reflect.Slice, reflect.Array:
+		if isBytes {
+			format := "byte"
+			schema.Type = String
+			schema.Format = &format
+			break
+		}
 		schema.Type = Array
-		schema.Items = typeToSchema(t.Elem())
+		schema.Items = c.typeToSchema(t.Elem())
 
 	case reflect.String:
 		schema.Type = String
@@ -80,27 +304,298 @@ func typeToSchema(t reflect.Type) *JSON {
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
 		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
 		schema.Type = Integer
+		schema.Format = numberFormat(t.Kind())
+		if t.Kind() >= reflect.Uint && t.Kind() <= reflect.Uint64 { // can't be negative, unless a json-minimum says more
+			minimum := 0.
+			schema.Minimum = &minimum
+		}
 
 	case reflect.Float32, reflect.Float64:
 		schema.Type = Number
+		schema.Format = numberFormat(t.Kind())
 
 	case reflect.Bool:
 		schema.Type = Boolean
+
+	case reflect.Interface:
+		// any value, e.g. the values of a map[string]any, is left as an unconstrained schema
+
+	case reflect.Chan, reflect.Func, reflect.Complex64, reflect.Complex128, reflect.UnsafePointer:
+		// encoding/json fails on these, fields of them are left out of structs and anywhere else they are unconstrained
+		c.errorf("%s can't be encoded as JSON", t)
+	}
+
+	if enum := registeredEnum(t); enum != nil {
+		schema.Enum = enum
 	}
 
 	return schema
 }
 
-func fieldToSchema(field reflect.StructField) *JSON {
-	schema := typeToSchema(field.Type)
+// jsonField is a field of a struct as encoding/json sees it, possibly promoted from an embedded struct
+type jsonField struct {
+	reflect.StructField
+	name       string
+	index      []int // of the field within the struct and those it is embedded in, for the order of encoding/json
+	depth      int   // how deeply embedded the field is, 0 for fields of the struct itself
+	tagged     bool  // named by its json tag
+	viaPointer bool  // promoted through an embedded pointer
+}
+
+// jsonFields returns the fields of the struct encoded by encoding/json, in order, promoting the fields of
+// embedded structs by the same rules: the shallowest field of a name wins, then the one named by its
+// json tag, and fields of a name that still collide are left out
+func jsonFields(t reflect.Type) []jsonField {
+	var all []jsonField
+	collectFields(t, nil, false, map[reflect.Type]bool{t: true}, &all)
+
+	var names []string
+	byName := map[string][]jsonField{}
+	for _, field := range all {
+		if _, seen := byName[field.name]; !seen {
+			names = append(names, field.name)
+		}
+		byName[field.name] = append(byName[field.name], field)
+	}
+
+	fields := make([]jsonField, 0, len(names))
+	for _, name := range names {
+		if field, ok := dominantField(byName[name]); ok {
+			fields = append(fields, field)
+		}
+	}
+	slices.SortFunc(fields, func(a, b jsonField) int {
+		return slices.Compare(a.index, b.index)
+	})
+	return fields
+}
+
+func collectFields(t reflect.Type, index []int, viaPointer bool, visiting map[reflect.Type]bool, fields *[]jsonField) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		fieldIndex := append(slices.Clip(index), i)
+
+		// Get the JSON field name from the json tag
+		jsonTag := field.Tag.Get("json")
+		name := strings.Split(jsonTag, ",")[0]
+		if name == "-" {
+			continue
+		}
+
+		// Embedded structs without a name have their fields promoted, even if the struct type isn't exported
+		if field.Anonymous && name == "" {
+			embedded, isPointer := field.Type, false
+			if embedded.Kind() == reflect.Ptr {
+				embedded, isPointer = embedded.Elem(), true
+			}
+			if embedded.Kind() == reflect.Struct && !isMarshaler(embedded) {
+				if !visiting[embedded] {
+					visiting[embedded] = true
+					collectFields(embedded, fieldIndex, viaPointer || isPointer, visiting, fields)
+					delete(visiting, embedded)
+				}
+				continue
+			}
+		}
+
+		// Skip unexported fields, a json tag on one is most likely a mistake
+		if !field.IsExported() {
+			if _, tagged := field.Tag.Lookup("json"); tagged {
+				slog.Debug("schema: skipping unexported field with json tag", "type", t.String(), "field", field.Name)
+			}
+			continue
+		}
+
+		tagged := name != ""
+		if !tagged {
+			name = field.Name
+		}
+		*fields = append(*fields, jsonField{StructField: field, name: name, index: fieldIndex, depth: len(index), tagged: tagged, viaPointer: viaPointer})
+	}
+}
+
+// dominantField picks the field encoding/json encodes among those of the same name, if any
+func dominantField(fields []jsonField) (jsonField, bool) {
+	var dominant []jsonField
+	for _, field := range fields {
+		if len(dominant) > 0 && field.depth > dominant[0].depth {
+			continue
+		}
+		if len(dominant) > 0 && field.depth < dominant[0].depth {
+			dominant = dominant[:0]
+		}
+		dominant = append(dominant, field)
+	}
+	if len(dominant) > 1 {
+		var tagged []jsonField
+		for _, field := range dominant {
+			if field.tagged {
+				tagged = append(tagged, field)
+			}
+		}
+		dominant = tagged
+	}
+	if len(dominant) != 1 {
+		return jsonField{}, false
+	}
+	return dominant[0], true
+}
+
+// sqlNullValue returns the type of the value wrapped by a nullable type of database/sql, e.g. string for
+// sql.NullString and T for sql.Null[T], that t embeds and marshals as that value or null. encoding/json encodes the
+// types themselves as objects of the value and a Valid flag, so only types adding a MarshalJSON to them are unwrapped
+func sqlNullValue(t reflect.Type) (reflect.Type, bool) {
+	if t.Kind() != reflect.Struct || t.NumField() != 1 || !t.Field(0).Anonymous {
+		return nil, false
+	}
+	if !t.Implements(jsonMarshalerType) && !reflect.PointerTo(t).Implements(jsonMarshalerType) {
+		return nil, false
+	}
+	null := t.Field(0).Type
+	if null.Kind() != reflect.Struct || null.PkgPath() != "database/sql" || !strings.HasPrefix(null.Name(), "Null") {
+		return nil, false
+	}
+	if null.NumField() != 2 || null.Field(1).Name != "Valid" {
+		return nil, false
+	}
+	return null.Field(0).Type, true
+}
+
+// isMapKey reports whether encoding/json encodes maps with keys of the type, as strings, integers
+// being written in decimal and TextMarshalers as their text
+func isMapKey(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return true
+	}
+	return t.Implements(textMarshalerType)
+}
+
+// numberFormat returns the OpenAPI format of the width of numbers of the kind, for generated clients to pick
+// a type they fit, or nil for kinds without one, e.g. int8
+func numberFormat(kind reflect.Kind) *string {
+	var format string
+	switch kind {
+	case reflect.Int32, reflect.Uint32:
+		format = "int32"
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64:
+		format = "int64"
+	case reflect.Float32:
+		format = "float"
+	case reflect.Float64:
+		format = "double"
+	default:
+		return nil
+	}
+	return &format
+}
+
+// unsupported reports whether encoding/json fails on values of t, being channels, functions, complex numbers or
+// unsafe pointers, or pointers to them, that don't marshal themselves or provide their own schema
+func unsupported(t reflect.Type) bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Chan, reflect.Func, reflect.Complex64, reflect.Complex128, reflect.UnsafePointer:
+		_, custom := reflect.New(t).Interface().(Schemaer)
+		_, registered := registeredType(t)
+		return !custom && !registered && !isMarshaler(t)
+	}
+	return false
+}
+
+// isMarshaler reports whether t, or a pointer to it, implements json.Marshaler or encoding.TextMarshaler
+func isMarshaler(t reflect.Type) bool {
+	if isIgnoredMarshaler(t) {
+		return false
+	}
+	pt := reflect.PointerTo(t)
+	return t.Implements(jsonMarshalerType) || pt.Implements(jsonMarshalerType) ||
+		t.Implements(textMarshalerType) || pt.Implements(textMarshalerType)
+}
+
+// checkTags reports the json-* tags of the field of t, converted to s, that can't be parsed, and are thus ignored,
+// or that conflict
+func (c *converter) checkTags(t reflect.Type, field reflect.StructField, s *JSON) {
+	for _, key := range []string{"json-nullable", "json-read-only", "json-write-only", "json-deprecated", "json-required"} {
+		if v := field.Tag.Get(key); v != "" && getBoolFromField(field, key) == nil {
+			c.errorf("%s.%s: %s %q is not a boolean", t, field.Name, key, v)
+		}
+	}
+	for _, key := range []string{"json-max-length", "json-min-length", "json-max-items", "json-min-items"} {
+		if v := field.Tag.Get(key); v != "" && getIntFromField(field, key) == nil {
+			c.errorf("%s.%s: %s %q is not an integer", t, field.Name, key, v)
+		}
+	}
+	for _, key := range []string{"json-minimum", "json-maximum", "json-exclusive-minimum", "json-exclusive-maximum", "json-multiple-of"} {
+		if v := field.Tag.Get(key); v != "" && getFloat64Ptr(v) == nil {
+			c.errorf("%s.%s: %s %q is not a number", t, field.Name, key, v)
+		}
+	}
+	if v := field.Tag.Get("json-pattern"); v != "" {
+		if _, err := compilePattern(v); err != nil {
+			c.errorf("%s.%s: json-pattern %q does not compile: %v", t, field.Name, v, err)
+		}
+	}
+	if v := field.Tag.Get("json-default"); v != "" && s.Default == nil {
+		c.errorf("%s.%s: json-default %q is not a valid %s", t, field.Name, v, s.Type)
+	}
+	if v := field.Tag.Get("json-example"); v != "" && s.Example == nil {
+		c.errorf("%s.%s: json-example %q is not a valid %s", t, field.Name, v, s.Type)
+	}
+	target := s
+	if s.Type == Array && s.Items != nil { // the const of arrays applies to their items
+		target = s.Items
+	}
+	if v, ok := field.Tag.Lookup("json-const"); ok && target.Const == nil {
+		c.errorf("%s.%s: json-const %q is not a valid %s", t, field.Name, v, target.Type)
+	}
+
+	_, hasConst := field.Tag.Lookup("json-const")
+	if _, hasEnum := field.Tag.Lookup("json-enum"); hasConst && hasEnum {
+		c.errorf("%s.%s: json-const overrides json-enum", t, field.Name)
+	}
+	readOnly, writeOnly := getBoolFromField(field, "json-read-only"), getBoolFromField(field, "json-write-only")
+	if readOnly != nil && *readOnly && writeOnly != nil && *writeOnly {
+		c.errorf("%s.%s: json-read-only and json-write-only both set, the field is never sent", t, field.Name)
+	}
+}
+
+func (c *converter) fieldToSchema(field reflect.StructField) *JSON {
+	schema := c.typeToSchema(field.Type)
 
 	// Override with field-specific tags
 	if desc := field.Tag.Get("json-description"); desc != "" {
 		schema.Description = desc
 	}
 	if typeName := field.Tag.Get("json-type"); typeName != "" {
+		if schema.Type != JSONType(typeName) && schema.Format != nil { // e.g. the width of an int sent as a string
+			schema.Format = nil
+		}
 		schema.Type = JSONType(typeName)
 	}
+	if nullable := getBoolFromField(field, "json-nullable"); nullable != nil { // overrides the nullability of pointers
+		schema.Nullable = *nullable
+	}
+	if readOnly := getBoolFromField(field, "json-read-only"); readOnly != nil {
+		schema.ReadOnly = *readOnly
+	}
+	if writeOnly := getBoolFromField(field, "json-write-only"); writeOnly != nil {
+		schema.WriteOnly = *writeOnly
+	}
+	if deprecated := getBoolFromField(field, "json-deprecated"); deprecated != nil {
+		schema.Deprecated = *deprecated
+	}
+
+	if def, ok := parseValue(field.Tag.Get("json-default"), schema.Type); ok {
+		schema.Default = def
+	}
+	if example, ok := parseExample(field.Tag.Get("json-example"), schema.Type); ok {
+		schema.Example = example
+	}
 
 	if schema.Type == "array" {
 		if maxItems := getIntFromField(field, "json-max-items"); maxItems != nil {
@@ -117,6 +612,94 @@ func fieldToSchema(field reflect.StructField) *JSON {
 	return schema
 }
 
+var xmlNameType = reflect.TypeFor[xml.Name]()
+
+// xmlName returns the namespace and the name of the xml tag, e.g. of "http://example.com/ns person,attr"
+func xmlName(tag string) (space string, name string) {
+	name, _, _ = strings.Cut(tag, ",")
+	if i := strings.LastIndexByte(name, ' '); i >= 0 {
+		return name[:i], name[i+1:]
+	}
+	return "", name
+}
+
+// describesXML reports whether the struct describes its XML encoding, by an XMLName field or xml tags. The fields of
+// such structs are described by the names encoding/xml gives them, whether tagged or not
+func describesXML(t reflect.Type) bool {
+	if field, ok := t.FieldByName("XMLName"); ok && field.Type == xmlNameType {
+		return true
+	}
+	for _, field := range jsonFields(t) {
+		if _, ok := field.Tag.Lookup("xml"); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// applyXMLTag describes the XML encoding of the field as encoding/xml writes it. Elements are named by the xml tag,
+// or the name of the field, unless their struct names them by its XMLName. Elements nested by the tag,
+// e.g. items>item, document arrays as wrapped, their items being named by the last element. Fields that aren't
+// elements or attributes, e.g. chardata, are marked by their node type
+func applyXMLTag(schema *JSON, field reflect.StructField) {
+	tag := field.Tag.Get("xml")
+	space, name := xmlName(tag)
+	_, options, _ := strings.Cut(tag, ",")
+	if name == "-" {
+		schema.XML = &XMLObject{NodeType: "none"}
+		return
+	}
+
+	xmlObject := &XMLObject{Namespace: space}
+	anyName := false
+	for _, option := range strings.Split(options, ",") {
+		switch option {
+		case "attr":
+			xmlObject.Attribute = true
+		case "chardata":
+			schema.XML = &XMLObject{NodeType: "text"}
+			return
+		case "cdata":
+			schema.XML = &XMLObject{NodeType: "cdata"}
+			return
+		case "innerxml", "comment":
+			schema.XML = &XMLObject{NodeType: "none"}
+			return
+		case "any":
+			anyName = true
+		}
+	}
+	tagged := name != ""
+	if !tagged && !anyName {
+		name = field.Name
+	}
+
+	parents := strings.Split(name, ">")
+	name = parents[len(parents)-1]
+	if schema.Type == Array && schema.Items != nil && !xmlObject.Attribute {
+		if name != "" {
+			item := XMLObject{Name: name}
+			if schema.Items.XML != nil {
+				item = *schema.Items.XML
+				if tagged || item.Name == "" {
+					item.Name = name
+				}
+			}
+			schema.Items.XML = &item
+		}
+		if len(parents) > 1 {
+			xmlObject.Name, xmlObject.Wrapped = parents[0], true
+		}
+	} else if !tagged && schema.XML != nil && schema.XML.Name != "" {
+		return // named by the XMLName of its struct
+	} else {
+		xmlObject.Name = name
+	}
+	if *xmlObject != (XMLObject{}) {
+		schema.XML = xmlObject
+	}
+}
+
 func applyValidationTagsToSchema(schema *JSON, field reflect.StructField) {
 	if schema == nil {
 		return
@@ -124,6 +707,9 @@ func applyValidationTagsToSchema(schema *JSON, field reflect.StructField) {
 
 	// Handle number validation
 	if schema.Type == "number" || schema.Type == "integer" {
+		if format := field.Tag.Get("json-format"); format != "" {
+			schema.Format = &format
+		}
 		if incmax := getFloat64Ptr(field.Tag.Get("json-maximum")); incmax != nil {
 			schema.Maximum = incmax
 		}
@@ -136,6 +722,9 @@ func applyValidationTagsToSchema(schema *JSON, field reflect.StructField) {
 		if excMin := getFloat64Ptr(field.Tag.Get("json-exclusive-minimum")); excMin != nil {
 			schema.ExclusiveMinimum = excMin
 		}
+		if multipleOf := getFloat64Ptr(field.Tag.Get("json-multiple-of")); multipleOf != nil {
+			schema.MultipleOf = multipleOf
+		}
 	}
 
 	// Handle string validation
@@ -154,13 +743,20 @@ func applyValidationTagsToSchema(schema *JSON, field reflect.StructField) {
 		}
 	}
 
-	// Handle enum validation
-	if enumStr := field.Tag.Get("json-enum"); enumStr != "" {
+	// Handle enum validation, a const pins a single value and wins over an enum
+	constStr, hasConst := field.Tag.Lookup("json-const")
+	if enumStr := field.Tag.Get("json-enum"); enumStr != "" && !hasConst {
 		switch schema.Type {
 		case "string", "number", "integer", "boolean":
 			schema.Enum = parseEnum(enumStr, field)
 		}
 	}
+	if hasConst {
+		switch schema.Type {
+		case "string", "number", "integer", "boolean":
+			schema.Const = parseKind(strings.TrimSpace(constStr), fieldKind(field))
+		}
+	}
 }
 
 // Helper functions
@@ -173,6 +769,15 @@ func getIntFromField(f reflect.StructField, key string) *int {
 	return nil
 }
 
+func getBoolFromField(f reflect.StructField, key string) *bool {
+	if v := f.Tag.Get(key); v != "" {
+		if b, err := strconv.ParseBool(v); err == nil {
+			return &b
+		}
+	}
+	return nil
+}
+
 func getFloat64Ptr(v string) *float64 {
 	if v != "" {
 		if f, err := strconv.ParseFloat(v, 64); err == nil {
@@ -182,10 +787,56 @@ func getFloat64Ptr(v string) *float64 {
 	return nil
 }
 
+// parseValue coerces a tag value to the JSON type of the schema, reporting false if
+// it is empty, malformed or the type has no scalar representation
+func parseValue(v string, t JSONType) (any, bool) {
+	if v == "" {
+		return nil, false
+	}
+	switch t {
+	case String:
+		return v, true
+	case Integer:
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return n, true
+		}
+	case Number:
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return f, true
+		}
+	case Boolean:
+		if b, err := strconv.ParseBool(v); err == nil {
+			return b, true
+		}
+	}
+	return nil, false
+}
+
+// parseExample coerces a tag value like parseValue, examples of objects and arrays are given as JSON
+func parseExample(v string, t JSONType) (any, bool) {
+	if t == Object || t == Array {
+		var example any
+		if err := json.Unmarshal([]byte(v), &example); err == nil {
+			return example, true
+		}
+		return nil, false
+	}
+	return parseValue(v, t)
+}
+
 func parseEnum(enumStr string, field reflect.StructField) []interface{} {
 	values := strings.Split(enumStr, ",")
 	enum := make([]interface{}, len(values))
 
+	kind := fieldKind(field)
+	for i, v := range values {
+		enum[i] = parseKind(strings.TrimSpace(v), kind)
+	}
+	return enum
+}
+
+// fieldKind returns the kind of the values of the field, looking through pointers and slices
+func fieldKind(field reflect.StructField) reflect.Kind {
 	t := field.Type
 	kind := t.Kind()
 	if kind == reflect.Ptr {
@@ -196,26 +847,26 @@ func parseEnum(enumStr string, field reflect.StructField) []interface{} {
 	if kind == reflect.Slice {
 		kind = t.Elem().Kind()
 	}
+	return kind
+}
 
-	for i, v := range values {
-		v = strings.TrimSpace(v)
-
-		switch kind {
-		case reflect.String:
-			enum[i] = v
-		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-			if n, err := strconv.ParseInt(v, 10, 64); err == nil {
-				enum[i] = n
-			}
-		case reflect.Float32, reflect.Float64:
-			if f, err := strconv.ParseFloat(v, 64); err == nil {
-				enum[i] = f
-			}
-		case reflect.Bool:
-			if b, err := strconv.ParseBool(v); err == nil {
-				enum[i] = b
-			}
+// parseKind coerces a tag value to the Go kind, returning nil if it is malformed
+func parseKind(v string, kind reflect.Kind) interface{} {
+	switch kind {
+	case reflect.String:
+		return v
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return n
+		}
+	case reflect.Float32, reflect.Float64:
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return f
+		}
+	case reflect.Bool:
+		if b, err := strconv.ParseBool(v); err == nil {
+			return b
 		}
 	}
-	return enum
+	return nil
 }
@@ -3,6 +3,7 @@ package schema_test
 import (
 	"github.com/modfin/strut/schema"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -50,13 +51,13 @@ func TestFrom_ZeroValues(t *testing.T) {
 	expected := &schema.JSON{
 		Type: schema.Object,
 		Properties: map[string]*schema.JSON{
-			"id":        {Type: schema.Integer},
+			"id":        {Type: schema.Integer, Format: ptr("int64")},
 			"name":      {Type: schema.String},
 			"is_active": {Type: schema.Boolean},
-			"score":     {Type: schema.Number},
+			"score":     {Type: schema.Number, Format: ptr("double")},
 			"empty_list": {
 				Type:  schema.Array,
-				Items: &schema.JSON{Type: schema.Integer},
+				Items: &schema.JSON{Type: schema.Integer, Format: ptr("int64")},
 			},
 		},
 		Required: []string{"id", "name", "is_active", "score", "empty_list"},
@@ -81,7 +82,7 @@ func TestFrom_NestedMapWithArrays(t *testing.T) {
 				Type: schema.Object,
 				AdditionalProperties: &schema.JSON{
 					Type:  schema.Array,
-					Items: &schema.JSON{Type: schema.Integer},
+					Items: &schema.JSON{Type: schema.Integer, Format: ptr("int64")},
 				},
 				Properties: map[string]*schema.JSON{},
 			},
@@ -95,6 +96,68 @@ func TestFrom_NestedMapWithArrays(t *testing.T) {
 	}
 }
 
+func TestFrom_MapKeyTypes(t *testing.T) {
+	// Keys are strings in JSON whatever their Go type, maps encoding/json can't encode are left unconstrained
+	type Currency string
+	type Point struct{ X, Y int }
+	type MapKeysStruct struct {
+		ByID       map[int]string    `json:"by_id"`
+		ByCurrency map[Currency]int  `json:"by_currency"`
+		ByPoint    map[Point]float64 `json:"by_point"`
+	}
+
+	expected := &schema.JSON{
+		Type: schema.Object,
+		Properties: map[string]*schema.JSON{
+			"by_id": {
+				Type:                 schema.Object,
+				Properties:           map[string]*schema.JSON{},
+				AdditionalProperties: &schema.JSON{Type: schema.String},
+			},
+			"by_currency": {
+				Type:                 schema.Object,
+				Properties:           map[string]*schema.JSON{},
+				AdditionalProperties: &schema.JSON{Type: schema.Integer, Format: ptr("int64")},
+			},
+			"by_point": {
+				Type:       schema.Object,
+				Properties: map[string]*schema.JSON{},
+			},
+		},
+		Required: []string{"by_id", "by_currency", "by_point"},
+	}
+
+	result := schema.From(MapKeysStruct{})
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, result)
+	}
+}
+
+func TestFrom_UnsupportedKinds(t *testing.T) {
+	// Fields encoding/json can't encode are left out, and unconstrained anywhere else
+	type Job struct {
+		Name     string         `json:"name"`
+		Done     chan bool      `json:"done"`
+		Callback func()         `json:"callback"`
+		Signal   *complex128    `json:"signal,omitempty"`
+		Hooks    []func() error `json:"hooks"`
+	}
+
+	expected := &schema.JSON{
+		Type: schema.Object,
+		Properties: map[string]*schema.JSON{
+			"name":  {Type: schema.String},
+			"hooks": {Type: schema.Array, Items: &schema.JSON{}},
+		},
+		Required: []string{"name", "hooks"},
+	}
+
+	result := schema.From(Job{})
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, result)
+	}
+}
+
 func TestFrom_MapWithEnumKeys(t *testing.T) {
 	// Test handling of maps with specific key types
 	type MapWithEnumKeysStruct struct {
@@ -165,7 +228,7 @@ func TestFrom_MultipleStructsWithSameField(t *testing.T) {
 	expected1 := &schema.JSON{
 		Type: schema.Object,
 		Properties: map[string]*schema.JSON{
-			"value": {Type: schema.Integer},
+			"value": {Type: schema.Integer, Format: ptr("int64")},
 		},
 		Required: []string{"value"},
 	}
@@ -227,7 +290,7 @@ func TestFrom_PointerToArray(t *testing.T) {
 			"items": {
 				Type:     schema.Array,
 				Nullable: true,
-				Items:    &schema.JSON{Type: schema.Integer},
+				Items:    &schema.JSON{Type: schema.Integer, Format: ptr("int64")},
 			},
 		},
 		Required: []string{"items"},
@@ -238,3 +301,49 @@ func TestFrom_PointerToArray(t *testing.T) {
 		t.Errorf("Expected %+v, got %+v", expected, result)
 	}
 }
+
+func TestFromE(t *testing.T) {
+	type Node struct {
+		Name     string `json:"name"`
+		Children []Node `json:"children"`
+	}
+	type Task struct {
+		Title    string    `json:"title" json-max-length:"long"`
+		Done     chan bool `json:"done"`
+		Priority int       `json:"priority" json-const:"1" json-enum:"1,2,3"`
+		Secret   string    `json:"secret" json-read-only:"true" json-write-only:"true"`
+		Tree     *Node     `json:"tree"`
+	}
+
+	result, err := schema.FromE(Task{})
+	if err == nil {
+		t.Fatal("Expected an error")
+	}
+	for _, problem := range []string{
+		"Task.Title: json-max-length \"long\" is not an integer",
+		"Task.Done: chan bool can't be encoded as JSON",
+		"Task.Priority: json-const overrides json-enum",
+		"Task.Secret: json-read-only and json-write-only both set",
+		"Node: recursive type",
+	} {
+		if !strings.Contains(err.Error(), problem) {
+			t.Errorf("Expected %q to be reported, got %v", problem, err)
+		}
+	}
+
+	// The best-effort schema is still returned, recursion stopping at an unconstrained object
+	children := result.Properties["tree"].Properties["children"]
+	if children == nil || children.Items.Type != schema.Object || len(children.Items.Properties) != 0 {
+		t.Errorf("Expected the recursive children to be unconstrained objects, got %+v", children)
+	}
+
+	// Cached schemas keep their error
+	if _, err := schema.FromE(Task{}); err == nil {
+		t.Error("Expected the error to be returned for a cached schema")
+	}
+	if _, err := schema.FromE(struct {
+		Name string `json:"name"`
+	}{}); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+}
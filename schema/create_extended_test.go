@@ -1,9 +1,11 @@
 package schema_test
 
 import (
+	"encoding/json"
 	"github.com/modfin/strut/schema"
 	"reflect"
 	"testing"
+	"time"
 )
 
 func TestFrom_TimeType(t *testing.T) {
@@ -61,7 +63,7 @@ func TestFrom_MapOfStructs(t *testing.T) {
 				AdditionalProperties: &schema.JSON{
 					Type: schema.Object,
 					Properties: map[string]*schema.JSON{
-						"id":   {Type: schema.Integer},
+						"id":   {Type: schema.Integer, Format: ptr("int64")},
 						"name": {Type: schema.String},
 					},
 					Required: []string{"id", "name"},
@@ -125,7 +127,7 @@ func TestFrom_NestedArrays(t *testing.T) {
 				MinItems: &minItems,
 				Items: &schema.JSON{
 					Type:  schema.Array,
-					Items: &schema.JSON{Type: schema.Integer},
+					Items: &schema.JSON{Type: schema.Integer, Format: ptr("int64")},
 				},
 			},
 		},
@@ -138,9 +140,98 @@ func TestFrom_NestedArrays(t *testing.T) {
 	}
 }
 
+type Person struct {
+	Name  string `json:"name"`
+	Email string `json:"email,omitempty"`
+}
+
+type Audited struct {
+	CreatedBy string `json:"created_by"`
+	Secret    string `json:"-"`
+}
+
+type Timestamps struct {
+	Audited
+	CreatedAt string `json:"created_at"`
+}
+
 func TestFrom_EmbeddedStruct(t *testing.T) {
-	// Skip this test as embedded struct handling is different than expected
-	t.Skip("Skipping embedded struct test as it requires special handling")
+	// Fields of embedded structs are promoted like encoding/json does, however deeply embedded
+	type PersonWithAge struct {
+		Person
+		Timestamps
+		Age int `json:"age"`
+	}
+
+	expected := &schema.JSON{
+		Type: schema.Object,
+		Properties: map[string]*schema.JSON{
+			"name":       {Type: schema.String},
+			"email":      {Type: schema.String},
+			"created_by": {Type: schema.String},
+			"created_at": {Type: schema.String},
+			"age":        {Type: schema.Integer, Format: ptr("int64")},
+		},
+		Required: []string{"name", "created_by", "created_at", "age"},
+	}
+
+	result := schema.From(PersonWithAge{})
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, result)
+	}
+}
+
+func TestFrom_EmbeddedStructPromotionRules(t *testing.T) {
+	type Named struct {
+		Name string `json:"name"`
+	}
+	type Titled struct {
+		Name string
+	}
+	type Shadowed struct {
+		*Person        // promoted through a pointer, so not required
+		Named          // name collides with Person.Name at the same depth, the tagged ones tie
+		Titled         // untagged, so encoded as Name which no other field is
+		Email   string `json:"email"`  // shallower than Person.Email
+		Nested  Named  `json:"nested"` // named, so not promoted
+	}
+
+	expected := &schema.JSON{
+		Type: schema.Object,
+		Properties: map[string]*schema.JSON{
+			"Name":  {Type: schema.String},
+			"email": {Type: schema.String},
+			"nested": {
+				Type:       schema.Object,
+				Properties: map[string]*schema.JSON{"name": {Type: schema.String}},
+				Required:   []string{"name"},
+			},
+		},
+		Required: []string{"Name", "email", "nested"},
+	}
+
+	result := schema.From(Shadowed{})
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, result)
+	}
+
+	// The properties are the keys encoding/json writes
+	b, err := json.Marshal(Shadowed{Person: &Person{Email: "ignored"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var encoded map[string]any
+	if err := json.Unmarshal(b, &encoded); err != nil {
+		t.Fatal(err)
+	}
+	for name := range encoded {
+		if _, ok := result.Properties[name]; !ok {
+			t.Errorf("Expected property %s, encoded as %s", name, b)
+		}
+	}
+	if len(encoded) != len(result.Properties) {
+		t.Errorf("Expected the properties of %s, got %+v", b, result.Properties)
+	}
 }
 
 func TestFrom_CustomTypes(t *testing.T) {
@@ -156,7 +247,7 @@ func TestFrom_CustomTypes(t *testing.T) {
 	expected := &schema.JSON{
 		Type: schema.Object,
 		Properties: map[string]*schema.JSON{
-			"id":    {Type: schema.Integer},
+			"id":    {Type: schema.Integer, Format: ptr("int64")},
 			"email": {Type: schema.String},
 		},
 		Required: []string{"id", "email"},
@@ -167,3 +258,376 @@ func TestFrom_CustomTypes(t *testing.T) {
 		t.Errorf("Expected %+v, got %+v", expected, result)
 	}
 }
+
+func TestFrom_DefaultTag(t *testing.T) {
+	type Config struct {
+		Name    string   `json:"name" json-default:"service"`
+		Workers int      `json:"workers" json-default:"4"`
+		Ratio   float64  `json:"ratio" json-default:"0.5"`
+		Verbose bool     `json:"verbose" json-default:"false"`
+		Retries *int     `json:"retries" json-default:"3"`
+		Port    string   `json:"port" json-type:"integer" json-default:"8080"`
+		Broken  int      `json:"broken" json-default:"many"`
+		Hosts   []string `json:"hosts" json-default:"localhost"`
+	}
+
+	expected := &schema.JSON{
+		Type: schema.Object,
+		Properties: map[string]*schema.JSON{
+			"name":    {Type: schema.String, Default: "service"},
+			"workers": {Type: schema.Integer, Format: ptr("int64"), Default: int64(4)},
+			"ratio":   {Type: schema.Number, Format: ptr("double"), Default: 0.5},
+			"verbose": {Type: schema.Boolean, Default: false},
+			"retries": {Type: schema.Integer, Format: ptr("int64"), Default: int64(3), Nullable: true},
+			"port":    {Type: schema.Integer, Default: int64(8080)},
+			"broken":  {Type: schema.Integer, Format: ptr("int64")},
+			"hosts":   {Type: schema.Array, Items: &schema.JSON{Type: schema.String}},
+		},
+		Required: []string{"name", "workers", "ratio", "verbose", "retries", "port", "broken", "hosts"},
+	}
+
+	result := schema.From(Config{})
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, result)
+	}
+}
+
+func TestFrom_ExampleTag(t *testing.T) {
+	type Product struct {
+		Name   string            `json:"name" json-example:"Coffee mug"`
+		Price  float64           `json:"price" json-example:"9.95"`
+		Stock  int               `json:"stock" json-example:"12"`
+		Tags   []string          `json:"tags" json-example:"[\"kitchen\",\"ceramic\"]"`
+		Labels map[string]string `json:"labels" json-example:"{\"color\":\"blue\"}"`
+		Broken int               `json:"broken" json-example:"lots"`
+	}
+
+	expected := &schema.JSON{
+		Type: schema.Object,
+		Properties: map[string]*schema.JSON{
+			"name":   {Type: schema.String, Example: "Coffee mug"},
+			"price":  {Type: schema.Number, Format: ptr("double"), Example: 9.95},
+			"stock":  {Type: schema.Integer, Format: ptr("int64"), Example: int64(12)},
+			"tags":   {Type: schema.Array, Items: &schema.JSON{Type: schema.String}, Example: []any{"kitchen", "ceramic"}},
+			"labels": {Type: schema.Object, Properties: map[string]*schema.JSON{}, AdditionalProperties: &schema.JSON{Type: schema.String}, Example: map[string]any{"color": "blue"}},
+			"broken": {Type: schema.Integer, Format: ptr("int64")},
+		},
+		Required: []string{"name", "price", "stock", "tags", "labels", "broken"},
+	}
+
+	result := schema.From(Product{})
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, result)
+	}
+}
+
+func TestFrom_MultipleOfTag(t *testing.T) {
+	type Payment struct {
+		Amount   float64   `json:"amount" json-multiple-of:"0.01"`
+		Quantity int       `json:"quantity" json-multiple-of:"5"`
+		Steps    []float64 `json:"steps" json-multiple-of:"0.5"`
+		Name     string    `json:"name" json-multiple-of:"2"`
+		Broken   int       `json:"broken" json-multiple-of:"often"`
+	}
+
+	cent, five, half := 0.01, 5.0, 0.5
+	expected := &schema.JSON{
+		Type: schema.Object,
+		Properties: map[string]*schema.JSON{
+			"amount":   {Type: schema.Number, Format: ptr("double"), MultipleOf: &cent},
+			"quantity": {Type: schema.Integer, Format: ptr("int64"), MultipleOf: &five},
+			"steps":    {Type: schema.Array, Items: &schema.JSON{Type: schema.Number, Format: ptr("double"), MultipleOf: &half}},
+			"name":     {Type: schema.String},
+			"broken":   {Type: schema.Integer, Format: ptr("int64")},
+		},
+		Required: []string{"amount", "quantity", "steps", "name", "broken"},
+	}
+
+	result := schema.From(Payment{})
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, result)
+	}
+}
+
+func TestFrom_ReadWriteOnlyTags(t *testing.T) {
+	// One struct serves both as request and response, readOnly fields are only sent by the server
+	type Order struct {
+		ID        string    `json:"id" json-read-only:"true"`
+		CreatedAt time.Time `json:"created_at" json-read-only:"true"`
+		Password  string    `json:"password" json-write-only:"true"`
+		Note      string    `json:"note" json-read-only:"false"`
+		Broken    string    `json:"broken" json-read-only:"maybe"`
+	}
+
+	expected := &schema.JSON{
+		Type: schema.Object,
+		Properties: map[string]*schema.JSON{
+			"id":         {Type: schema.String, ReadOnly: true},
+			"created_at": {Type: schema.String, ReadOnly: true},
+			"password":   {Type: schema.String, WriteOnly: true},
+			"note":       {Type: schema.String},
+			"broken":     {Type: schema.String},
+		},
+		Required: []string{"id", "created_at", "password", "note", "broken"},
+	}
+
+	result := schema.From(Order{})
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, result)
+	}
+
+	b, err := json.Marshal(result.Properties["note"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != `{"type":"string"}` {
+		t.Errorf("Expected readOnly to be omitted when false, got %s", b)
+	}
+}
+
+func TestFrom_DeprecatedTag(t *testing.T) {
+	type Line struct {
+		SKU     string `json:"sku"`
+		Product string `json:"product" json-deprecated:"true"`
+	}
+	type Order struct {
+		Total    float64  `json:"total"`
+		Amount   float64  `json:"amount" json-deprecated:"true"`
+		Lines    []Line   `json:"lines"`
+		Coupons  []string `json:"coupons" json-deprecated:"true"`
+		Shipping struct {
+			Carrier string `json:"carrier" json-deprecated:"true"`
+		} `json:"shipping"`
+	}
+
+	expected := &schema.JSON{
+		Type: schema.Object,
+		Properties: map[string]*schema.JSON{
+			"total":  {Type: schema.Number, Format: ptr("double")},
+			"amount": {Type: schema.Number, Format: ptr("double"), Deprecated: true},
+			"lines": {Type: schema.Array, Items: &schema.JSON{
+				Type: schema.Object,
+				Properties: map[string]*schema.JSON{
+					"sku":     {Type: schema.String},
+					"product": {Type: schema.String, Deprecated: true},
+				},
+				Required: []string{"sku", "product"},
+			}},
+			"coupons": {Type: schema.Array, Items: &schema.JSON{Type: schema.String}, Deprecated: true},
+			"shipping": {
+				Type:       schema.Object,
+				Properties: map[string]*schema.JSON{"carrier": {Type: schema.String, Deprecated: true}},
+				Required:   []string{"carrier"},
+			},
+		},
+		Required: []string{"total", "amount", "lines", "coupons", "shipping"},
+	}
+
+	result := schema.From(Order{})
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, result)
+	}
+
+	b, err := json.Marshal(result.Properties["amount"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != `{"deprecated":true,"type":"number","format":"double"}` {
+		t.Errorf("Expected deprecated to be encoded, got %s", b)
+	}
+}
+
+func TestFrom_RequiredTag(t *testing.T) {
+	type Embedded struct {
+		Region string `json:"region" json-required:"true"`
+	}
+	type Signup struct {
+		*Embedded
+		Email    string `json:"email,omitempty" json-required:"true"`
+		Nickname string `json:"nickname" json-required:"false"`
+		Referrer string `json:"referrer,omitempty"`
+		Coupon   string `json:"coupon,omitzero"`
+		Name     string `json:"name"`
+		Broken   string `json:"broken,omitempty" json-required:"sometimes"`
+	}
+
+	result := schema.From(Signup{})
+	expected := []string{"region", "email", "name"}
+	if !reflect.DeepEqual(result.Required, expected) {
+		t.Errorf("Expected %v, got %v", expected, result.Required)
+	}
+}
+
+func TestFrom_NullableTag(t *testing.T) {
+	type Stats struct {
+		Average  float64  `json:"average" json-nullable:"true"`
+		Median   *float64 `json:"median"`
+		Count    *int     `json:"count" json-nullable:"false"`
+		Labels   []string `json:"labels" json-nullable:"true"`
+		Computed string   `json:"computed" json-nullable:"perhaps"`
+	}
+
+	expected := &schema.JSON{
+		Type: schema.Object,
+		Properties: map[string]*schema.JSON{
+			"average":  {Type: schema.Number, Format: ptr("double"), Nullable: true},
+			"median":   {Type: schema.Number, Format: ptr("double"), Nullable: true},
+			"count":    {Type: schema.Integer, Format: ptr("int64")},
+			"labels":   {Type: schema.Array, Items: &schema.JSON{Type: schema.String}, Nullable: true},
+			"computed": {Type: schema.String},
+		},
+		Required: []string{"average", "median", "count", "labels", "computed"},
+	}
+
+	result := schema.From(Stats{})
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, result)
+	}
+}
+
+func TestFrom_PropertyOrdering(t *testing.T) {
+	type Line struct {
+		SKU      string `json:"sku"`
+		Quantity int    `json:"quantity"`
+	}
+	type Answer struct {
+		Reasoning string `json:"reasoning"`
+		Lines     []Line `json:"lines"`
+		Skipped   string `json:"-"`
+		Answer    string `json:"answer,omitempty"`
+	}
+
+	result := schema.From(Answer{}, schema.WithPropertyOrdering())
+	if expected := []string{"reasoning", "lines", "answer"}; !reflect.DeepEqual(result.PropertyOrdering, expected) {
+		t.Errorf("Expected %v, got %v", expected, result.PropertyOrdering)
+	}
+	if expected := []string{"sku", "quantity"}; !reflect.DeepEqual(result.Properties["lines"].Items.PropertyOrdering, expected) {
+		t.Errorf("Expected %v for nested structs, got %v", expected, result.Properties["lines"].Items.PropertyOrdering)
+	}
+
+	// Ordering is opt-in, and cached separately
+	if plain := schema.From(Answer{}); plain.PropertyOrdering != nil {
+		t.Errorf("Expected no ordering without the option, got %v", plain.PropertyOrdering)
+	}
+
+	b, err := json.Marshal(schema.ForStructuredOutput(result).Properties["lines"].Items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"type":"object","properties":{"quantity":{"type":"integer"},"sku":{"type":"string"}},"propertyOrdering":["sku","quantity"],"required":["sku","quantity"],"additionalProperties":false}`
+	if string(b) != expected {
+		t.Errorf("Expected %s, got %s", expected, b)
+	}
+}
+
+func TestFrom_Strict(t *testing.T) {
+	type Line struct {
+		SKU string `json:"sku"`
+	}
+	type Order struct {
+		Lines []Line          `json:"lines"`
+		Tags  map[string]Line `json:"tags,omitempty"`
+	}
+
+	b, err := json.Marshal(schema.From(Order{}, schema.Strict()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	line := `{"type":"object","properties":{"sku":{"type":"string"}},"required":["sku"],"additionalProperties":false}`
+	expected := `{"type":"object","properties":{"lines":{"type":"array","items":` + line + `},` +
+		`"tags":{"type":"object","additionalProperties":` + line + `}},"required":["lines"],"additionalProperties":false}`
+	if string(b) != expected {
+		t.Errorf("Expected %s, got %s", expected, b)
+	}
+
+	// Strictness is opt-in, and cached separately
+	if plain := schema.From(Order{}); plain.AdditionalPropertiesBool != nil {
+		t.Errorf("Expected open objects without the option, got %v", *plain.AdditionalPropertiesBool)
+	}
+}
+
+func TestFrom_NumberFormats(t *testing.T) {
+	type Reading struct {
+		Count    int     `json:"count"`
+		Small    int8    `json:"small"`
+		Sequence int32   `json:"sequence"`
+		Flags    uint32  `json:"flags"`
+		Total    uint64  `json:"total"`
+		Ratio    float32 `json:"ratio"`
+		Value    float64 `json:"value"`
+		Cents    int64   `json:"cents" json-format:"int32"`
+		ID       int64   `json:"id" json-type:"string"`
+	}
+
+	result := schema.From(Reading{})
+	expected := map[string]*string{
+		"count":    ptr("int64"),
+		"small":    nil,
+		"sequence": ptr("int32"),
+		"flags":    ptr("int32"),
+		"total":    ptr("int64"),
+		"ratio":    ptr("float"),
+		"value":    ptr("double"),
+		"cents":    ptr("int32"),
+		"id":       nil,
+	}
+	for name, format := range expected {
+		if !reflect.DeepEqual(result.Properties[name].Format, format) {
+			t.Errorf("Expected format of %s to be %v, got %v", name, format, result.Properties[name].Format)
+		}
+	}
+}
+
+func TestFrom_UnsignedMinimum(t *testing.T) {
+	type Inventory struct {
+		Stock    uint   `json:"stock"`
+		Reserved uint64 `json:"reserved"`
+		Shelf    uint8  `json:"shelf" json-minimum:"1"`
+		Delta    int    `json:"delta"`
+	}
+
+	result := schema.From(Inventory{})
+	expected := map[string]*float64{
+		"stock":    ptr(0.),
+		"reserved": ptr(0.),
+		"shelf":    ptr(1.),
+		"delta":    nil,
+	}
+	for name, minimum := range expected {
+		if !reflect.DeepEqual(result.Properties[name].Minimum, minimum) {
+			t.Errorf("Expected minimum of %s to be %v, got %v", name, minimum, result.Properties[name].Minimum)
+		}
+	}
+}
+
+func TestFrom_MaxDepth(t *testing.T) {
+	type Address struct {
+		City string `json:"city"`
+	}
+	type Customer struct {
+		Name    string  `json:"name"`
+		Address Address `json:"address"`
+		Avatar  []byte  `json:"avatar"`
+	}
+	type Order struct {
+		ID       string     `json:"id"`
+		Customer Customer   `json:"customer"`
+		Lines    [][]string `json:"lines"`
+	}
+
+	b, err := json.Marshal(schema.From(Order{}, schema.MaxDepth(2)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	customer := `{"type":"object","properties":{"address":{},"avatar":{"type":"string","format":"byte"},"name":{"type":"string"}},"required":["name","address","avatar"]}`
+	expected := `{"type":"object","properties":{"customer":` + customer + `,"id":{"type":"string"},` +
+		`"lines":{"type":"array","items":{}}},"required":["id","customer","lines"]}`
+	if string(b) != expected {
+		t.Errorf("Expected %s, got %s", expected, b)
+	}
+
+	// Depth is unlimited by default, and cached separately
+	if plain := schema.From(Order{}); plain.Properties["customer"].Properties["address"].Type != schema.Object {
+		t.Errorf("Expected nested objects without the option, got %+v", plain.Properties["customer"].Properties["address"])
+	}
+}
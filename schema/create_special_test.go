@@ -1,9 +1,14 @@
 package schema_test
 
 import (
+	"database/sql"
+	"encoding/json"
+	"encoding/xml"
 	"github.com/modfin/strut/schema"
+	"net"
 	"reflect"
 	"testing"
+	"time"
 )
 
 func TestFrom_StructWithAnonymousFields(t *testing.T) {
@@ -39,7 +44,7 @@ func TestFrom_ArrayOfMaps(t *testing.T) {
 				Type: schema.Array,
 				Items: &schema.JSON{
 					Type:                 schema.Object,
-					AdditionalProperties: &schema.JSON{Type: schema.Integer},
+					AdditionalProperties: &schema.JSON{Type: schema.Integer, Format: ptr("int64")},
 					Properties:           map[string]*schema.JSON{},
 				},
 			},
@@ -80,23 +85,23 @@ func TestFrom_StructWithJsonNameOverrides(t *testing.T) {
 func TestFrom_StructWithMultipleOmitEmpty(t *testing.T) {
 	// Test handling of structs with multiple omitempty fields
 	type StructWithMultipleOmitEmpty struct {
-		ID        int     `json:"id"`
-		Name      string  `json:"name,omitempty"`
-		Email     string  `json:"email,omitempty"`
-		Age       int     `json:"age,omitempty"`
-		IsActive  bool    `json:"is_active,omitempty"`
-		Score     float64 `json:"score,omitempty"`
+		ID       int     `json:"id"`
+		Name     string  `json:"name,omitempty"`
+		Email    string  `json:"email,omitempty"`
+		Age      int     `json:"age,omitempty"`
+		IsActive bool    `json:"is_active,omitempty"`
+		Score    float64 `json:"score,omitempty"`
 	}
 
 	expected := &schema.JSON{
 		Type: schema.Object,
 		Properties: map[string]*schema.JSON{
-			"id":        {Type: schema.Integer},
+			"id":        {Type: schema.Integer, Format: ptr("int64")},
 			"name":      {Type: schema.String},
 			"email":     {Type: schema.String},
-			"age":       {Type: schema.Integer},
+			"age":       {Type: schema.Integer, Format: ptr("int64")},
 			"is_active": {Type: schema.Boolean},
-			"score":     {Type: schema.Number},
+			"score":     {Type: schema.Number, Format: ptr("double")},
 		},
 		Required: []string{"id"},
 	}
@@ -125,11 +130,11 @@ func TestFrom_StructWithMixedArrayTypes(t *testing.T) {
 			},
 			"int_array": {
 				Type:  schema.Array,
-				Items: &schema.JSON{Type: schema.Integer},
+				Items: &schema.JSON{Type: schema.Integer, Format: ptr("int64")},
 			},
 			"float_array": {
 				Type:  schema.Array,
-				Items: &schema.JSON{Type: schema.Number},
+				Items: &schema.JSON{Type: schema.Number, Format: ptr("double")},
 			},
 			"bool_array": {
 				Type:  schema.Array,
@@ -162,7 +167,7 @@ func TestFrom_StructWithNestedAnonymousStruct(t *testing.T) {
 			"data": {
 				Type: schema.Object,
 				Properties: map[string]*schema.JSON{
-					"value": {Type: schema.Integer},
+					"value": {Type: schema.Integer, Format: ptr("int64")},
 					"key":   {Type: schema.String},
 				},
 				Required: []string{"value", "key"},
@@ -195,7 +200,7 @@ func TestFrom_StructWithNestedPointerToAnonymousStruct(t *testing.T) {
 				Type:     schema.Object,
 				Nullable: true,
 				Properties: map[string]*schema.JSON{
-					"value": {Type: schema.Integer},
+					"value": {Type: schema.Integer, Format: ptr("int64")},
 					"key":   {Type: schema.String},
 				},
 				Required: []string{"value", "key"},
@@ -209,3 +214,477 @@ func TestFrom_StructWithNestedPointerToAnonymousStruct(t *testing.T) {
 		t.Errorf("Expected %+v, got %+v", expected, result)
 	}
 }
+
+func TestFrom_RawMessageAndBytes(t *testing.T) {
+	// json.RawMessage holds arbitrary JSON and []byte is base64 encoded
+	type Payloads struct {
+		Raw      json.RawMessage            `json:"raw"`
+		RawPtr   *json.RawMessage           `json:"raw_ptr"`
+		RawList  []json.RawMessage          `json:"raw_list"`
+		RawMap   map[string]json.RawMessage `json:"raw_map"`
+		Data     []byte                     `json:"data"`
+		DataList [][]byte                   `json:"data_list"`
+		DataMap  map[string][]byte          `json:"data_map"`
+		Typed    json.RawMessage            `json:"typed" json-type:"object"`
+	}
+
+	byteFormat := "byte"
+	expected := &schema.JSON{
+		Type: schema.Object,
+		Properties: map[string]*schema.JSON{
+			"raw":       {},
+			"raw_ptr":   {Nullable: true},
+			"raw_list":  {Type: schema.Array, Items: &schema.JSON{}},
+			"raw_map":   {Type: schema.Object, Properties: map[string]*schema.JSON{}, AdditionalProperties: &schema.JSON{}},
+			"data":      {Type: schema.String, Format: &byteFormat},
+			"data_list": {Type: schema.Array, Items: &schema.JSON{Type: schema.String, Format: &byteFormat}},
+			"data_map":  {Type: schema.Object, Properties: map[string]*schema.JSON{}, AdditionalProperties: &schema.JSON{Type: schema.String, Format: &byteFormat}},
+			"typed":     {Type: schema.Object},
+		},
+		Required: []string{"raw", "raw_ptr", "raw_list", "raw_map", "data", "data_list", "data_map", "typed"},
+	}
+
+	result := schema.From(Payloads{})
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, result)
+	}
+}
+
+type Currency struct {
+	Code  string
+	Minor int
+}
+
+func (c Currency) MarshalJSON() ([]byte, error) {
+	return json.Marshal(c.Code)
+}
+
+type Level int
+
+func (l *Level) MarshalText() ([]byte, error) {
+	return []byte("info"), nil
+}
+
+type Point struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+func (p Point) MarshalJSON() ([]byte, error) {
+	type plain Point
+	return json.Marshal(plain(p))
+}
+
+func TestFrom_Marshalers(t *testing.T) {
+	// Types marshaling themselves are documented as strings rather than by their layout,
+	// unless opted out with IgnoreMarshaler
+	schema.IgnoreMarshaler(reflect.TypeOf(Point{}))
+
+	type Price struct {
+		Currency Currency   `json:"currency"`
+		Level    Level      `json:"level"`
+		At       time.Time  `json:"at"`
+		Previous *time.Time `json:"previous"`
+		Origin   Point      `json:"origin"`
+	}
+
+	expected := &schema.JSON{
+		Type: schema.Object,
+		Properties: map[string]*schema.JSON{
+			"currency": {Type: schema.String},
+			"level":    {Type: schema.String},
+			"at":       {Type: schema.String},
+			"previous": {Type: schema.String, Nullable: true},
+			"origin": {
+				Type: schema.Object,
+				Properties: map[string]*schema.JSON{
+					"x": {Type: schema.Number, Format: ptr("double")},
+					"y": {Type: schema.Number, Format: ptr("double")},
+				},
+				Required: []string{"x", "y"},
+			},
+		},
+		Required: []string{"currency", "level", "at", "previous", "origin"},
+	}
+
+	result := schema.From(Price{})
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, result)
+	}
+}
+
+type Money struct {
+	Amount   int64
+	Currency string
+}
+
+func (Money) JSONSchema() *schema.JSON {
+	pattern := "^[0-9]+\\.[0-9]{2} [A-Z]{3}$"
+	return &schema.JSON{Type: schema.String, Pattern: &pattern, Description: "Amount and currency, e.g. 10.00 SEK"}
+}
+
+func TestFrom_Schemaer(t *testing.T) {
+	// Types implementing Schemaer provide their schema verbatim, wherever they appear
+	pattern := "^[0-9]+\\.[0-9]{2} [A-Z]{3}$"
+	money := func() *schema.JSON {
+		return &schema.JSON{Type: schema.String, Pattern: &pattern, Description: "Amount and currency, e.g. 10.00 SEK"}
+	}
+
+	if result := schema.From(Money{}); !reflect.DeepEqual(result, money()) {
+		t.Errorf("Expected %+v, got %+v", money(), result)
+	}
+
+	type Invoice struct {
+		Total    Money            `json:"total"`
+		Discount *Money           `json:"discount"`
+		Lines    []Money          `json:"lines"`
+		Taxes    map[string]Money `json:"taxes"`
+		Fee      Money            `json:"fee" json-description:"Handling fee"`
+	}
+
+	nullable := money()
+	nullable.Nullable = true
+	fee := money()
+	fee.Description = "Handling fee"
+	expected := &schema.JSON{
+		Type: schema.Object,
+		Properties: map[string]*schema.JSON{
+			"total":    money(),
+			"discount": nullable,
+			"lines":    {Type: schema.Array, Items: money()},
+			"taxes":    {Type: schema.Object, Properties: map[string]*schema.JSON{}, AdditionalProperties: money()},
+			"fee":      fee,
+		},
+		Required: []string{"total", "discount", "lines", "taxes", "fee"},
+	}
+
+	result := schema.From(Invoice{})
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, result)
+	}
+}
+
+// Maybe encodes as the value it wraps, or null
+type Maybe[T any] struct {
+	Value *T
+}
+
+func (Maybe[T]) WrappedType() reflect.Type {
+	return reflect.TypeFor[*T]()
+}
+
+func TestFrom_Wrapper(t *testing.T) {
+	// Wrappers are documented as the type they wrap, converted with the options of the schema they are in
+	type Size struct {
+		Width int `json:"width"`
+	}
+	type Poster struct {
+		Size Maybe[Size] `json:"size" json-description:"The size, if known"`
+	}
+
+	closed := false
+	expected := &schema.JSON{
+		Type: schema.Object,
+		Properties: map[string]*schema.JSON{
+			"size": {
+				Type:                     schema.Object,
+				Description:              "The size, if known",
+				Nullable:                 true,
+				Properties:               map[string]*schema.JSON{"width": {Type: schema.Integer, Format: ptr("int64")}},
+				Required:                 []string{"width"},
+				AdditionalPropertiesBool: &closed,
+			},
+		},
+		Required:                 []string{"size"},
+		AdditionalPropertiesBool: &closed,
+	}
+
+	result := schema.From(Poster{}, schema.Strict())
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, result)
+	}
+}
+
+// skuSchema is shared by every Sku, as schemas returned by JSONSchema methods often are
+var skuSchema = &schema.JSON{Type: schema.String, Description: "Stock keeping unit"}
+
+type Sku string
+
+func (Sku) JSONSchema() *schema.JSON {
+	return skuSchema
+}
+
+func TestFrom_SharedSchemaer(t *testing.T) {
+	// The schema returned by a JSONSchema method is copied before being adjusted by the fields using it
+	type Item struct {
+		SKU      *Sku `json:"sku" json-description:"The item"`
+		Previous Sku  `json:"previous" json-example:"A-1"`
+	}
+
+	schema.SetDialect(schema.From(Item{}), schema.JSONSchema)
+	if expected := (&schema.JSON{Type: schema.String, Description: "Stock keeping unit"}); !reflect.DeepEqual(skuSchema, expected) {
+		t.Errorf("Expected %+v to be left as is, got %+v", expected, skuSchema)
+	}
+}
+
+func TestFrom_InterfaceMapValues(t *testing.T) {
+	// map[string]any accepts any value, documented as an unconstrained additionalProperties schema
+	type Event struct {
+		Attributes map[string]any `json:"attributes"`
+		Values     []interface{}  `json:"values"`
+	}
+
+	expected := &schema.JSON{
+		Type: schema.Object,
+		Properties: map[string]*schema.JSON{
+			"attributes": {Type: schema.Object, Properties: map[string]*schema.JSON{}, AdditionalProperties: &schema.JSON{}},
+			"values":     {Type: schema.Array, Items: &schema.JSON{}},
+		},
+		Required: []string{"attributes", "values"},
+	}
+
+	result := schema.From(Event{})
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, result)
+	}
+
+	data, err := json.Marshal(result.Properties["attributes"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"type":"object","additionalProperties":{}}` {
+		t.Errorf("Expected an unconstrained additionalProperties, got %s", data)
+	}
+}
+
+type OrderPlaced struct {
+	Kind    string `json:"kind" json-enum:"placed"`
+	OrderID string `json:"order_id"`
+}
+
+type OrderShipped struct {
+	Kind    string `json:"kind" json-enum:"shipped"`
+	Carrier string `json:"carrier"`
+}
+
+type OrderEvent struct {
+	Payload any
+}
+
+func (OrderEvent) JSONSchema() *schema.JSON {
+	s := schema.OneOf(OrderPlaced{}, OrderShipped{})
+	s.Discriminator = &schema.Discriminator{PropertyName: "kind"}
+	return s
+}
+
+func TestFrom_OneOf(t *testing.T) {
+	// Polymorphic fields are documented through a Schemaer returning oneOf
+	placed := &schema.JSON{
+		Type: schema.Object,
+		Properties: map[string]*schema.JSON{
+			"kind":     {Type: schema.String, Enum: []interface{}{"placed"}},
+			"order_id": {Type: schema.String},
+		},
+		Required: []string{"kind", "order_id"},
+	}
+	shipped := &schema.JSON{
+		Type: schema.Object,
+		Properties: map[string]*schema.JSON{
+			"kind":    {Type: schema.String, Enum: []interface{}{"shipped"}},
+			"carrier": {Type: schema.String},
+		},
+		Required: []string{"kind", "carrier"},
+	}
+
+	type Envelope struct {
+		Event OrderEvent `json:"event" json-description:"The order event"`
+	}
+
+	expected := &schema.JSON{
+		Type: schema.Object,
+		Properties: map[string]*schema.JSON{
+			"event": {
+				Description:   "The order event",
+				OneOf:         []*schema.JSON{placed, shipped},
+				Discriminator: &schema.Discriminator{PropertyName: "kind"},
+			},
+		},
+		Required: []string{"event"},
+	}
+
+	result := schema.From(Envelope{})
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, result)
+	}
+
+	anyOf := schema.AnyOf("", 0)
+	expectedAnyOf := &schema.JSON{AnyOf: []*schema.JSON{{Type: schema.String}, {Type: schema.Integer, Format: ptr("int64")}}}
+	if !reflect.DeepEqual(anyOf, expectedAnyOf) {
+		t.Errorf("Expected %+v, got %+v", expectedAnyOf, anyOf)
+	}
+}
+
+func TestWithDiscriminator(t *testing.T) {
+	mapping := map[string]string{"placed": "#/components/schemas/OrderPlaced"}
+	result := schema.OneOf(OrderPlaced{}, OrderShipped{}).WithDiscriminator("kind", mapping)
+
+	expected := &schema.Discriminator{PropertyName: "kind", Mapping: mapping}
+	if !reflect.DeepEqual(result.Discriminator, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, result.Discriminator)
+	}
+
+	// Every branch must require the property for generators to pick one by it
+	type OrderCancelled struct {
+		Kind   string `json:"kind,omitempty"`
+		Reason string `json:"reason"`
+	}
+	defer func() {
+		if r := recover(); r != "schema: discriminator kind is not required by anyOf[1]" {
+			t.Errorf("Expected a panic naming the branch, got %v", r)
+		}
+	}()
+	schema.AnyOf(OrderPlaced{}, OrderCancelled{}).WithDiscriminator("kind", nil)
+}
+
+type Timeout time.Duration
+
+func (d Timeout) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+func TestFrom_TextMarshalers(t *testing.T) {
+	// encoding.TextMarshaler types are strings whatever their kind, e.g. net.IP being a byte slice
+	type Host struct {
+		Addr     net.IP             `json:"addr"`
+		Timeout  Timeout            `json:"timeout"`
+		Retries  []Timeout          `json:"retries"`
+		Aliases  map[Timeout]net.IP `json:"aliases"`
+		Fallback *net.IP            `json:"fallback"`
+	}
+
+	expected := &schema.JSON{
+		Type: schema.Object,
+		Properties: map[string]*schema.JSON{
+			"addr":     {Type: schema.String},
+			"timeout":  {Type: schema.String},
+			"retries":  {Type: schema.Array, Items: &schema.JSON{Type: schema.String}},
+			"aliases":  {Type: schema.Object, Properties: map[string]*schema.JSON{}, AdditionalProperties: &schema.JSON{Type: schema.String}},
+			"fallback": {Type: schema.String, Nullable: true},
+		},
+		Required: []string{"addr", "timeout", "retries", "aliases", "fallback"},
+	}
+
+	result := schema.From(Host{})
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, result)
+	}
+}
+
+// NullString is a sql.NullString encoded as its string, or null if not valid
+type NullString struct{ sql.NullString }
+
+func (n NullString) MarshalJSON() ([]byte, error) {
+	if !n.Valid {
+		return []byte("null"), nil
+	}
+	return json.Marshal(n.String)
+}
+
+// Nullable is a sql.Null encoded as its value, or null if not valid
+type Nullable[T any] struct{ sql.Null[T] }
+
+func (n Nullable[T]) MarshalJSON() ([]byte, error) {
+	if !n.Valid {
+		return []byte("null"), nil
+	}
+	return json.Marshal(n.V)
+}
+
+func TestFrom_SQLNullTypes(t *testing.T) {
+	// The nullable types of database/sql are documented as the value they wrap when marshaled as it, tags still
+	// winning, and as the objects encoding/json writes otherwise
+	type Customer struct {
+		Name     NullString                 `json:"name"`
+		Age      Nullable[int64]            `json:"age"`
+		SeenAt   Nullable[time.Time]        `json:"seen_at"`
+		Aliases  []NullString               `json:"aliases"`
+		Limits   map[string]Nullable[int32] `json:"limits"`
+		Code     NullString                 `json:"code" json-nullable:"false" json-type:"integer"`
+		Verified sql.NullBool               `json:"verified"`
+	}
+
+	expected := &schema.JSON{
+		Type: schema.Object,
+		Properties: map[string]*schema.JSON{
+			"name":    {Type: schema.String, Nullable: true},
+			"age":     {Type: schema.Integer, Format: ptr("int64"), Nullable: true},
+			"seen_at": {Type: schema.String, Nullable: true},
+			"aliases": {Type: schema.Array, Items: &schema.JSON{Type: schema.String, Nullable: true}},
+			"limits": {
+				Type:                 schema.Object,
+				Properties:           map[string]*schema.JSON{},
+				AdditionalProperties: &schema.JSON{Type: schema.Integer, Format: ptr("int32"), Nullable: true},
+			},
+			"code": {Type: schema.Integer},
+			"verified": {
+				Type:       schema.Object,
+				Properties: map[string]*schema.JSON{"Bool": {Type: schema.Boolean}, "Valid": {Type: schema.Boolean}},
+				Required:   []string{"Bool", "Valid"},
+			},
+		},
+		Required: []string{"name", "age", "seen_at", "aliases", "limits", "code", "verified"},
+	}
+
+	result := schema.From(Customer{})
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, result)
+	}
+}
+
+func TestFrom_XMLTags(t *testing.T) {
+	// The XML encoding is described by the xml tags, elements nested by them wrapping arrays, and untagged fields
+	// by their Go names, as encoding/xml writes them
+	type Line struct {
+		XMLName xml.Name `json:"-" xml:"line"`
+		SKU     string   `json:"sku" xml:"sku,attr"`
+	}
+	type Invoice struct {
+		XMLName xml.Name `json:"-" xml:"urn:billing invoice"`
+		ID      string   `json:"id" xml:"id,attr"`
+		Total   float64  `json:"total" xml:"amount"`
+		Notes   string   `json:"notes" xml:",chardata"`
+		Lines   []Line   `json:"lines" xml:"lines>line"`
+		Tags    []string `json:"tags" xml:"tag"`
+		Due     string   `json:"due"`
+		Secret  string   `json:"secret" xml:"-"`
+	}
+
+	expected := &schema.JSON{
+		Type: schema.Object,
+		XML:  &schema.XMLObject{Name: "invoice", Namespace: "urn:billing"},
+		Properties: map[string]*schema.JSON{
+			"id":    {Type: schema.String, XML: &schema.XMLObject{Name: "id", Attribute: true}},
+			"total": {Type: schema.Number, Format: ptr("double"), XML: &schema.XMLObject{Name: "amount"}},
+			"notes": {Type: schema.String, XML: &schema.XMLObject{NodeType: "text"}},
+			"lines": {
+				Type: schema.Array,
+				XML:  &schema.XMLObject{Name: "lines", Wrapped: true},
+				Items: &schema.JSON{
+					Type:       schema.Object,
+					XML:        &schema.XMLObject{Name: "line"},
+					Properties: map[string]*schema.JSON{"sku": {Type: schema.String, XML: &schema.XMLObject{Name: "sku", Attribute: true}}},
+					Required:   []string{"sku"},
+				},
+			},
+			"tags":   {Type: schema.Array, Items: &schema.JSON{Type: schema.String, XML: &schema.XMLObject{Name: "tag"}}},
+			"due":    {Type: schema.String, XML: &schema.XMLObject{Name: "Due"}},
+			"secret": {Type: schema.String, XML: &schema.XMLObject{NodeType: "none"}},
+		},
+		Required: []string{"id", "total", "notes", "lines", "tags", "due", "secret"},
+	}
+
+	result := schema.From(Invoice{})
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, result)
+	}
+}
@@ -1,8 +1,11 @@
 package schema_test
 
 import (
+	"bytes"
 	"github.com/modfin/strut/schema"
+	"log/slog"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -30,13 +33,13 @@ func TestOf(t *testing.T) {
 		Type: schema.Object,
 		Properties: map[string]*schema.JSON{
 			"name":  {Type: schema.String, MinLength: ptr(1), MaxLength: ptr(100)},
-			"age":   {Type: schema.Integer, Minimum: ptr(0.), Maximum: ptr(150.)},
+			"age":   {Type: schema.Integer, Format: ptr("int64"), Minimum: ptr(0.), Maximum: ptr(150.)},
 			"email": {Type: schema.String, Nullable: true},
 			"address": {
 				Type: schema.Object,
 				Properties: map[string]*schema.JSON{
 					"street":   {Type: schema.String, Description: "The street address"},
-					"number":   {Type: schema.Integer, Minimum: ptr(1.)},
+					"number":   {Type: schema.Integer, Format: ptr("int64"), Minimum: ptr(1.)},
 					"zip_code": {Type: schema.String},
 				},
 				Required: []string{"street", "number"},
@@ -47,7 +50,7 @@ func TestOf(t *testing.T) {
 					Type: schema.Object,
 					Properties: map[string]*schema.JSON{
 						"street":   {Type: schema.String, Description: "The street address"},
-						"number":   {Type: schema.Integer, Minimum: ptr(1.)},
+						"number":   {Type: schema.Integer, Format: ptr("int64"), Minimum: ptr(1.)},
 						"zip_code": {Type: schema.String},
 					},
 					Required: []string{"street", "number"},
@@ -56,7 +59,7 @@ func TestOf(t *testing.T) {
 			},
 			"tags":   {Type: schema.Array, Items: &schema.JSON{Type: schema.String, MinLength: ptr(1), MaxLength: ptr(50), Pattern: ptr("^[a-zA-Z0-9_]+$"), Enum: []any{"tag1", "tag2", "tag3"}}, MinItems: ptr(1), Nullable: true},
 			"status": {Type: schema.String, Enum: []interface{}{"active", "inactive", "pending"}},
-			"ints":   {Type: schema.Integer, Enum: []interface{}{int64(1), int64(2), int64(3)}},
+			"ints":   {Type: schema.Integer, Format: ptr("int64"), Enum: []interface{}{int64(1), int64(2), int64(3)}},
 			"labels": {
 				Type: schema.Array,
 				Items: &schema.JSON{
@@ -71,13 +74,13 @@ func TestOf(t *testing.T) {
 					Type: schema.Object,
 					Properties: map[string]*schema.JSON{
 						"street":   {Type: schema.String, Description: "The street address"},
-						"number":   {Type: schema.Integer, Minimum: ptr(1.)},
+						"number":   {Type: schema.Integer, Format: ptr("int64"), Minimum: ptr(1.)},
 						"zip_code": {Type: schema.String},
 					},
 					Required: []string{"street", "number"},
 				},
 			},
-			"map2": {Type: schema.Object, AdditionalProperties: &schema.JSON{Type: schema.Number}, Properties: map[string]*schema.JSON{}},
+			"map2": {Type: schema.Object, AdditionalProperties: &schema.JSON{Type: schema.Number, Format: ptr("double")}, Properties: map[string]*schema.JSON{}},
 		},
 		Required: []string{"name", "age", "email", "address", "addresses", "tags", "status", "ints", "labels", "map", "map2"},
 	}
@@ -114,7 +117,8 @@ func TestFrom_PrimitiveTypes(t *testing.T) {
 			name:  "integer",
 			input: 42,
 			expected: &schema.JSON{
-				Type: schema.Integer,
+				Type:   schema.Integer,
+				Format: ptr("int64"),
 			},
 		},
 		{
@@ -122,6 +126,7 @@ func TestFrom_PrimitiveTypes(t *testing.T) {
 			input: new(int),
 			expected: &schema.JSON{
 				Type:     schema.Integer,
+				Format:   ptr("int64"),
 				Nullable: true,
 			},
 		},
@@ -129,7 +134,8 @@ func TestFrom_PrimitiveTypes(t *testing.T) {
 			name:  "float",
 			input: 3.14,
 			expected: &schema.JSON{
-				Type: schema.Number,
+				Type:   schema.Number,
+				Format: ptr("double"),
 			},
 		},
 		{
@@ -137,6 +143,7 @@ func TestFrom_PrimitiveTypes(t *testing.T) {
 			input: new(float64),
 			expected: &schema.JSON{
 				Type:     schema.Number,
+				Format:   ptr("double"),
 				Nullable: true,
 			},
 		},
@@ -177,7 +184,7 @@ func TestFrom_Struct(t *testing.T) {
 		Type: schema.Object,
 		Properties: map[string]*schema.JSON{
 			"name": {Type: schema.String},
-			"age":  {Type: schema.Integer},
+			"age":  {Type: schema.Integer, Format: ptr("int64")},
 		},
 		Required: []string{"name"},
 	}
@@ -202,7 +209,7 @@ func TestFrom_NestedStruct(t *testing.T) {
 			"inner": {
 				Type: schema.Object,
 				Properties: map[string]*schema.JSON{
-					"x": {Type: schema.Integer},
+					"x": {Type: schema.Integer, Format: ptr("int64")},
 				},
 				Required: []string{"x"},
 			},
@@ -220,7 +227,7 @@ func TestFrom_Map(t *testing.T) {
 	input := map[string]int{}
 	expected := &schema.JSON{
 		Type:                 schema.Object,
-		AdditionalProperties: &schema.JSON{Type: schema.Integer},
+		AdditionalProperties: &schema.JSON{Type: schema.Integer, Format: ptr("int64")},
 		Properties:           map[string]*schema.JSON{},
 	}
 
@@ -236,12 +243,12 @@ func TestFrom_SliceAndArray(t *testing.T) {
 
 	expectedSlice := &schema.JSON{
 		Type:  schema.Array,
-		Items: &schema.JSON{Type: schema.Integer},
+		Items: &schema.JSON{Type: schema.Integer, Format: ptr("int64")},
 	}
 
 	expectedArray := &schema.JSON{
 		Type:  schema.Array,
-		Items: &schema.JSON{Type: schema.Integer},
+		Items: &schema.JSON{Type: schema.Integer, Format: ptr("int64")},
 	}
 
 	t.Run("slice", func(t *testing.T) {
@@ -281,11 +288,13 @@ func TestFrom_Tags(t *testing.T) {
 			},
 			"age": {
 				Type:    schema.Integer,
+				Format:  ptr("int64"),
 				Minimum: &minAge,
 				Maximum: &maxAge,
 			},
 			"rate": {
 				Type:             schema.Number,
+				Format:           ptr("double"),
 				ExclusiveMinimum: &excMinRate,
 				ExclusiveMaximum: &excMaxRate,
 			},
@@ -315,12 +324,14 @@ func TestFrom_Enum(t *testing.T) {
 				Enum: []interface{}{"red", "green", "blue"},
 			},
 			"status": {
-				Type: schema.Integer,
-				Enum: []interface{}{int64(200), int64(404), int64(500)},
+				Type:   schema.Integer,
+				Format: ptr("int64"),
+				Enum:   []interface{}{int64(200), int64(404), int64(500)},
 			},
 			"factor": {
-				Type: schema.Number,
-				Enum: []interface{}{1.0, 2.0, 3.0},
+				Type:   schema.Number,
+				Format: ptr("double"),
+				Enum:   []interface{}{1.0, 2.0, 3.0},
 			},
 			"enabled": {
 				Type: schema.Boolean,
@@ -336,6 +347,44 @@ func TestFrom_Enum(t *testing.T) {
 	}
 }
 
+func TestFrom_Const(t *testing.T) {
+	var buf bytes.Buffer
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, nil)))
+
+	type Event struct {
+		Type    string  `json:"type" json-const:"order.created"`
+		Version int     `json:"version" json-const:"2"`
+		Weight  float64 `json:"weight" json-const:"0.5"`
+		Live    bool    `json:"live" json-const:"false"`
+		Kind    string  `json:"kind" json-const:"order" json-enum:"order,refund"`
+		Broken  int     `json:"broken" json-const:"two"`
+	}
+
+	expected := &schema.JSON{
+		Type: schema.Object,
+		Properties: map[string]*schema.JSON{
+			"type":    {Type: schema.String, Const: "order.created"},
+			"version": {Type: schema.Integer, Format: ptr("int64"), Const: int64(2)},
+			"weight":  {Type: schema.Number, Format: ptr("double"), Const: 0.5},
+			"live":    {Type: schema.Boolean, Const: false},
+			"kind":    {Type: schema.String, Const: "order"},
+			"broken":  {Type: schema.Integer, Format: ptr("int64")},
+		},
+		Required: []string{"type", "version", "weight", "live", "kind", "broken"},
+	}
+
+	result := schema.From(Event{})
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, result)
+	}
+
+	out := buf.String()
+	if !strings.Contains(out, "level=WARN") || !strings.Contains(out, "Event.Kind: json-const overrides json-enum") {
+		t.Errorf("Expected a warning about json-enum being overridden on Kind, got %q", out)
+	}
+}
+
 func TestFrom_UnexportedFields(t *testing.T) {
 	type TestStruct struct {
 		name string `json:"name"`
@@ -345,7 +394,7 @@ func TestFrom_UnexportedFields(t *testing.T) {
 	expected := &schema.JSON{
 		Type: schema.Object,
 		Properties: map[string]*schema.JSON{
-			"age": {Type: schema.Integer},
+			"age": {Type: schema.Integer, Format: ptr("int64")},
 		},
 		Required: []string{"age"},
 	}
@@ -356,6 +405,28 @@ func TestFrom_UnexportedFields(t *testing.T) {
 	}
 }
 
+func TestFrom_UnexportedTaggedFieldWarning(t *testing.T) {
+	var buf bytes.Buffer
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+
+	// built by reflection, as vet flags unexported fields with json tags declared in the source
+	typ := reflect.StructOf([]reflect.StructField{
+		{Name: "name", PkgPath: "schema_test", Type: reflect.TypeFor[string](), Tag: `json:"name"`},
+		{Name: "internal", PkgPath: "schema_test", Type: reflect.TypeFor[int]()},
+		{Name: "Age", Type: reflect.TypeFor[int](), Tag: `json:"age"`},
+	})
+	schema.From(reflect.New(typ).Elem().Interface())
+
+	out := buf.String()
+	if !strings.Contains(out, "level=DEBUG") || !strings.Contains(out, "field=name") {
+		t.Errorf("Expected a debug warning about field name, got %q", out)
+	}
+	if strings.Contains(out, "field=internal") {
+		t.Errorf("Expected no warning about the untagged field internal, got %q", out)
+	}
+}
+
 func TestFrom_IgnoredField(t *testing.T) {
 	type TestStruct struct {
 		Secret string `json:"-"`
@@ -365,7 +436,7 @@ func TestFrom_IgnoredField(t *testing.T) {
 	expected := &schema.JSON{
 		Type: schema.Object,
 		Properties: map[string]*schema.JSON{
-			"public": {Type: schema.Integer},
+			"public": {Type: schema.Integer, Format: ptr("int64")},
 		},
 		Required: []string{"public"},
 	}
@@ -422,7 +493,7 @@ func TestFrom_SliceOfPointers(t *testing.T) {
 		Properties: map[string]*schema.JSON{
 			"list": {
 				Type:  schema.Array,
-				Items: &schema.JSON{Type: schema.Integer, Nullable: true},
+				Items: &schema.JSON{Type: schema.Integer, Format: ptr("int64"), Nullable: true},
 			},
 		},
 		Required: []string{"list"},
@@ -438,7 +509,7 @@ func TestFrom_MapWithPointerValues(t *testing.T) {
 	input := map[string]*float64{}
 	expected := &schema.JSON{
 		Type:                 schema.Object,
-		AdditionalProperties: &schema.JSON{Type: schema.Number, Nullable: true},
+		AdditionalProperties: &schema.JSON{Type: schema.Number, Format: ptr("double"), Nullable: true},
 		Properties:           map[string]*schema.JSON{},
 	}
 
@@ -456,7 +527,7 @@ func TestFrom_InvalidTags(t *testing.T) {
 	expected := &schema.JSON{
 		Type: schema.Object,
 		Properties: map[string]*schema.JSON{
-			"age": {Type: schema.Integer},
+			"age": {Type: schema.Integer, Format: ptr("int64")},
 		},
 		Required: []string{"age"},
 	}
@@ -0,0 +1,19 @@
+package schema
+
+// Dialect selects how a schema is encoded where JSON Schema and OpenAPI disagree. Schemas are encoded as
+// OpenAPI 3.0 unless set to another dialect
+type Dialect int
+
+const (
+	// OpenAPI30 encodes schemas as the JSON Schema subset of OpenAPI 3.0, e.g. with boolean exclusive bounds
+	OpenAPI30 Dialect = iota
+	// JSONSchema encodes schemas as JSON Schema 2020-12, which OpenAPI 3.1 uses, e.g. with numeric exclusive bounds
+	JSONSchema
+)
+
+// SetDialect sets the dialect s, and every schema within it, is encoded in
+func SetDialect(s *JSON, d Dialect) {
+	Walk(s, func(s *JSON) {
+		s.dialect = d
+	})
+}
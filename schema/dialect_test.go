@@ -0,0 +1,139 @@
+package schema_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/modfin/strut/schema"
+	"gopkg.in/yaml.v3"
+)
+
+func TestSetDialect_ExclusiveBounds(t *testing.T) {
+	type Rating struct {
+		Rate float64 `json:"rate" json-exclusive-minimum:"0" json-exclusive-maximum:"5"`
+	}
+
+	s := schema.From(Rating{})
+	b, err := json.Marshal(s.Properties["rate"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := `{"type":"number","maximum":5,"minimum":0,"format":"double","exclusiveMinimum":true,"exclusiveMaximum":true}`; string(b) != expected {
+		t.Errorf("Expected %s, got %s", expected, b)
+	}
+
+	y, err := yaml.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded struct {
+		Properties map[string]map[string]any `yaml:"properties"`
+	}
+	if err := yaml.Unmarshal(y, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	rate := decoded.Properties["rate"]
+	if rate["minimum"] != 0 || rate["maximum"] != 5 || rate["exclusiveMinimum"] != true || rate["exclusiveMaximum"] != true {
+		t.Errorf("Expected boolean exclusive bounds, got %s", y)
+	}
+
+	schema.SetDialect(s, schema.JSONSchema)
+	b, err = json.Marshal(s.Properties["rate"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := `{"type":"number","exclusiveMaximum":5,"exclusiveMinimum":0,"format":"double"}`; string(b) != expected {
+		t.Errorf("Expected %s, got %s", expected, b)
+	}
+}
+
+func TestSetDialect_Nullable(t *testing.T) {
+	type Shipment struct {
+		Carrier *string `json:"carrier" json-example:"DHL"`
+		Status  *string `json:"status" json-enum:"sent,delivered"`
+	}
+
+	s := schema.From(Shipment{})
+	b, err := json.Marshal(s.Properties["carrier"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := `{"example":"DHL","type":"string","nullable":true}`; string(b) != expected {
+		t.Errorf("Expected the OpenAPI 3.0 encoding unless set otherwise, got %s", b)
+	}
+
+	tests := []struct {
+		dialect schema.Dialect
+		carrier string
+		status  string
+	}{
+		{schema.JSONSchema, `{"type":["string","null"],"examples":["DHL"]}`, `{"enum":["sent","delivered",null],"type":["string","null"]}`},
+		{schema.OpenAPI30, `{"example":"DHL","type":"string","nullable":true}`, `{"type":"string","nullable":true,"enum":["sent","delivered"]}`},
+	}
+	for _, tt := range tests {
+		schema.SetDialect(s, tt.dialect)
+		for name, expected := range map[string]string{"carrier": tt.carrier, "status": tt.status} {
+			b, err := json.Marshal(s.Properties[name])
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(b) != expected {
+				t.Errorf("Expected %s, got %s", expected, b)
+			}
+		}
+	}
+
+	y, err := yaml.Marshal(s.Properties["carrier"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "example: DHL\ntype: string\nnullable: true\n"; string(y) != expected {
+		t.Errorf("Expected %q, got %q", expected, y)
+	}
+}
+
+func TestSetDialect_NullableRef(t *testing.T) {
+	s := &schema.JSON{Ref: "#/components/schemas/Address", Nullable: true}
+	tests := []struct {
+		dialect  schema.Dialect
+		expected string
+	}{
+		{schema.JSONSchema, `{"anyOf":[{"$ref":"#/components/schemas/Address"},{"type":"null"}]}`},
+		{schema.OpenAPI30, `{"$ref":"#/components/schemas/Address","nullable":true}`},
+	}
+	for _, tt := range tests {
+		schema.SetDialect(s, tt.dialect)
+		b, err := json.Marshal(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != tt.expected {
+			t.Errorf("Expected %s, got %s", tt.expected, b)
+		}
+	}
+}
+
+func TestSetDialect_Const(t *testing.T) {
+	type Event struct {
+		Type string `json:"type" json-const:"order.created"`
+	}
+
+	s := schema.From(Event{})
+	tests := []struct {
+		dialect  schema.Dialect
+		expected string
+	}{
+		{schema.JSONSchema, `{"type":"string","const":"order.created"}`},
+		{schema.OpenAPI30, `{"type":"string","enum":["order.created"]}`},
+	}
+	for _, tt := range tests {
+		schema.SetDialect(s, tt.dialect)
+		b, err := json.Marshal(s.Properties["type"])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != tt.expected {
+			t.Errorf("Expected %s, got %s", tt.expected, b)
+		}
+	}
+}
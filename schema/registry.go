@@ -0,0 +1,121 @@
+package schema
+
+import (
+	"mime/multipart"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"sync"
+)
+
+// registry holds the global, per type, adjustments to how schemas are generated
+var registry = struct {
+	sync.RWMutex
+	ignoredMarshalers map[reflect.Type]bool
+	enums             map[reflect.Type][]interface{}
+	formats           map[reflect.Type]string
+	types             map[reflect.Type]*JSON
+}{
+	ignoredMarshalers: map[reflect.Type]bool{},
+	types:             map[reflect.Type]*JSON{},
+	enums:             map[reflect.Type][]interface{}{},
+	formats: map[reflect.Type]string{
+		reflect.TypeOf(url.URL{}):              "uri",
+		reflect.TypeOf(mail.Address{}):         "email",
+		reflect.TypeOf(multipart.FileHeader{}): "binary", // file parts of multipart/form-data requests
+	},
+}
+
+// uuidTypes are the common uuid packages, matched by name to not depend on them
+var uuidTypes = map[string]bool{
+	"github.com/google/uuid.UUID":    true,
+	"github.com/gofrs/uuid.UUID":     true,
+	"github.com/gofrs/uuid/v5.UUID":  true,
+	"github.com/satori/go.uuid.UUID": true,
+}
+
+// IgnoreMarshaler makes From reflect into t even though it implements json.Marshaler or encoding.TextMarshaler,
+// for types whose MarshalJSON emits an object rather than a string
+func IgnoreMarshaler(t reflect.Type) {
+	registry.Lock()
+	defer registry.Unlock()
+	registry.ignoredMarshalers[t] = true
+	cache.reset()
+}
+
+func isIgnoredMarshaler(t reflect.Type) bool {
+	registry.RLock()
+	defer registry.RUnlock()
+	return registry.ignoredMarshalers[t]
+}
+
+// RegisterType makes From document t as s wherever it appears, e.g. for third-party types that can't get a
+// JSONSchema method, pointers to t being nullable. It takes precedence over a JSONSchema method, which in turn
+// takes precedence over reflection, the json-* tags of fields being applied on top of either. s is copied,
+// adjusting it afterwards doesn't affect the registration
+func RegisterType(t reflect.Type, s *JSON) {
+	registry.Lock()
+	defer registry.Unlock()
+	registry.types[t] = s.Clone()
+	cache.reset()
+}
+
+func registeredType(t reflect.Type) (*JSON, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+	s, ok := registry.types[t]
+	if !ok {
+		return nil, false
+	}
+	return s.Clone(), true
+}
+
+// RegisterEnum records the allowed values of a named type, e.g. a set of string consts,
+// which From then documents as the enum of every field of that type
+func RegisterEnum[T ~string | ~int](values ...T) {
+	enum := make([]interface{}, len(values))
+	for i, v := range values {
+		rv := reflect.ValueOf(v)
+		if rv.Kind() == reflect.String {
+			enum[i] = rv.String()
+			continue
+		}
+		enum[i] = rv.Int()
+	}
+
+	registry.Lock()
+	defer registry.Unlock()
+	registry.enums[reflect.TypeFor[T]()] = enum
+	cache.reset()
+}
+
+func registeredEnum(t reflect.Type) []interface{} {
+	registry.RLock()
+	defer registry.RUnlock()
+	enum, ok := registry.enums[t]
+	if !ok {
+		return nil
+	}
+	return append([]interface{}{}, enum...)
+}
+
+// RegisterFormat makes From document t as a string of the given format, e.g. "uuid", unless a field
+// overrides it with a json-format tag. url.URL, mail.Address and the common uuid types are registered by default
+func RegisterFormat(t reflect.Type, format string) {
+	registry.Lock()
+	defer registry.Unlock()
+	registry.formats[t] = format
+	cache.reset()
+}
+
+func registeredFormat(t reflect.Type) (string, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+	if format, ok := registry.formats[t]; ok {
+		return format, true
+	}
+	if uuidTypes[t.PkgPath()+"."+t.Name()] {
+		return "uuid", true
+	}
+	return "", false
+}
@@ -0,0 +1,175 @@
+package schema_test
+
+import (
+	"fmt"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/modfin/strut/schema"
+)
+
+type PaymentMethod string
+
+const (
+	PaymentCard    PaymentMethod = "card"
+	PaymentInvoice PaymentMethod = "invoice"
+	PaymentSwish   PaymentMethod = "swish"
+)
+
+type Priority int
+
+const (
+	PriorityLow Priority = iota + 1
+	PriorityHigh
+)
+
+func TestRegisterEnum(t *testing.T) {
+	schema.RegisterEnum(PaymentCard, PaymentInvoice, PaymentSwish)
+	schema.RegisterEnum(PriorityLow, PriorityHigh)
+
+	type Order struct {
+		PaymentMethod PaymentMethod   `json:"payment_method"`
+		Fallback      *PaymentMethod  `json:"fallback,omitempty"`
+		Accepted      []PaymentMethod `json:"accepted"`
+		Priority      Priority        `json:"priority"`
+		Express       PaymentMethod   `json:"express" json-enum:"card"`
+	}
+
+	methods := func() []interface{} { return []interface{}{"card", "invoice", "swish"} }
+	expected := &schema.JSON{
+		Type: schema.Object,
+		Properties: map[string]*schema.JSON{
+			"payment_method": {Type: schema.String, Enum: methods()},
+			"fallback":       {Type: schema.String, Enum: methods(), Nullable: true},
+			"accepted":       {Type: schema.Array, Items: &schema.JSON{Type: schema.String, Enum: methods()}},
+			"priority":       {Type: schema.Integer, Format: ptr("int64"), Enum: []interface{}{int64(1), int64(2)}},
+			"express":        {Type: schema.String, Enum: []interface{}{"card"}}, // tags take precedence
+		},
+		Required: []string{"payment_method", "accepted", "priority", "express"},
+	}
+
+	result := schema.From(Order{})
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, result)
+	}
+
+	// Plain strings are unaffected by the registration of a named string type
+	if result := schema.From(""); result.Enum != nil {
+		t.Errorf("Expected no enum for string, got %+v", result.Enum)
+	}
+}
+
+func TestRegisterEnum_Concurrent(t *testing.T) {
+	type Color string
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			schema.RegisterEnum[Color]("red", "green")
+		}()
+		go func() {
+			defer wg.Done()
+			schema.From(Color(""))
+		}()
+	}
+	wg.Wait()
+
+	expected := &schema.JSON{Type: schema.String, Enum: []interface{}{"red", "green"}}
+	if result := schema.From(Color("")); !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, result)
+	}
+}
+
+type OrderID [16]byte
+
+func (id OrderID) MarshalText() ([]byte, error) {
+	return []byte(fmt.Sprintf("%x", id[:])), nil
+}
+
+func TestRegisterFormat(t *testing.T) {
+	schema.RegisterFormat(reflect.TypeOf(OrderID{}), "uuid")
+
+	type Contact struct {
+		ID       OrderID       `json:"id"`
+		Homepage url.URL       `json:"homepage"`
+		Avatar   *url.URL      `json:"avatar"`
+		Email    mail.Address  `json:"email"`
+		Others   []OrderID     `json:"others"`
+		Legacy   OrderID       `json:"legacy" json-format:"hex"`
+		Replies  *mail.Address `json:"replies" json-description:"Reply address"`
+	}
+
+	format := func(f string) *string { return &f }
+	expected := &schema.JSON{
+		Type: schema.Object,
+		Properties: map[string]*schema.JSON{
+			"id":       {Type: schema.String, Format: format("uuid")},
+			"homepage": {Type: schema.String, Format: format("uri")},
+			"avatar":   {Type: schema.String, Format: format("uri"), Nullable: true},
+			"email":    {Type: schema.String, Format: format("email")},
+			"others":   {Type: schema.Array, Items: &schema.JSON{Type: schema.String, Format: format("uuid")}},
+			"legacy":   {Type: schema.String, Format: format("hex")}, // tags take precedence
+			"replies":  {Type: schema.String, Format: format("email"), Nullable: true, Description: "Reply address"},
+		},
+		Required: []string{"id", "homepage", "avatar", "email", "others", "legacy", "replies"},
+	}
+
+	result := schema.From(Contact{})
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, result)
+	}
+}
+
+type Amount struct {
+	Units int64
+	Nanos int32
+}
+
+type Cents int64
+
+func (Cents) JSONSchema() *schema.JSON {
+	return &schema.JSON{Type: schema.Integer, Description: "Amount in cents"}
+}
+
+func TestRegisterType(t *testing.T) {
+	money := &schema.JSON{Type: schema.String, Description: "Decimal amount, e.g. 10.50"}
+	schema.RegisterType(reflect.TypeOf(Amount{}), money)
+	schema.RegisterType(reflect.TypeOf(Cents(0)), &schema.JSON{Type: schema.String, Description: "Registered"})
+	money.Description = "changed after registering"
+
+	type Invoice struct {
+		Total    Amount            `json:"total"`
+		Discount *Amount           `json:"discount"`
+		Lines    []Amount          `json:"lines"`
+		Taxes    map[string]Amount `json:"taxes"`
+		Rounding Amount            `json:"rounding" json-description:"Rounded off"`
+		Fee      Cents             `json:"fee"`
+	}
+
+	expected := &schema.JSON{
+		Type: schema.Object,
+		Properties: map[string]*schema.JSON{
+			"total":    {Type: schema.String, Description: "Decimal amount, e.g. 10.50"},
+			"discount": {Type: schema.String, Description: "Decimal amount, e.g. 10.50", Nullable: true},
+			"lines":    {Type: schema.Array, Items: &schema.JSON{Type: schema.String, Description: "Decimal amount, e.g. 10.50"}},
+			"taxes": {
+				Type:                 schema.Object,
+				Properties:           map[string]*schema.JSON{},
+				AdditionalProperties: &schema.JSON{Type: schema.String, Description: "Decimal amount, e.g. 10.50"},
+			},
+			"rounding": {Type: schema.String, Description: "Rounded off"}, // tags take precedence
+			"fee":      {Type: schema.String, Description: "Registered"},  // over the JSONSchema method
+		},
+		Required: []string{"total", "discount", "lines", "taxes", "rounding", "fee"},
+	}
+
+	result := schema.From(Invoice{})
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, result)
+	}
+}
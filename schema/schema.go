@@ -1,5 +1,12 @@
 package schema
 
+import (
+	"encoding/json"
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
 //https://platform.openai.com/docs/guides/structured-outputs#supported-schemas
 //String
 //Number
@@ -40,6 +47,7 @@ const (
 	Number  JSONType = "number"
 	Integer JSONType = "integer"
 	Boolean JSONType = "boolean"
+	Null    JSONType = "null"
 )
 
 type JSON struct {
@@ -49,6 +57,12 @@ type JSON struct {
 
 	// JSON Metadata
 	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+	ReadOnly    bool   `json:"readOnly,omitempty" yaml:"readOnly,omitempty"`     // only sent in responses, e.g. server assigned ids
+	WriteOnly   bool   `json:"writeOnly,omitempty" yaml:"writeOnly,omitempty"`   // only sent in requests, e.g. passwords
+	Deprecated  bool   `json:"deprecated,omitempty" yaml:"deprecated,omitempty"` // still sent, but to be removed
+
+	Default interface{} `json:"default,omitempty" yaml:"default,omitempty"` // value assumed when the field is absent
+	Example interface{} `json:"example,omitempty" yaml:"example,omitempty"`
 
 	// Type System
 	Type     JSONType `json:"type,omitempty" yaml:"type,omitempty"`
@@ -56,11 +70,19 @@ type JSON struct {
 
 	// Combinators
 	Properties           map[string]*JSON `json:"properties,omitempty" yaml:"properties,omitempty"`                     // for Object
+	PropertyOrdering     []string         `json:"propertyOrdering,omitempty" yaml:"propertyOrdering,omitempty"`         // order of Properties, see WithPropertyOrdering
 	AdditionalProperties *JSON            `json:"additionalProperties,omitempty" yaml:"additionalProperties,omitempty"` // for Map[string]someting...
-	Items                *JSON            `json:"items,omitempty" yaml:"items,omitempty"`                               // for Array
+	// AdditionalPropertiesBool, when set, is emitted as additionalProperties in place of AdditionalProperties,
+	// e.g. false for objects that may not have any other properties than the listed ones
+	AdditionalPropertiesBool *bool          `json:"-" yaml:"-"`
+	Items                    *JSON          `json:"items,omitempty" yaml:"items,omitempty"`                 // for Array
+	OneOf                    []*JSON        `json:"oneOf,omitempty" yaml:"oneOf,omitempty"`                 // exactly one must match, e.g. tagged unions
+	AnyOf                    []*JSON        `json:"anyOf,omitempty" yaml:"anyOf,omitempty"`                 // at least one must match
+	Discriminator            *Discriminator `json:"discriminator,omitempty" yaml:"discriminator,omitempty"` // for OneOf and AnyOf
 
 	// Validation
 	Enum     []interface{} `json:"enum,omitempty" yaml:"enum,omitempty"`
+	Const    interface{}   `json:"const,omitempty" yaml:"const,omitempty"` // the single allowed value, e.g. of a discriminator
 	Required []string      `json:"required,omitempty" yaml:"required,omitempty"`
 
 	/// Number Validation
@@ -68,6 +90,7 @@ type JSON struct {
 	Minimum          *float64 `json:"minimum,omitempty" yaml:"minimum,omitempty"`
 	ExclusiveMaximum *float64 `json:"exclusiveMaximum,omitempty" yaml:"exclusiveMaximum,omitempty"`
 	ExclusiveMinimum *float64 `json:"exclusiveMinimum,omitempty" yaml:"exclusiveMinimum,omitempty"`
+	MultipleOf       *float64 `json:"multipleOf,omitempty" yaml:"multipleOf,omitempty"` // e.g. 0.01 for monetary amounts
 
 	/// String Validation
 	MaxLength *int    `json:"maxLength,omitempty" yaml:"maxLength,omitempty"`
@@ -79,4 +102,142 @@ type JSON struct {
 	// Array Validation
 	MaxItems *int `json:"maxItems,omitempty" yaml:"maxItems,omitempty"`
 	MinItems *int `json:"minItems,omitempty" yaml:"minItems,omitempty"`
+
+	// XML describes how the value is encoded as XML, set from the xml struct tags
+	XML *XMLObject `json:"xml,omitempty" yaml:"xml,omitempty"`
+
+	dialect Dialect // how keywords that differ between dialects are encoded, see SetDialect
+}
+
+// plainJSON has the fields of JSON but not its marshalers, to encode them without recursing
+type plainJSON JSON
+
+// keyword is a schema keyword whose encoding can't be expressed by the struct fields alone
+type keyword struct {
+	key   string
+	value interface{}
+}
+
+// encoded splits s into the keywords encoded straight from the struct fields and those that aren't,
+// i.e. AdditionalPropertiesBool, the bounds and const of the OpenAPI 3.0 dialect and nullable and example of JSON Schema
+func (s JSON) encoded() (plainJSON, []keyword) {
+	// JSON Schema has no nullable, a reference, or a schema of no single type, accepts null by a branch of its own
+	if s.dialect == JSONSchema && s.Nullable && (s.Ref != "" || s.Type == "") {
+		nonNull := s
+		nonNull.Nullable = false
+		return plainJSON{AnyOf: []*JSON{&nonNull, {Type: Null, dialect: JSONSchema}}}, nil
+	}
+
+	p := plainJSON(s)
+	var extra []keyword
+
+	if s.AdditionalPropertiesBool != nil {
+		p.AdditionalProperties = nil
+		extra = append(extra, keyword{"additionalProperties", *s.AdditionalPropertiesBool})
+	}
+
+	// JSON Schema has no nullable, null is a type of its own, and examples are a list
+	if s.dialect == JSONSchema {
+		if s.Nullable {
+			p.Nullable = false
+			if s.Type != "" && s.Type != Null {
+				p.Type = ""
+				extra = append(extra, keyword{"type", []JSONType{s.Type, Null}})
+			}
+			if len(s.Enum) > 0 {
+				p.Enum = append(append([]interface{}{}, s.Enum...), nil)
+			}
+		}
+		if s.Example != nil {
+			p.Example = nil
+			extra = append(extra, keyword{"examples", []interface{}{s.Example}})
+		}
+	}
+
+	// OpenAPI 3.0 marks minimum and maximum as exclusive with a boolean, rather than by a bound of their own
+	if s.dialect == OpenAPI30 {
+		if s.ExclusiveMinimum != nil {
+			p.ExclusiveMinimum = nil
+			if s.Minimum == nil || *s.ExclusiveMinimum >= *s.Minimum {
+				p.Minimum = s.ExclusiveMinimum
+				extra = append(extra, keyword{"exclusiveMinimum", true})
+			}
+		}
+		if s.ExclusiveMaximum != nil {
+			p.ExclusiveMaximum = nil
+			if s.Maximum == nil || *s.ExclusiveMaximum <= *s.Maximum {
+				p.Maximum = s.ExclusiveMaximum
+				extra = append(extra, keyword{"exclusiveMaximum", true})
+			}
+		}
+		// nor does it have const, a single valued enum pins the value as well
+		if s.Const != nil {
+			p.Const = nil
+			p.Enum = []interface{}{s.Const}
+		}
+	}
+	return p, extra
+}
+
+// MarshalJSON encodes s, including the keywords that differ from the struct fields
+func (s JSON) MarshalJSON() ([]byte, error) {
+	p, extra := s.encoded()
+	b, err := json.Marshal(p)
+	if err != nil || len(extra) == 0 {
+		return b, err
+	}
+
+	b = b[:len(b)-1] // drop the closing brace, to append the extra keywords
+	for _, kw := range extra {
+		value, err := json.Marshal(kw.value)
+		if err != nil {
+			return nil, err
+		}
+		if len(b) > 1 {
+			b = append(b, ',')
+		}
+		b = append(b, fmt.Sprintf("%q:", kw.key)...)
+		b = append(b, value...)
+	}
+	return append(b, '}'), nil
+}
+
+// MarshalYAML encodes s, including the keywords that differ from the struct fields
+func (s JSON) MarshalYAML() (interface{}, error) {
+	p, extra := s.encoded()
+	if len(extra) == 0 {
+		return p, nil
+	}
+
+	var node yaml.Node
+	if err := node.Encode(p); err != nil {
+		return nil, err
+	}
+	for _, kw := range extra {
+		var value yaml.Node
+		if err := value.Encode(kw.value); err != nil {
+			return nil, err
+		}
+		node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: kw.key}, &value)
+	}
+	return &node, nil
+}
+
+// XMLObject describes the XML encoding of a value, e.g. the element name of a property or that it is an attribute
+type XMLObject struct {
+	Name      string `json:"name,omitempty" yaml:"name,omitempty"`
+	Namespace string `json:"namespace,omitempty" yaml:"namespace,omitempty"`
+	Prefix    string `json:"prefix,omitempty" yaml:"prefix,omitempty"`
+	Attribute bool   `json:"attribute,omitempty" yaml:"attribute,omitempty"`
+	Wrapped   bool   `json:"wrapped,omitempty" yaml:"wrapped,omitempty"` // arrays whose items are wrapped by an element
+	// NodeType marks values that aren't elements or attributes, text for character data and none for values left out
+	// of the XML or written as is, like nodeType of OpenAPI 3.2
+	NodeType string `json:"x-nodeType,omitempty" yaml:"x-nodeType,omitempty"`
+}
+
+// Discriminator names the property telling which of the OneOf or AnyOf schemas a value conforms to,
+// Mapping maps its values to schema $refs when they are not the component names
+type Discriminator struct {
+	PropertyName string            `json:"propertyName" yaml:"propertyName"`
+	Mapping      map[string]string `json:"mapping,omitempty" yaml:"mapping,omitempty"`
 }
@@ -0,0 +1,29 @@
+package schema
+
+// StripWriteOnly removes the properties marked writeOnly in s from v, a value decoded from JSON
+// into an interface{}, so that e.g. passwords are never echoed back in responses
+func StripWriteOnly(s *JSON, v any) any {
+	if s == nil {
+		return v
+	}
+
+	switch val := v.(type) {
+	case map[string]any:
+		for name, item := range val {
+			prop, ok := s.Properties[name]
+			if !ok {
+				prop = s.AdditionalProperties
+			}
+			if prop != nil && prop.WriteOnly {
+				delete(val, name)
+				continue
+			}
+			val[name] = StripWriteOnly(prop, item)
+		}
+	case []any:
+		for i, item := range val {
+			val[i] = StripWriteOnly(s.Items, item)
+		}
+	}
+	return v
+}
@@ -0,0 +1,59 @@
+package schema_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/modfin/strut/schema"
+)
+
+func TestFrom_WriteOnlyTag(t *testing.T) {
+	type Login struct {
+		User     string `json:"user"`
+		Password string `json:"password" json-write-only:"true"`
+	}
+
+	expected := &schema.JSON{
+		Type: schema.Object,
+		Properties: map[string]*schema.JSON{
+			"user":     {Type: schema.String},
+			"password": {Type: schema.String, WriteOnly: true},
+		},
+		Required: []string{"user", "password"},
+	}
+
+	result := schema.From(Login{})
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, result)
+	}
+}
+
+func TestStripWriteOnly(t *testing.T) {
+	type Credential struct {
+		Name   string `json:"name"`
+		Secret string `json:"secret" json-write-only:"true"`
+	}
+	type Account struct {
+		User        string                `json:"user"`
+		Password    string                `json:"password" json-write-only:"true"`
+		Credentials []Credential          `json:"credentials"`
+		Named       map[string]Credential `json:"named"`
+	}
+
+	v := decode(t, `{
+		"user": "jane",
+		"password": "hunter2",
+		"credentials": [{"name": "ci", "secret": "s1"}],
+		"named": {"deploy": {"name": "deploy", "secret": "s2"}}
+	}`)
+	expected := decode(t, `{
+		"user": "jane",
+		"credentials": [{"name": "ci"}],
+		"named": {"deploy": {"name": "deploy"}}
+	}`)
+
+	result := schema.StripWriteOnly(schema.From(Account{}), v)
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, result)
+	}
+}
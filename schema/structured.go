@@ -0,0 +1,96 @@
+package schema
+
+import "sort"
+
+// ForStructuredOutput rewrites s into a schema accepted as structured output by e.g. OpenAI and Gemini.
+// Keywords they reject are dropped, objects are closed with additionalProperties false and every property
+// is made required, properties that were optional by also allowing null. Maps, which structured output can't
+// express, end up as empty objects. s itself is left untouched
+func ForStructuredOutput(s *JSON) *JSON {
+	if s == nil {
+		return nil
+	}
+
+	result := &JSON{
+		Ref:         s.Ref,
+		Description: s.Description,
+		Type:        s.Type,
+		Enum:        s.Enum,
+	}
+	if s.Defs != nil {
+		result.Defs = make(map[string]*JSON, len(s.Defs))
+		for name, def := range s.Defs {
+			result.Defs[name] = ForStructuredOutput(def)
+		}
+	}
+
+	// oneOf is not supported, but a value matching exactly one schema also matches anyOf them
+	for _, sub := range append(append([]*JSON{}, s.OneOf...), s.AnyOf...) {
+		result.AnyOf = append(result.AnyOf, ForStructuredOutput(sub))
+	}
+
+	switch s.Type {
+	case Object:
+		closed := false
+		result.Properties = make(map[string]*JSON, len(s.Properties))
+		result.AdditionalPropertiesBool = &closed
+
+		required := map[string]bool{}
+		for _, name := range s.Required {
+			required[name] = true
+		}
+		names := make([]string, 0, len(s.Properties))
+		for name := range s.Properties {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		// Keep the order of the originally required properties, followed by the optional ones
+		for _, name := range s.Required {
+			if _, ok := s.Properties[name]; ok {
+				result.Required = append(result.Required, name)
+			}
+		}
+		for _, name := range names {
+			prop := ForStructuredOutput(s.Properties[name])
+			if !required[name] {
+				result.Required = append(result.Required, name)
+				prop = orNull(prop)
+			}
+			result.Properties[name] = prop
+		}
+		if result.Required == nil {
+			result.Required = []string{}
+		}
+		for _, name := range s.PropertyOrdering {
+			if _, ok := s.Properties[name]; ok {
+				result.PropertyOrdering = append(result.PropertyOrdering, name)
+			}
+		}
+
+	case Array:
+		result.Items = ForStructuredOutput(s.Items)
+	}
+
+	if s.Nullable {
+		return orNull(result)
+	}
+	return result
+}
+
+// orNull makes s also accept null, nullable is not supported so it is expressed as anyOf null
+func orNull(s *JSON) *JSON {
+	if s.Type == Null {
+		return s
+	}
+	for _, sub := range s.AnyOf {
+		if sub.Type == Null {
+			return s
+		}
+	}
+	if s.AnyOf != nil && s.Type == "" {
+		s.AnyOf = append(s.AnyOf, &JSON{Type: Null})
+		return s
+	}
+	return &JSON{AnyOf: []*JSON{s, {Type: Null}}}
+}
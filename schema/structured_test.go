@@ -0,0 +1,102 @@
+package schema_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/modfin/strut/schema"
+	"gopkg.in/yaml.v3"
+)
+
+type StructuredOrderLine struct {
+	SKU      string `json:"sku" json-pattern:"^[A-Z0-9]+$" json-description:"Stock keeping unit"`
+	Quantity int    `json:"quantity" json-minimum:"1" json-default:"1"`
+}
+
+type StructuredOrder struct {
+	ID       string                `json:"id" json-format:"uuid" json-write-only:"true"`
+	Status   string                `json:"status" json-enum:"pending,shipped"`
+	Lines    []StructuredOrderLine `json:"lines" json-min-items:"1"`
+	Note     *string               `json:"note"`
+	Coupon   string                `json:"coupon,omitempty" json-max-length:"12"`
+	Metadata map[string]string     `json:"metadata,omitempty"`
+}
+
+func TestForStructuredOutput(t *testing.T) {
+	closed := func() *bool { b := false; return &b }
+	expected := &schema.JSON{
+		Type:                     schema.Object,
+		AdditionalPropertiesBool: closed(),
+		Properties: map[string]*schema.JSON{
+			"id":     {Type: schema.String},
+			"status": {Type: schema.String, Enum: []interface{}{"pending", "shipped"}},
+			"lines": {Type: schema.Array, Items: &schema.JSON{
+				Type:                     schema.Object,
+				AdditionalPropertiesBool: closed(),
+				Properties: map[string]*schema.JSON{
+					"sku":      {Type: schema.String, Description: "Stock keeping unit"},
+					"quantity": {Type: schema.Integer},
+				},
+				Required: []string{"sku", "quantity"},
+			}},
+			"note":   {AnyOf: []*schema.JSON{{Type: schema.String}, {Type: schema.Null}}},
+			"coupon": {AnyOf: []*schema.JSON{{Type: schema.String}, {Type: schema.Null}}},
+			"metadata": {AnyOf: []*schema.JSON{
+				{Type: schema.Object, Properties: map[string]*schema.JSON{}, AdditionalPropertiesBool: closed(), Required: []string{}},
+				{Type: schema.Null},
+			}},
+		},
+		Required: []string{"id", "status", "lines", "note", "coupon", "metadata"},
+	}
+
+	original := schema.From(StructuredOrder{})
+	result := schema.ForStructuredOutput(original)
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, result)
+	}
+
+	// The original schema is left untouched
+	if original.AdditionalPropertiesBool != nil || original.Properties["lines"].MinItems == nil {
+		t.Errorf("Expected the original schema to be unchanged, got %+v", original)
+	}
+}
+
+func TestForStructuredOutput_OneOf(t *testing.T) {
+	result := schema.ForStructuredOutput(schema.OneOf("", 0))
+	expected := &schema.JSON{AnyOf: []*schema.JSON{{Type: schema.String}, {Type: schema.Integer}}}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, result)
+	}
+}
+
+func TestJSON_AdditionalPropertiesBool(t *testing.T) {
+	closed := false
+	s := &schema.JSON{
+		Type:                     schema.Object,
+		Properties:               map[string]*schema.JSON{"name": {Type: schema.String}},
+		AdditionalProperties:     &schema.JSON{Type: schema.String},
+		AdditionalPropertiesBool: &closed,
+	}
+
+	b, err := json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"type":"object","properties":{"name":{"type":"string"}},"additionalProperties":false}`
+	if string(b) != expected {
+		t.Errorf("Expected %s, got %s", expected, b)
+	}
+
+	y, err := yaml.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded map[string]any
+	if err := yaml.Unmarshal(y, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded["additionalProperties"] != false {
+		t.Errorf("Expected additionalProperties false, got %s", y)
+	}
+}
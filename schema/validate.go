@@ -0,0 +1,252 @@
+package schema
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"sync"
+	"unicode/utf8"
+)
+
+// ValidationError describes a value not conforming to its schema,
+// Path points out the offending value, e.g. items[2].quantity
+type ValidationError struct {
+	Path    string
+	Message string
+}
+
+func (e ValidationError) Error() string {
+	if e.Path == "" {
+		return e.Message
+	}
+	return e.Path + ": " + e.Message
+}
+
+// Validate checks v, a value decoded from JSON into an interface{}, against s, e.g. its types,
+// required and closed properties, item counts, bounds, lengths and patterns, and returns every violation found
+func Validate(s *JSON, v any) []ValidationError {
+	return validate(s, v, "", nil)
+}
+
+func validate(s *JSON, v any, path string, errs []ValidationError) []ValidationError {
+	if s == nil {
+		return errs
+	}
+	if v == nil {
+		if s.Nullable || s.Type == "" || s.Type == Null {
+			return errs
+		}
+		return append(errs, ValidationError{Path: path, Message: fmt.Sprintf("type %s", s.Type)})
+	}
+
+	if !matchesType(s.Type, v) {
+		return append(errs, ValidationError{Path: path, Message: fmt.Sprintf("type %s", s.Type)})
+	}
+
+	switch s.Type {
+	case Object:
+		obj, ok := v.(map[string]any)
+		if !ok {
+			return errs
+		}
+		for _, name := range s.Required {
+			if _, ok := obj[name]; !ok {
+				errs = append(errs, ValidationError{Path: joinPath(path, name), Message: "required"})
+			}
+		}
+		for _, name := range sortedKeys(obj) {
+			if prop, ok := s.Properties[name]; ok {
+				errs = validate(prop, obj[name], joinPath(path, name), errs)
+				continue
+			}
+			if s.AdditionalPropertiesBool != nil && !*s.AdditionalPropertiesBool {
+				errs = append(errs, ValidationError{Path: joinPath(path, name), Message: "additionalProperties false"})
+				continue
+			}
+			errs = validate(s.AdditionalProperties, obj[name], joinPath(path, name), errs)
+		}
+
+	case Array:
+		arr, ok := v.([]any)
+		if !ok {
+			return errs
+		}
+		if s.MinItems != nil && len(arr) < *s.MinItems {
+			errs = append(errs, ValidationError{Path: path, Message: fmt.Sprintf("minItems %d", *s.MinItems)})
+		}
+		if s.MaxItems != nil && len(arr) > *s.MaxItems {
+			errs = append(errs, ValidationError{Path: path, Message: fmt.Sprintf("maxItems %d", *s.MaxItems)})
+		}
+		for i, item := range arr {
+			errs = validate(s.Items, item, fmt.Sprintf("%s[%d]", path, i), errs)
+		}
+
+	case Number, Integer:
+		n, ok := v.(float64)
+		if !ok {
+			return errs
+		}
+		if s.Minimum != nil && n < *s.Minimum {
+			errs = append(errs, ValidationError{Path: path, Message: fmt.Sprintf("minimum %v", *s.Minimum)})
+		}
+		if s.Maximum != nil && n > *s.Maximum {
+			errs = append(errs, ValidationError{Path: path, Message: fmt.Sprintf("maximum %v", *s.Maximum)})
+		}
+		if s.ExclusiveMinimum != nil && n <= *s.ExclusiveMinimum {
+			errs = append(errs, ValidationError{Path: path, Message: fmt.Sprintf("exclusiveMinimum %v", *s.ExclusiveMinimum)})
+		}
+		if s.ExclusiveMaximum != nil && n >= *s.ExclusiveMaximum {
+			errs = append(errs, ValidationError{Path: path, Message: fmt.Sprintf("exclusiveMaximum %v", *s.ExclusiveMaximum)})
+		}
+
+	case String:
+		str, ok := v.(string)
+		if !ok {
+			return errs
+		}
+		length := utf8.RuneCountInString(str)
+		if s.MinLength != nil && length < *s.MinLength {
+			errs = append(errs, ValidationError{Path: path, Message: fmt.Sprintf("minLength %d", *s.MinLength)})
+		}
+		if s.MaxLength != nil && length > *s.MaxLength {
+			errs = append(errs, ValidationError{Path: path, Message: fmt.Sprintf("maxLength %d", *s.MaxLength)})
+		}
+		if s.Pattern != nil {
+			// a pattern that doesn't compile, as reported by FromE, can't be checked and so no value conforms to it
+			if re, err := compilePattern(*s.Pattern); err != nil || !re.MatchString(str) {
+				errs = append(errs, ValidationError{Path: path, Message: fmt.Sprintf("pattern %s", *s.Pattern)})
+			}
+		}
+	}
+
+	return errs
+}
+
+// patterns caches the compiled patterns of schemas, along with the errors of those that don't compile
+var patterns sync.Map // string => compiledPattern
+
+type compiledPattern struct {
+	re  *regexp.Regexp
+	err error
+}
+
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	if compiled, ok := patterns.Load(pattern); ok {
+		return compiled.(compiledPattern).re, compiled.(compiledPattern).err
+	}
+	re, err := regexp.Compile(pattern)
+	patterns.Store(pattern, compiledPattern{re: re, err: err})
+	return re, err
+}
+
+// matchesType reports whether v, decoded from JSON, is of the type, any value matching no type at all
+func matchesType(t JSONType, v any) bool {
+	switch t {
+	case Object:
+		_, ok := v.(map[string]any)
+		return ok
+	case Array:
+		_, ok := v.([]any)
+		return ok
+	case String:
+		_, ok := v.(string)
+		return ok
+	case Number:
+		_, ok := v.(float64)
+		return ok
+	case Integer:
+		f, ok := v.(float64)
+		return ok && f == math.Trunc(f)
+	case Boolean:
+		_, ok := v.(bool)
+		return ok
+	}
+	return true
+}
+
+// Coerce converts the strings of v, a value decoded from JSON into an interface{}, to numbers,
+// integers or booleans where s expects them, e.g. "42" for an integer. Values that can't be
+// converted are left as they are, for Validate to report
+func Coerce(s *JSON, v any) any {
+	if s == nil || v == nil {
+		return v
+	}
+
+	switch val := v.(type) {
+	case string:
+		switch s.Type {
+		case Number:
+			if f, err := strconv.ParseFloat(val, 64); err == nil {
+				return f
+			}
+		case Integer:
+			if n, err := strconv.ParseInt(val, 10, 64); err == nil {
+				return float64(n)
+			}
+		case Boolean:
+			if b, err := strconv.ParseBool(val); err == nil {
+				return b
+			}
+		}
+	case map[string]any:
+		for name, item := range val {
+			if prop, ok := s.Properties[name]; ok {
+				val[name] = Coerce(prop, item)
+				continue
+			}
+			val[name] = Coerce(s.AdditionalProperties, item)
+		}
+	case []any:
+		for i, item := range val {
+			val[i] = Coerce(s.Items, item)
+		}
+	}
+	return v
+}
+
+// ApplyDefaults sets the properties absent from the objects of v, a value decoded from JSON into an interface{},
+// to the default of their schema in s, if any. Properties given explicitly, even as zero or null, are left as they are
+func ApplyDefaults(s *JSON, v any) any {
+	if s == nil || v == nil {
+		return v
+	}
+
+	switch val := v.(type) {
+	case map[string]any:
+		for name, prop := range s.Properties {
+			if _, ok := val[name]; !ok && prop != nil && prop.Default != nil {
+				val[name] = prop.Default
+			}
+		}
+		for name, item := range val {
+			if prop, ok := s.Properties[name]; ok {
+				val[name] = ApplyDefaults(prop, item)
+				continue
+			}
+			val[name] = ApplyDefaults(s.AdditionalProperties, item)
+		}
+	case []any:
+		for i, item := range val {
+			val[i] = ApplyDefaults(s.Items, item)
+		}
+	}
+	return v
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
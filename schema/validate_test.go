@@ -0,0 +1,245 @@
+package schema_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/modfin/strut/schema"
+)
+
+func decode(t *testing.T, data string) any {
+	t.Helper()
+	var v any
+	if err := json.Unmarshal([]byte(data), &v); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestValidate_ItemCounts(t *testing.T) {
+	type Item struct {
+		Tags []string `json:"tags" json-max-items:"2"`
+	}
+	type Order struct {
+		Items []Item `json:"items" json-min-items:"1"`
+	}
+	s := schema.From(Order{})
+
+	tests := []struct {
+		name     string
+		data     string
+		expected []schema.ValidationError
+	}{
+		{"valid", `{"items": [{"tags": ["a"]}]}`, nil},
+		{"too few", `{"items": []}`, []schema.ValidationError{{Path: "items", Message: "minItems 1"}}},
+		{"nested too many", `{"items": [{"tags": []}, {"tags": ["a", "b", "c"]}]}`, []schema.ValidationError{{Path: "items[1].tags", Message: "maxItems 2"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := schema.Validate(s, decode(t, tt.data))
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("Expected %+v, got %+v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestValidationError_Error(t *testing.T) {
+	err := schema.ValidationError{Path: "items", Message: "minItems 1"}
+	if err.Error() != "items: minItems 1" {
+		t.Errorf("Expected 'items: minItems 1', got %q", err.Error())
+	}
+}
+
+func TestValidate_RequiredAndTypes(t *testing.T) {
+	type Item struct {
+		SKU      string  `json:"sku"`
+		Quantity int     `json:"quantity"`
+		Gift     bool    `json:"gift,omitempty"`
+		Note     *string `json:"note,omitempty"`
+	}
+	s := schema.From([]Item{})
+
+	tests := []struct {
+		name     string
+		data     string
+		expected []schema.ValidationError
+	}{
+		{"valid", `[{"sku": "a-1", "quantity": 2}]`, nil},
+		{"missing", `[{"sku": "a-1"}]`, []schema.ValidationError{{Path: "[0].quantity", Message: "required"}}},
+		{"fraction", `[{"sku": "a-1", "quantity": 1.5}]`, []schema.ValidationError{{Path: "[0].quantity", Message: "type integer"}}},
+		{"types", `[{"sku": 1, "quantity": 2, "gift": "yes"}]`, []schema.ValidationError{
+			{Path: "[0].gift", Message: "type boolean"},
+			{Path: "[0].sku", Message: "type string"},
+		}},
+		{"null", `[{"sku": null, "quantity": 2}]`, []schema.ValidationError{{Path: "[0].sku", Message: "type string"}}},
+		{"nullable", `[{"sku": "a-1", "quantity": 2, "note": null}]`, nil},
+		{"not an array", `{}`, []schema.ValidationError{{Path: "", Message: "type array"}}},
+		{"null array", `null`, []schema.ValidationError{{Path: "", Message: "type array"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := schema.Validate(s, decode(t, tt.data))
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("Expected %+v, got %+v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestValidate_Bounds(t *testing.T) {
+	type Item struct {
+		SKU      string `json:"sku" json-min-length:"3" json-max-length:"5"`
+		Quantity int    `json:"quantity" json-minimum:"1" json-maximum:"10"`
+	}
+	s := schema.From(Item{})
+
+	tests := []struct {
+		name     string
+		data     string
+		expected []schema.ValidationError
+	}{
+		{"valid", `{"sku": "åäö", "quantity": 10}`, nil},
+		{"below", `{"sku": "a", "quantity": 0}`, []schema.ValidationError{
+			{Path: "quantity", Message: "minimum 1"},
+			{Path: "sku", Message: "minLength 3"},
+		}},
+		{"above", `{"sku": "a-1234", "quantity": 11}`, []schema.ValidationError{
+			{Path: "quantity", Message: "maximum 10"},
+			{Path: "sku", Message: "maxLength 5"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := schema.Validate(s, decode(t, tt.data))
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("Expected %+v, got %+v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestValidate_PatternsAndExclusiveBounds(t *testing.T) {
+	type Line struct {
+		SKU      string  `json:"sku" json-pattern:"^[A-Z]{2}-[0-9]+$"`
+		Quantity int     `json:"quantity" json-minimum:"1"`
+		Discount float64 `json:"discount,omitempty" json-exclusive-minimum:"0" json-exclusive-maximum:"1"`
+	}
+	type Order struct {
+		Lines  []Line            `json:"lines"`
+		Labels map[string]Line   `json:"labels,omitempty"`
+		Groups [][]Line          `json:"groups,omitempty"`
+		Notes  map[string]string `json:"notes,omitempty"`
+	}
+	s := schema.From(Order{})
+
+	tests := []struct {
+		name     string
+		data     string
+		expected []schema.ValidationError
+	}{
+		{"valid", `{"lines": [{"sku": "AB-1", "quantity": 1, "discount": 0.5}], "labels": {"x": {"sku": "CD-22", "quantity": 3}}}`, nil},
+		{"nested arrays", `{"lines": [{"sku": "AB-1", "quantity": 1}, {"sku": "AB-1", "quantity": 1}, {"sku": "ab-1", "quantity": 0}]}`, []schema.ValidationError{
+			{Path: "lines[2].quantity", Message: "minimum 1"},
+			{Path: "lines[2].sku", Message: "pattern ^[A-Z]{2}-[0-9]+$"},
+		}},
+		{"maps", `{"lines": [], "labels": {"b": {"sku": "AB-1", "quantity": 1, "discount": 1}, "a": {"sku": "AB", "quantity": 1, "discount": 0}}}`, []schema.ValidationError{
+			{Path: "labels.a.discount", Message: "exclusiveMinimum 0"},
+			{Path: "labels.a.sku", Message: "pattern ^[A-Z]{2}-[0-9]+$"},
+			{Path: "labels.b.discount", Message: "exclusiveMaximum 1"},
+		}},
+		{"arrays of arrays", `{"lines": [], "groups": [[], [{"sku": "AB-1"}]]}`, []schema.ValidationError{
+			{Path: "groups[1][0].quantity", Message: "required"},
+		}},
+		{"map values", `{"lines": [], "notes": {"a": 1}}`, []schema.ValidationError{
+			{Path: "notes.a", Message: "type string"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := schema.Validate(s, decode(t, tt.data))
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("Expected %+v, got %+v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestValidate_InvalidPattern(t *testing.T) {
+	// Patterns that don't compile can't be checked, so no value conforms to them, and FromE reports them
+	pattern := "^[a-z"
+	s := &schema.JSON{Type: schema.String, Pattern: &pattern}
+	expected := []schema.ValidationError{{Path: "", Message: "pattern ^[a-z"}}
+	for i := 0; i < 2; i++ {
+		if result := schema.Validate(s, "abc"); !reflect.DeepEqual(result, expected) {
+			t.Errorf("Expected %+v, got %+v", expected, result)
+		}
+	}
+
+	type Coupon struct {
+		Code string `json:"code" json-pattern:"^[A-Z"`
+	}
+	result, err := schema.FromE(Coupon{})
+	if err == nil || !strings.Contains(err.Error(), `schema_test.Coupon.Code: json-pattern "^[A-Z" does not compile`) {
+		t.Errorf("Expected the pattern to be reported, got %v", err)
+	}
+	if result.Properties["code"].Pattern == nil {
+		t.Errorf("Expected the pattern to be documented still")
+	}
+}
+
+func TestCoerce(t *testing.T) {
+	type Item struct {
+		SKU      string  `json:"sku"`
+		Quantity int     `json:"quantity"`
+		Price    float64 `json:"price"`
+		Gift     bool    `json:"gift"`
+	}
+	s := schema.From([]Item{})
+
+	result := schema.Coerce(s, decode(t, `[{"sku": "42", "quantity": "2", "price": "9.95", "gift": "true"}, {"quantity": "two"}]`))
+	expected := decode(t, `[{"sku": "42", "quantity": 2, "price": 9.95, "gift": true}, {"quantity": "two"}]`)
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, result)
+	}
+}
+
+func TestValidate_AdditionalPropertiesFalse(t *testing.T) {
+	type Item struct {
+		SKU string `json:"sku"`
+	}
+	s := schema.From(Item{}, schema.Strict())
+
+	result := schema.Validate(s, decode(t, `{"sku": "a-1", "color": "red"}`))
+	expected := []schema.ValidationError{{Path: "color", Message: "additionalProperties false"}}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, result)
+	}
+	if result := schema.Validate(schema.From(Item{}), decode(t, `{"sku": "a-1", "color": "red"}`)); result != nil {
+		t.Errorf("Expected extra properties to be allowed without Strict, got %+v", result)
+	}
+}
+
+func TestApplyDefaults(t *testing.T) {
+	type Line struct {
+		SKU      string `json:"sku"`
+		Quantity int    `json:"quantity" json-default:"1"`
+	}
+	type Order struct {
+		Currency string `json:"currency" json-default:"SEK"`
+		Note     string `json:"note,omitempty" json-default:"none"`
+		Lines    []Line `json:"lines"`
+	}
+	s := schema.From(Order{})
+
+	b, err := json.Marshal(schema.ApplyDefaults(s, decode(t, `{"note": "", "lines": [{"sku": "a-1"}, {"sku": "b-2", "quantity": 0}]}`)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"currency":"SEK","lines":[{"quantity":1,"sku":"a-1"},{"quantity":0,"sku":"b-2"}],"note":""}`
+	if string(b) != expected {
+		t.Errorf("Expected %s, got %s", expected, b)
+	}
+}
@@ -0,0 +1,24 @@
+package schema
+
+// Walk calls fn for s and every schema within it, i.e. its definitions, properties, additional properties, items
+// and the branches of oneOf and anyOf, parents before their children. References are not followed
+func Walk(s *JSON, fn func(s *JSON)) {
+	if s == nil {
+		return
+	}
+	fn(s)
+	for _, def := range s.Defs {
+		Walk(def, fn)
+	}
+	for _, prop := range s.Properties {
+		Walk(prop, fn)
+	}
+	Walk(s.AdditionalProperties, fn)
+	Walk(s.Items, fn)
+	for _, sub := range s.OneOf {
+		Walk(sub, fn)
+	}
+	for _, sub := range s.AnyOf {
+		Walk(sub, fn)
+	}
+}
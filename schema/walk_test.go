@@ -0,0 +1,45 @@
+package schema_test
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/modfin/strut/schema"
+)
+
+func TestWalk(t *testing.T) {
+	// Every schema within the schema is visited, whichever keyword holds it
+	s := &schema.JSON{
+		Description: "root",
+		Defs:        map[string]*schema.JSON{"def": {Description: "def"}},
+		Properties: map[string]*schema.JSON{
+			"items": {Description: "items", Items: &schema.JSON{Description: "item"}},
+		},
+		AdditionalProperties: &schema.JSON{Description: "additional"},
+		OneOf:                []*schema.JSON{{Description: "one"}},
+		AnyOf:                []*schema.JSON{{Description: "any"}},
+	}
+
+	var visited []string
+	schema.Walk(s, func(s *schema.JSON) {
+		visited = append(visited, s.Description)
+	})
+	if visited[0] != "root" {
+		t.Errorf("Expected the root to be visited first, got %v", visited)
+	}
+	sort.Strings(visited)
+	expected := []string{"additional", "any", "def", "item", "items", "one", "root"}
+	if len(visited) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, visited)
+	}
+	for i := range expected {
+		if visited[i] != expected[i] {
+			t.Errorf("Expected %v, got %v", expected, visited)
+			break
+		}
+	}
+
+	schema.Walk(nil, func(s *schema.JSON) {
+		t.Error("Expected nothing to be visited for a nil schema")
+	})
+}
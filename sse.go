@@ -0,0 +1,129 @@
+package strut
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/modfin/strut/schema"
+	"github.com/modfin/strut/swag"
+)
+
+// Event is a server-sent event, the data being encoded as JSON
+type Event[T any] struct {
+	ID    string // optional, lets clients resume from it using the Last-Event-ID header
+	Event string // optional event type, clients treat events without one as "message"
+	Data  T
+}
+
+// HandlerSSE streams events by calling send until ctx is done, i.e. the client disconnected.
+// send flushes each event to the client and fails once the client is gone, or for IDs and types with line breaks
+type HandlerSSE[T any] func(ctx context.Context, send func(event Event[T]) error) error
+
+// SSE registers a GET endpoint streaming server-sent events, documented as text/event-stream
+func SSE[T any](s *Strut, path string, handler HandlerSSE[T], ops ...OpConfig) {
+
+	op := assignOperation(ops...)
+	setOperation(s, path, http.MethodGet, op)
+	assignEventStream(op)
+
+	s.handle(http.MethodGet, path, func(w http.ResponseWriter, r *http.Request) {
+		if !checkRequest(op, w, r) {
+			return
+		}
+		ctx := decorateContext(s, op, r, w)
+		if !canFlush(w) { // fail before committing to the stream by its status
+			s.log.ErrorContext(ctx, "response writer does not support streaming")
+			_ = RespondError[any](http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError)).Respond(w, r)
+			return
+		}
+
+		rc := http.NewResponseController(w)
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.WriteHeader(successCode(op))
+		if err := rc.Flush(); err != nil {
+			s.log.ErrorContext(ctx, "response writer does not support streaming", "error", err)
+			return
+		}
+
+		send := func(event Event[T]) error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if err := writeEvent(w, event); err != nil {
+				return err
+			}
+			return rc.Flush()
+		}
+		err := handler(ctx, send)
+		if err != nil && !errors.Is(err, context.Canceled) {
+			s.log.ErrorContext(ctx, "error streaming events", "error", err)
+		}
+	})
+}
+
+// canFlush reports whether w, or a writer it wraps, flushes, looking it up the way http.ResponseController does
+func canFlush(w http.ResponseWriter) bool {
+	for {
+		switch t := w.(type) {
+		case http.Flusher, interface{ FlushError() error }:
+			return true
+		case interface{ Unwrap() http.ResponseWriter }:
+			w = t.Unwrap()
+		default:
+			return false
+		}
+	}
+}
+
+// writeEvent writes the event in the text/event-stream format, refusing IDs and types that would break out of
+// their line into fields or events of their own
+func writeEvent[T any](w http.ResponseWriter, event Event[T]) error {
+	if strings.ContainsAny(event.ID, "\r\n") {
+		return fmt.Errorf("event id %q contains a line break", event.ID)
+	}
+	if strings.ContainsAny(event.Event, "\r\n") {
+		return fmt.Errorf("event type %q contains a line break", event.Event)
+	}
+	data, err := json.Marshal(event.Data)
+	if err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	if event.ID != "" {
+		fmt.Fprintf(&b, "id: %s\n", event.ID)
+	}
+	if event.Event != "" {
+		fmt.Fprintf(&b, "event: %s\n", event.Event)
+	}
+	fmt.Fprintf(&b, "data: %s\n\n", data)
+	_, err = w.Write([]byte(b.String()))
+	return err
+}
+
+// assignEventStream documents the successful response of the operation as a text/event-stream
+func assignEventStream(op *swag.Operation) {
+	code := strconv.Itoa(successCode(op))
+	if op.Responses == nil {
+		op.Responses = map[string]*swag.OpResponse{}
+	}
+	if op.Responses[code] == nil {
+		op.Responses[code] = &swag.OpResponse{}
+	}
+	res := op.Responses[code]
+	if res.Description == "" {
+		res.Description = "A stream of server-sent events"
+	}
+	if res.Content == nil {
+		res.Content = map[string]swag.MediaType{}
+	}
+	mediaType := res.Content["text/event-stream"]
+	mediaType.Schema = &schema.JSON{Type: schema.String}
+	res.Content["text/event-stream"] = mediaType
+}
@@ -1,14 +1,28 @@
 package strut
 
 import (
+	"bytes"
 	"compress/gzip"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"maps"
+	"mime"
 	"net/http"
 	"path/filepath"
 	"reflect"
+	"regexp"
+	"runtime/debug"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/modfin/strut/schema"
@@ -16,11 +30,48 @@ import (
 	"gopkg.in/yaml.v3"
 )
 
-func New(log *slog.Logger, mux chi.Router) *Strut {
+// Option configures a Strut when created by New
+type Option func(s *Strut)
 
-	return &Strut{
-		log: log,
-		mux: mux,
+// WithRecover recovers panics of handlers, responding them as a 500 Error, see Strut.Recoverer
+func WithRecover() Option {
+	return func(s *Strut) {
+		s.Use(s.Recoverer)
+	}
+}
+
+// WithOpenAPIVersion sets the OpenAPI version of the spec, 3.0.3 by default. Specs of 3.1 encode their
+// schemas as JSON Schema 2020-12, e.g. nullable types as a type array including null
+func WithOpenAPIVersion(version string) Option {
+	return func(s *Strut) {
+		s.Definition.OpenAPI = version
+	}
+}
+
+// WithRespondedStatuses documents the statuses responded by e.g. RespondCreated or RespondError the first time they
+// are responded, while the spec isn't frozen. The spec then depends on the requests served so far, so statuses are
+// better declared by with.Responds
+func WithRespondedStatuses() Option {
+	return func(s *Strut) {
+		s.spec.respondedStatuses = true
+	}
+}
+
+// WithSchemaNamer names the components of request and response types by namer, instead of pkg_Name, e.g. by
+// the type name only or by the full import path. Types implementing SchemaNamer still choose their own name
+func WithSchemaNamer(namer func(reflect.Type) string) Option {
+	return func(s *Strut) {
+		s.spec.schemaNamer = namer
+	}
+}
+
+// New creates a Strut registering endpoints on mux, e.g. a chi router or an http.ServeMux adapted by ServeMux
+func New(log *slog.Logger, mux Router, opts ...Option) *Strut {
+	s := &Strut{
+		log:         log,
+		mux:         mux,
+		contentType: "application/json",
+		spec:        &specState{schemaTypes: map[string]reflect.Type{}},
 		Definition: &swag.Definition{
 			OpenAPI: "3.0.3",
 			Info: swag.Info{
@@ -34,13 +85,25 @@ func New(log *slog.Logger, mux chi.Router) *Strut {
 			},
 		},
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
 type Strut struct {
 	Definition *swag.Definition
-	mux        chi.Router
+	mux        Router
 	log        *slog.Logger
 	middleware []func(http.Handler) http.Handler
+	prefix     string // of the paths registered, see Route
+
+	contentType  string
+	spec         *specState // shared with clones, as is the Definition
+	redactFields []string
+
+	swaggerUIBaseURL string
+	redocScriptURL   string
 }
 
 func (s *Strut) clone() *Strut {
@@ -49,6 +112,15 @@ func (s *Strut) clone() *Strut {
 		mux:        s.mux,
 		log:        s.log,
 		middleware: append([]func(http.Handler) http.Handler(nil), s.middleware...),
+		prefix:     s.prefix,
+
+		contentType: s.contentType,
+		spec:        s.spec,
+
+		redactFields: append([]string(nil), s.redactFields...),
+
+		swaggerUIBaseURL: s.swaggerUIBaseURL,
+		redocScriptURL:   s.redocScriptURL,
 	}
 }
 
@@ -56,22 +128,165 @@ func (s *Strut) Use(middlewares ...func(http.Handler) http.Handler) {
 	s.middleware = append(s.middleware, middlewares...)
 }
 
+// Recoverer is middleware recovering panics of the handlers it wraps. The panic is logged with its stack
+// and responded as a 500 Error, except for http.ErrAbortHandler which is left to abort the request
+func (s *Strut) Recoverer(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			}
+			if rec == http.ErrAbortHandler {
+				panic(rec)
+			}
+			s.log.ErrorContext(r.Context(), "panic handling request", "panic", rec, "method", r.Method, "path", r.URL.Path, "stack", string(debug.Stack()))
+			_ = RespondError[any](http.StatusInternalServerError, "internal error").Respond(w, r)
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
+
 func (s *Strut) Group(fn func(s *Strut)) {
 	ss := s.clone()
-	s.mux.Group(func(r chi.Router) {
-		ss.mux = r
+	switch mux := s.mux.(type) {
+	case chi.Router:
+		mux.Group(func(r chi.Router) {
+			ss.mux = r
+			fn(ss)
+		})
+	case *serveMux:
+		ss.mux = mux.route("")
 		fn(ss)
-	})
+	default:
+		fn(ss)
+	}
+}
+
+// Route lets fn register endpoints under the prefix, e.g. /orders, like Group does with middleware. Paths are
+// documented in the spec with the prefix, which nested routes add to
+func (s *Strut) Route(prefix string, fn func(s *Strut)) {
+	ss := s.clone()
+	ss.prefix = joinPath(s.prefix, prefix)
+	switch mux := s.mux.(type) {
+	case chi.Router:
+		mux.Route(prefix, func(r chi.Router) {
+			ss.mux = r
+			fn(ss)
+		})
+	case *serveMux:
+		ss.mux = mux.route(prefix)
+		fn(ss)
+	default:
+		panic(fmt.Sprintf("strut: Route is not supported by %T", s.mux))
+	}
+}
+
+// joinPath prefixes the path, the root path of a prefix being the prefix itself as it's routed by chi
+func joinPath(prefix string, path string) string {
+	prefix = strings.TrimSuffix(prefix, "/")
+	if path == "/" && prefix != "" {
+		return prefix
+	}
+	return prefix + path
 }
 
 func (s *Strut) With(middleware ...func(http.Handler) http.Handler) *Strut {
 	ss := s.clone()
-	ss.mux = ss.mux.With()
+	switch mux := s.mux.(type) {
+	case chi.Router:
+		ss.mux = mux.With()
+	case *serveMux:
+		ss.mux = mux.route("")
+	}
 	ss.middleware = append(ss.middleware, middleware...)
 	return ss
 }
 
+// Router returns the router endpoints are registered on, e.g. a chi.Router
+func (s *Strut) Router() Router {
+	return s.mux
+}
+
+// UseRouter lets fn register routes that are not part of the spec, e.g. health checks or static files,
+// on the chi router with the middleware of s applied. Such routes of an http.ServeMux are registered on it directly
+func (s *Strut) UseRouter(fn func(r chi.Router)) {
+	mux, ok := s.mux.(chi.Router)
+	if !ok {
+		panic(fmt.Sprintf("strut: UseRouter is not supported by %T", s.mux))
+	}
+	fn(mux.With(s.middleware...))
+}
+
+// handle registers the handler of the method on the path, with the middleware of s applied
+func (s *Strut) handle(method string, path string, h http.HandlerFunc) {
+	s.mux.Method(method, path, chain(s.middleware, h))
+}
+
+// Mount serves the endpoints of sub under the prefix, e.g. /v1, with the middleware of s applied, and merges its
+// paths, re-keyed by the prefix, components and tags into the spec of s. Mounted operations keep the security of
+// sub rather than that of s. Endpoints are to be registered on sub before it's mounted, those registered after are
+// served but not documented by s. Components colliding with those of s are warned about, as by addComponent
+func (s *Strut) Mount(prefix string, sub *Strut) {
+	s.mustBeMutable()
+	switch mux := s.mux.(type) {
+	case chi.Router:
+		mux.With(s.middleware...).Mount(prefix, sub.mux)
+	case *serveMux:
+		mux.mount(prefix, chain(s.middleware, sub.mux))
+	default:
+		panic(fmt.Sprintf("strut: Mount is not supported by %T", s.mux))
+	}
+
+	specMu.Lock()
+	defer specMu.Unlock()
+	for path, p := range sub.Definition.Paths {
+		full := joinPath(s.prefix, joinPath(prefix, path))
+		if _, ok := s.Definition.Paths[full]; ok {
+			s.log.Warn("path mounted twice, replacing the first", "path", full)
+		}
+		s.Definition.Paths[full] = p
+		for _, op := range p.Operations() {
+			if op.Security == nil && (sub.Definition.Security != nil || s.Definition.Security != nil) {
+				security := append([]swag.SecurityRequirement{}, sub.Definition.Security...)
+				op.Security = &security
+			}
+		}
+	}
+	for name, js := range sub.Definition.Components.Schemas {
+		s.addComponent(name, sub.spec.schemaTypes[name], js)
+	}
+	for name, res := range sub.Definition.Components.Responses {
+		if s.Definition.Components.Responses == nil {
+			s.Definition.Components.Responses = map[string]*swag.OpResponse{}
+		}
+		s.Definition.Components.Responses[name] = res
+	}
+	for name, param := range sub.Definition.Components.Parameters {
+		if s.Definition.Components.Parameters == nil {
+			s.Definition.Components.Parameters = map[string]*swag.Param{}
+		}
+		s.Definition.Components.Parameters[name] = param
+	}
+	for name, scheme := range sub.Definition.Components.SecuritySchemes {
+		if s.Definition.Components.SecuritySchemes == nil {
+			s.Definition.Components.SecuritySchemes = map[string]*swag.SecurityScheme{}
+		}
+		s.Definition.Components.SecuritySchemes[name] = scheme
+	}
+	for _, tag := range sub.Definition.Tags {
+		if t := s.tag(tag.Name); t.Description == "" && t.ExternalDocs == nil {
+			*t = tag
+		}
+	}
+	if sub.spec.respondedStatuses { // errors the endpoints of sub respond with are documented once they do
+		name := sub.componentName(reflect.TypeFor[Error]())
+		s.addComponent(name, reflect.TypeFor[Error](), schema.From(Error{}))
+	}
+}
+
 func (s *Strut) AddServer(url string, description string) *Strut {
+	s.mustBeMutable()
 	s.Definition.Servers = append(s.Definition.Servers, swag.Server{
 		URL:         url,
 		Description: description,
@@ -79,44 +294,562 @@ func (s *Strut) AddServer(url string, description string) *Strut {
 	return s
 }
 
+// AddServerWithVars adds a server whose URL is templated by variables, e.g. https://{region}.api.example.com/{version},
+// each of which must have a default. It panics if a variable of the URL is missing or has no default
+func (s *Strut) AddServerWithVars(url string, description string, vars map[string]swag.ServerVariable) *Strut {
+	s.mustBeMutable()
+	if err := validateServerVariables(url, vars); err != nil {
+		panic("strut: " + err.Error())
+	}
+	s.Definition.Servers = append(s.Definition.Servers, swag.Server{
+		URL:         url,
+		Description: description,
+		Variables:   vars,
+	})
+	return s
+}
+
+// validateServerVariables checks that every variable templated in the URL is declared, with a default among its enum
+func validateServerVariables(url string, vars map[string]swag.ServerVariable) error {
+	rest := url
+	for {
+		start := strings.Index(rest, "{")
+		if start < 0 {
+			return nil
+		}
+		end := strings.Index(rest[start:], "}")
+		if end < 0 {
+			return fmt.Errorf("unclosed variable in server %s", url)
+		}
+		name := rest[start+1 : start+end]
+		rest = rest[start+end+1:]
+
+		v, ok := vars[name]
+		if !ok {
+			return fmt.Errorf("variable %s of server %s is not declared", name, url)
+		}
+		if v.Default == "" {
+			return fmt.Errorf("variable %s of server %s has no default", name, url)
+		}
+		if len(v.Enum) > 0 && !slices.Contains(v.Enum, v.Default) {
+			return fmt.Errorf("default %s of variable %s of server %s is not one of its enum", v.Default, name, url)
+		}
+	}
+}
+
+// AddResponse adds a response to the components of the spec, e.g. a shared 404 Error,
+// for operations to reference by with.ResponseRef
+func (s *Strut) AddResponse(name string, res *swag.OpResponse) *Strut {
+	s.mustBeMutable()
+	if s.Definition.Components.Responses == nil {
+		s.Definition.Components.Responses = map[string]*swag.OpResponse{}
+	}
+	s.Definition.Components.Responses[name] = res
+	return s
+}
+
+// AddParam adds a parameter to the components of the spec, e.g. page or limit of list endpoints,
+// for operations to reference by with.ParamRef
+func (s *Strut) AddParam(name string, param swag.Param) *Strut {
+	s.mustBeMutable()
+	if s.Definition.Components.Parameters == nil {
+		s.Definition.Components.Parameters = map[string]*swag.Param{}
+	}
+	s.Definition.Components.Parameters[name] = &param
+	return s
+}
+
+// AddSecurityScheme adds a security scheme to the components of the spec, for Security and with.Security to require
+func (s *Strut) AddSecurityScheme(name string, scheme swag.SecurityScheme) *Strut {
+	s.mustBeMutable()
+	if s.Definition.Components.SecuritySchemes == nil {
+		s.Definition.Components.SecuritySchemes = map[string]*swag.SecurityScheme{}
+	}
+	s.Definition.Components.SecuritySchemes[name] = &scheme
+	return s
+}
+
+// Security requires the security scheme, with the scopes, of every operation not declaring its own security by
+// with.Security or with.NoSecurity. Each call adds a requirement, any of which satisfies the security of the API
+func (s *Strut) Security(name string, scopes ...string) *Strut {
+	s.mustBeMutable()
+	s.Definition.Security = append(s.Definition.Security, swag.SecurityRequirement{name: append([]string{}, scopes...)})
+	return s
+}
+
+// PathServers sets the servers of the operations of the path, overriding those added by AddServer,
+// e.g. for an upload endpoint served from another host
+func (s *Strut) PathServers(path string, servers ...swag.Server) *Strut {
+	getPath(s, path).Servers = servers
+	return s
+}
+
+// AddTag describes a tag operations are grouped by, see with.Tags
+func (s *Strut) AddTag(name string, description string) *Strut {
+	s.mustBeMutable()
+	s.tag(name).Description = description
+	return s
+}
+
+// TagExternalDocs links the tag to documentation outside the spec
+func (s *Strut) TagExternalDocs(name string, url string, description string) *Strut {
+	s.mustBeMutable()
+	s.tag(name).ExternalDocs = &swag.ExternalDocs{URL: url, Description: description}
+	return s
+}
+
+// tag returns the tag of the given name, adding it if it doesn't exist
+func (s *Strut) tag(name string) *swag.Tag {
+	for i := range s.Definition.Tags {
+		if s.Definition.Tags[i].Name == name {
+			return &s.Definition.Tags[i]
+		}
+	}
+	s.Definition.Tags = append(s.Definition.Tags, swag.Tag{Name: name})
+	return &s.Definition.Tags[len(s.Definition.Tags)-1]
+}
+
+// markDeprecatedPaths flags the paths whose operations are all deprecated, for UIs to hint at it on the path
+func markDeprecatedPaths(d *swag.Definition) {
+	for _, path := range d.Paths {
+		ops := path.Operations()
+		path.Deprecated = len(ops) > 0
+		for _, op := range ops {
+			if !op.Deprecated {
+				path.Deprecated = false
+				break
+			}
+		}
+	}
+}
+
+// declareTags adds the tags used by operations that weren't declared by AddTag, after the declared ones
+func (s *Strut) declareTags() {
+	used := map[string]bool{}
+	for _, paths := range []map[string]*swag.Path{s.Definition.Paths, s.Definition.Webhooks} {
+		for _, path := range paths {
+			for _, op := range path.Operations() {
+				for _, name := range op.Tags {
+					used[name] = true
+				}
+			}
+		}
+	}
+	for _, tag := range s.Definition.Tags {
+		delete(used, tag.Name)
+	}
+
+	names := make([]string, 0, len(used))
+	for name := range used {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		s.tag(name)
+	}
+}
+
 func (s *Strut) Title(title string) *Strut {
+	s.mustBeMutable()
 	s.Definition.Info.Title = title
 	return s
 
 }
 func (s *Strut) Description(description string) *Strut {
+	s.mustBeMutable()
 	s.Definition.Info.Description = description
 	return s
 
 }
 func (s *Strut) Version(version string) *Strut {
+	s.mustBeMutable()
 	s.Definition.Info.Version = version
 	return s
 
 }
 
-func (s *Strut) SchemaHandlerYAML(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/yaml")
-	err := yaml.NewEncoder(w).Encode(s.Definition)
+// ExternalDocs links documentation of the API outside the spec, e.g. a developer portal
+func (s *Strut) ExternalDocs(url string, description string) *Strut {
+	s.mustBeMutable()
+	s.Definition.ExternalDocs = &swag.ExternalDocs{URL: url, Description: description}
+	return s
+}
+
+// TermsOfService links the terms of service of the API
+func (s *Strut) TermsOfService(url string) *Strut {
+	s.mustBeMutable()
+	s.Definition.Info.TermsOfService = url
+	return s
+}
+
+// Contact sets who to contact about the API, empty values being left out
+func (s *Strut) Contact(name string, url string, email string) *Strut {
+	s.mustBeMutable()
+	s.Definition.Info.Contact = &swag.Contact{Name: name, URL: url, Email: email}
+	return s
+}
+
+// License sets the license the API is offered under, linking its text
+func (s *Strut) License(name string, url string) *Strut {
+	s.mustBeMutable()
+	s.Definition.Info.License = &swag.License{Name: name, URL: url}
+	return s
+}
+
+// LicenseIdentifier sets the license the API is offered under by its SPDX identifier, e.g. Apache-2.0.
+// Identifiers are part of OpenAPI 3.1
+func (s *Strut) LicenseIdentifier(name string, identifier string) *Strut {
+	s.mustBeMutable()
+	s.Definition.Info.License = &swag.License{Name: name, Identifier: identifier}
+	return s
+}
+
+// DefaultContentType sets the content type of request and response bodies, e.g. application/vnd.api+json,
+// both in the spec and in the Content-Type of responses. Defaults to application/json
+func (s *Strut) DefaultContentType(contentType string) *Strut {
+	s.mustBeMutable()
+	s.contentType = contentType
+	return s
+}
+
+// specMu serializes building the spec, which adjusts the schemas of a Definition in place before encoding it,
+// with documenting statuses as they are responded
+var specMu sync.RWMutex
+
+// buildSpec prepares the Definition for encoding, e.g. encoding schemas in the dialect of the OpenAPI version,
+// and encodes it while it can't be adjusted underneath
+func (s *Strut) buildSpec(encode func(w io.Writer, d *swag.Definition) error) ([]byte, error) {
+	specMu.Lock()
+	defer specMu.Unlock()
+	d := s.prepareSpec()
+
+	var buf bytes.Buffer
+	err := encode(&buf, d)
+	return buf.Bytes(), err
+}
+
+// prepareSpec adjusts the Definition to be encoded, specMu being held, and returns what to encode: the Definition
+// itself, or a copy of it with the curl samples of its operations or without the webhooks OpenAPI 3.0 has no place for
+func (s *Strut) prepareSpec() *swag.Definition {
+	dialect := schema.OpenAPI30
+	if !strings.HasPrefix(s.Definition.OpenAPI, "3.0") {
+		dialect = schema.JSONSchema
+	}
+	s.Definition.EachSchema(func(js *schema.JSON) {
+		schema.SetDialect(js, dialect)
+	})
+	s.declareTags()
+	markDeprecatedPaths(s.Definition)
+	d := s.Definition
+	if s.spec.curlSamples {
+		d = withCurlSamples(d)
+	}
+	if dialect == schema.OpenAPI30 && d.Webhooks != nil {
+		withoutWebhooks := *d
+		withoutWebhooks.Webhooks = nil // reported by Validate
+		d = &withoutWebhooks
+	}
+	return d
+}
+
+// specState holds the spec encoded once by Freeze
+type specState struct {
+	mu     sync.RWMutex
+	frozen bool
+	json   encodedSpec
+	yaml   encodedSpec
+
+	curlSamples bool // see GenerateCurlSamples
+
+	respondedStatuses bool // see WithRespondedStatuses
+
+	schemaNamer func(reflect.Type) string // see WithSchemaNamer
+	schemaTypes map[string]reflect.Type   // the type each component schema was named for, to tell collisions
+}
+
+type encodedSpec struct {
+	body []byte
+	etag string
+
+	gzipped     []byte // body compressed by Freeze, served to clients accepting gzip
+	gzippedETag string
+}
+
+func newEncodedSpec(body []byte) encodedSpec {
+	sum := sha256.Sum256(body)
+	return encodedSpec{body: body, etag: `"` + hex.EncodeToString(sum[:16]) + `"`}
+}
+
+// newFrozenSpec encodes body like newEncodedSpec, compressing it as well
+func newFrozenSpec(body []byte) (encodedSpec, error) {
+	spec := newEncodedSpec(body)
+
+	var buf bytes.Buffer
+	gz, err := gzip.NewWriterLevel(&buf, gzip.BestCompression)
 	if err != nil {
-		slog.Error("error encoding schema", "error", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return spec, err
+	}
+	if _, err := gz.Write(body); err != nil {
+		return spec, err
+	}
+	if err := gz.Close(); err != nil {
+		return spec, err
 	}
+	spec.gzipped = buf.Bytes()
+	spec.gzippedETag = strings.TrimSuffix(spec.etag, `"`) + `-gzip"` // another representation, so another ETag
+	return spec, nil
 }
 
-func (s *Strut) SchemaHandlerJSON(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	err := json.NewEncoder(w).Encode(s.Definition)
+// Freeze encodes the spec once, which SchemaHandlerJSON and SchemaHandlerYAML then serve without
+// encoding it per request. Registering endpoints or changing the spec through s afterwards panics,
+// changes made to the Definition directly are not picked up
+func (s *Strut) Freeze() error {
+	jsonSpec, err := s.buildSpec(func(w io.Writer, d *swag.Definition) error {
+		return json.NewEncoder(w).Encode(d)
+	})
 	if err != nil {
-		slog.Error("error encoding schema", "error", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return err
+	}
+	yamlSpec, err := s.buildSpec(func(w io.Writer, d *swag.Definition) error {
+		return yaml.NewEncoder(w).Encode(d)
+	})
+	if err != nil {
+		return err
+	}
+
+	frozenJSON, err := newFrozenSpec(jsonSpec)
+	if err != nil {
+		return err
+	}
+	frozenYAML, err := newFrozenSpec(yamlSpec)
+	if err != nil {
+		return err
+	}
+
+	s.spec.mu.Lock()
+	defer s.spec.mu.Unlock()
+	s.spec.frozen = true
+	s.spec.json = frozenJSON
+	s.spec.yaml = frozenYAML
+	return nil
+}
+
+func (s *Strut) mustBeMutable() {
+	s.spec.mu.RLock()
+	defer s.spec.mu.RUnlock()
+	if s.spec.frozen {
+		panic("strut: the spec can't be changed after Freeze")
+	}
+}
+
+// SchemaHandlerYAML serves the spec as YAML. Maps, e.g. of paths, responses, components and properties,
+// are encoded by their sorted keys, so the same spec is encoded to the same bytes whatever order it was built in
+func (s *Strut) SchemaHandlerYAML(w http.ResponseWriter, r *http.Request) {
+	s.serveSpec(w, r, "application/yaml", func() (encodedSpec, bool) {
+		return s.spec.yaml, s.spec.frozen
+	}, func(w io.Writer, d *swag.Definition) error {
+		return yaml.NewEncoder(w).Encode(d)
+	})
+}
+
+// SchemaHandlerJSON serves the spec as JSON, encoded as deterministically as by SchemaHandlerYAML
+func (s *Strut) SchemaHandlerJSON(w http.ResponseWriter, r *http.Request) {
+	s.serveSpec(w, r, "application/json", func() (encodedSpec, bool) {
+		return s.spec.json, s.spec.frozen
+	}, func(w io.Writer, d *swag.Definition) error {
+		return json.NewEncoder(w).Encode(d)
+	})
+}
+
+// SchemaHandler serves the spec as YAML or JSON depending on the Accept header of the request, JSON being
+// preferred for */* or no Accept header at all. Anything else is answered with 406 Not Acceptable
+func (s *Strut) SchemaHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Vary", "Accept")
+	switch negotiateSpec(r.Header.Get("Accept")) {
+	case "json":
+		s.SchemaHandlerJSON(w, r)
+	case "yaml":
+		s.SchemaHandlerYAML(w, r)
+	default:
+		http.Error(w, "the spec is available as application/json or application/yaml", http.StatusNotAcceptable)
+	}
+}
+
+// negotiateContentType picks the media type the Accept header prefers among the offered ones, the first one if it
+// doesn't accept any of them
+func negotiateContentType(accept string, offered []string) string {
+	if contentType := negotiate(accept, offered); contentType != "" {
+		return contentType
+	}
+	return offered[0]
+}
+
+// isXML reports whether the media type is XML, e.g. application/xml or application/atom+xml
+func isXML(mediaType string) bool {
+	return mediaType == "application/xml" || mediaType == "text/xml" || strings.HasSuffix(mediaType, "+xml")
+}
+
+// negotiateSpec picks "json" or "yaml" for the media ranges of the Accept header, by their quality,
+// or "" if neither is acceptable
+func negotiateSpec(accept string) string {
+	if strings.TrimSpace(accept) == "" {
+		return "json"
+	}
+	switch negotiate(accept, []string{"application/json", "application/yaml", "text/yaml"}) {
+	case "application/json":
+		return "json"
+	case "application/yaml", "text/yaml":
+		return "yaml"
+	}
+	return ""
+}
+
+// mediaRange is a media range of an Accept header, e.g. application/*;q=0.5
+type mediaRange struct {
+	mediaType string
+	q         float64
+}
+
+// parseAccept returns the media ranges of the Accept header, their quality being 1 unless given by q
+func parseAccept(accept string) []mediaRange {
+	var ranges []mediaRange
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, _ := strings.Cut(part, ";")
+		mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+		if mediaType == "" {
+			continue
+		}
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			if value, ok := strings.CutPrefix(strings.TrimSpace(param), "q="); ok {
+				if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+		ranges = append(ranges, mediaRange{mediaType: mediaType, q: q})
+	}
+	return ranges
+}
+
+// quality returns the quality of the media type given by the most specific of the ranges matching it, e.g.
+// application/json before application/* before */*, or 0 if none matches
+func quality(ranges []mediaRange, mediaType string) float64 {
+	mediaType = strings.ToLower(mediaType)
+	kind, _, _ := strings.Cut(mediaType, "/")
+	q, specificity := 0.0, -1
+	for _, r := range ranges {
+		matched := -1
+		switch r.mediaType {
+		case mediaType:
+			matched = 2
+		case kind + "/*":
+			matched = 1
+		case "*/*":
+			matched = 0
+		}
+		if matched > specificity {
+			q, specificity = r.q, matched
+		}
+	}
+	return q
+}
+
+// negotiate picks the offered media type of the highest quality in the Accept header, the first of those
+// equally acceptable, or "" if none is acceptable
+func negotiate(accept string, offered []string) string {
+	ranges := parseAccept(accept)
+	best, bestQ := "", 0.0
+	for _, mediaType := range offered {
+		if q := quality(ranges, mediaType); q > bestQ {
+			best, bestQ = mediaType, q
+		}
+	}
+	return best
+}
+
+// serveSpec writes the frozen spec, or encodes it if not frozen, answering 304 if the client has it already
+func (s *Strut) serveSpec(w http.ResponseWriter, r *http.Request, contentType string, frozen func() (encodedSpec, bool), encode func(w io.Writer, d *swag.Definition) error) {
+	s.spec.mu.RLock()
+	spec, ok := frozen()
+	s.spec.mu.RUnlock()
+
+	if !ok {
+		body, err := s.buildSpec(encode)
+		if err != nil {
+			slog.Error("error encoding schema", "error", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		spec = newEncodedSpec(body)
+	}
+
+	body, etag := spec.body, spec.etag
+	if spec.gzipped != nil {
+		w.Header().Add("Vary", "Accept-Encoding")
+		if acceptsGzip(r.Header.Get("Accept-Encoding")) {
+			body, etag = spec.gzipped, spec.gzippedETag
+			w.Header().Set("Content-Encoding", "gzip")
+		}
+	}
+
+	w.Header().Set("ETag", etag)
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
 	}
+	w.Header().Set("Content-Type", contentType)
+	_, _ = w.Write(body)
 }
 
-func decorateContext(req *http.Request, w http.ResponseWriter) context.Context {
+// acceptsGzip reports whether the Accept-Encoding header allows gzip, i.e. lists gzip or * without q=0
+func acceptsGzip(acceptEncoding string) bool {
+	for _, coding := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(coding, ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name != "gzip" && name != "*" {
+			continue
+		}
+		q := strings.ReplaceAll(strings.TrimSpace(params), " ", "")
+		if q, ok := strings.CutPrefix(q, "q="); ok {
+			if v, err := strconv.ParseFloat(q, 64); err == nil && v == 0 {
+				continue
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// etagMatches reports whether the If-None-Match header lists etag, weak validators matching as well
+func etagMatches(ifNoneMatch string, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// ctxKey is the type of the context keys of strut, not to collide with those of other packages
+type ctxKey int
+
+const (
+	requestKey ctxKey = iota
+	responseWriterKey
+	contentTypeKey
+	operationKey
+	definitionKey
+)
+
+func decorateContext(s *Strut, op *swag.Operation, req *http.Request, w http.ResponseWriter) context.Context {
 	ctx := req.Context()
-	ctx = context.WithValue(ctx, "http-request", req)
-	ctx = context.WithValue(ctx, "http-response-writer", w)
+	ctx = context.WithValue(ctx, requestKey, req)
+	ctx = context.WithValue(ctx, responseWriterKey, w)
+	ctx = context.WithValue(ctx, contentTypeKey, s.contentType)
+	ctx = context.WithValue(ctx, operationKey, op)
+	ctx = context.WithValue(ctx, definitionKey, s.Definition)
 	return ctx
 }
 
@@ -127,55 +860,206 @@ func assignOperation(ops ...OpConfig) *swag.Operation {
 	for _, o := range ops {
 		o(op)
 	}
+	if op.CacheMaxAge > 0 {
+		documentCacheControl(op)
+	}
 	return op
 }
 
-func assignRequest[REQ any](s *Strut, op *swag.Operation) {
+// documentCacheControl documents the Cache-Control header set by with.Cacheable on the success response, whichever
+// order the success code and caching were configured in
+func documentCacheControl(op *swag.Operation) {
+	code := strconv.Itoa(successCode(op))
+	if op.Responses == nil {
+		op.Responses = map[string]*swag.OpResponse{}
+	}
+	if op.Responses[code] == nil {
+		op.Responses[code] = &swag.OpResponse{}
+	}
+	if op.Responses[code].Headers == nil {
+		op.Responses[code].Headers = map[string]swag.Header{}
+	}
+	op.Responses[code].Headers["Cache-Control"] = swag.Header{
+		Description: "How long the response may be cached",
+		Schema:      &schema.JSON{Type: schema.String},
+		Example:     fmt.Sprintf("max-age=%d", int(op.CacheMaxAge.Seconds())),
+	}
+}
+
+// SchemaNamer can be implemented by request and response types to choose their component name in the spec,
+// instead of the default pkg_Name or that of WithSchemaNamer
+type SchemaNamer interface {
+	SchemaName() string
+}
+
+var (
+	// packageQualifier matches the import paths qualifying the type arguments of generic type names,
+	// e.g. github.com/modfin/strut. of Page[github.com/modfin/strut.Order]
+	packageQualifier = regexp.MustCompile(`(?:[A-Za-z0-9_.\-]+/)*[A-Za-z0-9_\-]+\.`)
+	// invalidComponentChars matches what component names can't contain, e.g. the brackets of generic types
+	invalidComponentChars = regexp.MustCompile(`[^A-Za-z0-9_.\-]+`)
+)
+
+// componentName is the key a type is stored under in the components section of the spec. Names are made valid
+// component keys, e.g. Page[github.com/modfin/strut.Order] becoming Page_Order
+func (s *Strut) componentName(t reflect.Type) string {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	var name string
+	if namer, ok := reflect.New(t).Interface().(SchemaNamer); ok {
+		name = namer.SchemaName()
+	} else if s.spec.schemaNamer != nil {
+		name = s.spec.schemaNamer(t)
+	} else {
+		name = fmt.Sprintf("%s_%s", filepath.Base(t.PkgPath()), t.Name())
+	}
+	if i := strings.IndexByte(name, '['); i >= 0 { // only type arguments are qualified, names may contain dots
+		name = name[:i] + packageQualifier.ReplaceAllString(name[i:], "")
+	}
+	return strings.Trim(invalidComponentChars.ReplaceAllString(name, "_"), "_")
+}
+
+// addComponent stores js as the component schema name of t. A schema already stored under the name is kept if it
+// is the same, e.g. for types used by several endpoints, and is replaced otherwise, which is warned about as the
+// name then collides, e.g. for types of packages sharing a base name
+func (s *Strut) addComponent(name string, t reflect.Type, js *schema.JSON) {
+	if existing, ok := s.Definition.Components.Schemas[name]; ok {
+		if existing.Equal(js) {
+			return
+		}
+		s.log.Warn("schema name collides, replacing the component", "name", name, "type", fmt.Sprint(t),
+			"previous", fmt.Sprint(s.spec.schemaTypes[name]))
+	}
+	s.spec.schemaTypes[name] = t
+	s.Definition.Components.Schemas[name] = js.Clone() // the spec may be adjusted, e.g. by SetDialect, without affecting validation
+}
+
+// schemaOf converts v like schema.From, logging the problems met converting it, e.g. unsupported field types,
+// when the endpoint is registered
+func (s *Strut) schemaOf(v any) *schema.JSON {
+	js, err := schema.FromE(v)
+	if err != nil {
+		s.log.Warn("schema converted best-effort", "type", fmt.Sprint(reflect.TypeOf(v)), "error", err)
+	}
+	return js
+}
+
+func assignRequest[REQ any](s *Strut, op *swag.Operation) *schema.JSON {
 	var req REQ
-	reqSchema := schema.From(req)
-	reqType := reflect.TypeOf(req)
-	reqName := reqType.Name()
-	reqPkg := filepath.Base(reqType.PkgPath())
-	reqUri := fmt.Sprintf("%s_%s", reqPkg, reqName)
+	reqSchema := s.schemaOf(req)
+	reqUri := s.componentName(reflect.TypeFor[REQ]())
 
 	reqRef := "#/components/schemas/" + reqUri
-	s.Definition.Components.Schemas[reqUri] = reqSchema
+	s.addComponent(reqUri, reflect.TypeFor[REQ](), reqSchema)
 
 	if op.RequestBody == nil { // Defaulting stuff...
 		op.RequestBody = &swag.RequestBody{}
 	}
-	if op.RequestBody.Content == nil {
-		op.RequestBody.Content = map[string]swag.MediaType{}
+	contentType := s.contentType
+	if op.RequestContentType != "" {
+		contentType = op.RequestContentType
 	}
-	op.RequestBody.Content["application/json"] = swag.MediaType{
-		Schema: &schema.JSON{Ref: reqRef},
+	op.RequestBody.Content = assignSchema(op.RequestBody.Content, contentType, reqRef)
+	return reqSchema
+}
+
+// successCode is the status the response body of the operation is documented under, see with.SuccessCode
+func successCode(op *swag.Operation) int {
+	if op == nil || op.SuccessCode == 0 {
+		return http.StatusOK
 	}
+	return op.SuccessCode
 }
-func assignResponse[RES any](s *Strut, op *swag.Operation) {
-	var res RES
-	resSchema := schema.From(res)
 
-	resType := reflect.TypeOf(res)
-	resName := resType.Name()
-	resPkg := filepath.Base(resType.PkgPath())
-	resUri := fmt.Sprintf("%s_%s", resPkg, resName)
-	resRef := "#/components/schemas/" + resUri
-	s.Definition.Components.Schemas[resUri] = resSchema
+func assignResponse[RES any](s *Strut, op *swag.Operation) *schema.JSON {
+	var res RES
+	resSchema := s.schemaOf(res)
+	defer documentStatuses(s, op) // once the success response is, for typed bodies to be documented like it
+	_, noBody := any(res).(NoBody)
+	if noBody && op.SuccessCode == 0 {
+		op.SuccessCode = http.StatusNoContent
+	}
+	code := strconv.Itoa(successCode(op))
+	contentTypes := op.ResponseContentTypes
+	if len(contentTypes) == 0 {
+		contentTypes = []string{s.contentType}
+	}
+	if res := op.Responses[code]; res != nil && (res.Ref != "" || documentsOtherContent(res.Content, contentTypes[0])) {
+		return resSchema
+	}
 	if op.Responses == nil {
 		op.Responses = map[string]*swag.OpResponse{}
 	}
-	if op.Responses["200"] == nil {
-		op.Responses["200"] = &swag.OpResponse{}
+	if op.Responses[code] == nil {
+		op.Responses[code] = &swag.OpResponse{}
+	}
+	if noBody || successCode(op) == http.StatusNoContent { // documented without a body
+		if op.Responses[code].Description == "" {
+			op.Responses[code].Description = http.StatusText(successCode(op))
+		}
+		return resSchema
+	}
+	resUri := s.componentName(reflect.TypeFor[RES]())
+	resRef := "#/components/schemas/" + resUri
+	s.addComponent(resUri, reflect.TypeFor[RES](), resSchema)
+	for _, contentType := range contentTypes {
+		op.Responses[code].Content = assignSchema(op.Responses[code].Content, contentType, resRef)
+	}
+	return resSchema
+}
+
+// assignSchema references the schema from the media type of the content, keeping e.g. examples already set on it.
+// Examples are set before the content type is known and default to JSON, so they are moved over to it
+func assignSchema(content map[string]swag.MediaType, contentType string, ref string) map[string]swag.MediaType {
+	if content == nil {
+		content = map[string]swag.MediaType{}
+	}
+	mediaType, ok := content[contentType]
+	if def, isDefault := content["application/json"]; !ok && isDefault && def.Schema == nil {
+		mediaType = def
+		delete(content, "application/json")
 	}
-	if op.Responses["200"].Content == nil {
-		op.Responses["200"].Content = map[string]swag.MediaType{}
+	mediaType.Schema = &schema.JSON{Ref: ref}
+	content[contentType] = mediaType
+	return content
+}
+
+// documentsOtherContent reports whether the content already documents a schema, e.g. by with.ResponseContentType,
+// for a media type other than contentType, in which case the response isn't documented as contentType
+func documentsOtherContent(content map[string]swag.MediaType, contentType string) bool {
+	if _, ok := content[contentType]; ok {
+		return false
+	}
+	for _, mediaType := range content {
+		if mediaType.Schema != nil {
+			return true
+		}
 	}
-	op.Responses["200"].Content["application/json"] = swag.MediaType{
-		Schema: &schema.JSON{Ref: resRef},
+	return false
+}
+
+// setOperation sets the operation of the method on the path, warning if it replaces one registered before,
+// most likely by mistake
+func setOperation(s *Strut, path string, method string, op *swag.Operation) {
+	setItemOperation(s, getPath(s, path), "path", joinPath(s.prefix, path), method, op)
+}
+
+// setItemOperation sets the operation of the method on the item, a path or webhook of the spec whose kind and key
+// are logged, warning if it replaces one registered before
+func setItemOperation(s *Strut, item *swag.Path, kind string, key string, method string, op *swag.Operation) {
+	if replaced := item.SetOperation(method, op); replaced != nil {
+		s.log.Warn("operation registered twice, replacing the first", "method", method, kind, key,
+			"operationId", op.OperationID, "replacedOperationId", replaced.OperationID)
 	}
 }
 
-func getPath(d *swag.Definition, path string) *swag.Path {
+// getPath returns the path of the spec the path registered on s is documented under, adding it if it doesn't exist
+func getPath(s *Strut, path string) *swag.Path {
+	s.mustBeMutable()
+	path = joinPath(s.prefix, path)
+	d := s.Definition
 	if d.Paths == nil {
 		d.Paths = map[string]*swag.Path{}
 	}
@@ -187,13 +1071,349 @@ func getPath(d *swag.Definition, path string) *swag.Path {
 
 func createResponse(s *Strut, ctx context.Context, responder Response[any]) {
 	w, r := HTTPResponseWriter(ctx), HTTPRequest(ctx)
-	err := responder.Respond(w, r)
+	err := responder.Respond(w, r.WithContext(ctx))
 	if err != nil {
 		s.log.Error("error responding", "error", err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 	}
 }
 
+// operationResponse applies the runtime behaviour configured on the operation to the responder
+func operationResponse(s *Strut, op *swag.Operation, resSchema *schema.JSON, responder Response[any]) Response[any] {
+	documentStatus(s, op, responder)
+	if op.StripWriteOnly {
+		responder = stripWriteOnly(responder, resSchema)
+	}
+	if op.CacheMaxAge > 0 {
+		responder = cacheControl(responder, fmt.Sprintf("max-age=%d", int(op.CacheMaxAge.Seconds())))
+	}
+	return responder
+}
+
+// cacheControl sets the Cache-Control header of successful GET responses, unless set by the handler
+func cacheControl(responder Response[any], value string) Response[any] {
+	return RespondFunc[any](func(w http.ResponseWriter, r *http.Request) error {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			return responder.Respond(w, r)
+		}
+		return responder.Respond(&cachingResponse{ResponseWriter: w, value: value}, r)
+	})
+}
+
+// cachingResponse sets the Cache-Control header once the status is written, if successful
+type cachingResponse struct {
+	http.ResponseWriter
+	value       string
+	wroteHeader bool
+}
+
+func (c *cachingResponse) WriteHeader(status int) {
+	if !c.wroteHeader && status < 300 && c.Header().Get("Cache-Control") == "" {
+		c.Header().Set("Cache-Control", c.value)
+	}
+	c.wroteHeader = true
+	c.ResponseWriter.WriteHeader(status)
+}
+
+func (c *cachingResponse) Write(p []byte) (int, error) {
+	if !c.wroteHeader {
+		c.WriteHeader(http.StatusOK)
+	}
+	return c.ResponseWriter.Write(p)
+}
+
+func (c *cachingResponse) Unwrap() http.ResponseWriter {
+	return c.ResponseWriter
+}
+
+// documentedResponse is implemented by the responses of Respond and the like, knowing their status up front
+type documentedResponse interface {
+	StatusCode() int
+	bodyKind() bodyKind
+}
+
+// documentStatuses adds the statuses declared by with.Responds to the operation, when it's registered
+func documentStatuses(s *Strut, op *swag.Operation) {
+	for _, status := range op.Statuses {
+		kind := bodyTyped
+		switch {
+		case status >= 400:
+			kind = bodyError
+		case status == http.StatusNoContent || status == http.StatusNotModified:
+			kind = bodyNone
+		}
+		addStatus(s, op, status, kind)
+	}
+}
+
+// documentStatus adds the status of the response to the operation in the spec, with WithRespondedStatuses,
+// unless it's frozen or the status is already documented
+func documentStatus(s *Strut, op *swag.Operation, responder Response[any]) {
+	res, ok := responder.(documentedResponse)
+	if !s.spec.respondedStatuses || !ok || res.StatusCode() == 0 {
+		return
+	}
+	s.spec.mu.RLock()
+	frozen := s.spec.frozen
+	s.spec.mu.RUnlock()
+	if frozen {
+		return
+	}
+
+	code := strconv.Itoa(res.StatusCode())
+	specMu.RLock()
+	documented := op.Responses[code] != nil
+	specMu.RUnlock()
+	if documented {
+		return
+	}
+
+	specMu.Lock()
+	defer specMu.Unlock()
+	addStatus(s, op, res.StatusCode(), res.bodyKind())
+}
+
+// addStatus documents the status on the operation, unless it's documented already. Typed bodies are documented
+// like the success response and errors as Error
+func addStatus(s *Strut, op *swag.Operation, status int, kind bodyKind) {
+	code := strconv.Itoa(status)
+	if op.Responses == nil {
+		op.Responses = map[string]*swag.OpResponse{}
+	}
+	if op.Responses[code] != nil {
+		return
+	}
+
+	response := &swag.OpResponse{Description: http.StatusText(status)}
+	switch kind {
+	case bodyTyped:
+		if success := op.Responses[strconv.Itoa(successCode(op))]; success != nil {
+			response.Content = maps.Clone(success.Content)
+		}
+	case bodyError:
+		name := s.componentName(reflect.TypeFor[Error]())
+		s.addComponent(name, reflect.TypeFor[Error](), schema.From(Error{}))
+		response.Content = map[string]swag.MediaType{
+			s.contentType: {Schema: &schema.JSON{Ref: "#/components/schemas/" + name}},
+		}
+	}
+	op.Responses[code] = response
+}
+
+// stripWriteOnly removes writeOnly fields from successful JSON responses
+func stripWriteOnly(responder Response[any], resSchema *schema.JSON) Response[any] {
+	return RespondFunc[any](func(w http.ResponseWriter, r *http.Request) error {
+		buf := &bufferedResponse{ResponseWriter: w, status: http.StatusOK}
+		err := responder.Respond(buf, r)
+		if err != nil {
+			return err
+		}
+
+		body := buf.body.Bytes()
+		mediaType, _, _ := mime.ParseMediaType(w.Header().Get("Content-Type"))
+		if buf.status < 300 && isJSON(mediaType) {
+			var v any
+			if json.Unmarshal(body, &v) == nil {
+				stripped, err := json.Marshal(schema.StripWriteOnly(resSchema, v))
+				if err != nil {
+					return err
+				}
+				body = append(stripped, '\n')
+			}
+		}
+
+		w.Header().Del("Content-Length")
+		w.WriteHeader(buf.status)
+		_, err = w.Write(body)
+		return err
+	})
+}
+
+// isJSON reports whether the media type is JSON, e.g. application/json or application/problem+json
+func isJSON(mediaType string) bool {
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// bufferedResponse holds on to the status and body written, while headers go straight to the wrapped writer
+type bufferedResponse struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (b *bufferedResponse) WriteHeader(status int) {
+	b.status = status
+}
+
+func (b *bufferedResponse) Write(p []byte) (int, error) {
+	return b.body.Write(p)
+}
+
+// decodeRequest reads the JSON request body into REQ, applying the defaults of reqSchema and validating it against it
+// if the operation asks for it. If it fails, an error has already been written to the client
+func decodeRequest[REQ any](s *Strut, ctx context.Context, op *swag.Operation, reqSchema *schema.JSON) (req REQ, ok bool) {
+	var err error
+	r := HTTPRequest(ctx)
+	if isForm(op.RequestContentType) {
+		req, err = decodeForm[REQ](r)
+		if err == nil {
+			err = checkFiles(op, r)
+		}
+		if err != nil {
+			decodeFailed(s, ctx, err)
+			return req, false
+		}
+		return req, true
+	}
+
+	reader := r.Body
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		reader, err = gzip.NewReader(r.Body)
+		if err != nil {
+			s.log.Error("error decoding gzip", "error", err)
+			createResponse(s, ctx, RespondError[any](http.StatusBadRequest, "could not decode request: "+err.Error()))
+			return req, false
+		}
+	}
+
+	if !op.Validate && !op.ApplyDefaults {
+		err = json.NewDecoder(reader).Decode(&req)
+		if err != nil {
+			decodeFailed(s, ctx, err)
+			return req, false
+		}
+		return req, true
+	}
+
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		decodeFailed(s, ctx, err)
+		return req, false
+	}
+	var raw any
+	err = json.Unmarshal(body, &raw)
+	if err != nil {
+		decodeFailed(s, ctx, err)
+		return req, false
+	}
+	if op.ApplyDefaults { // on the raw body, telling absent fields from ones explicitly given as zero
+		raw = schema.ApplyDefaults(reqSchema, raw)
+		body, err = json.Marshal(raw)
+		if err != nil {
+			decodeFailed(s, ctx, err)
+			return req, false
+		}
+	}
+	if op.Validate {
+		if violations := schema.Validate(reqSchema, raw); len(violations) > 0 {
+			createResponse(s, ctx, RespondError[any](http.StatusBadRequest, violationsMessage(violations)))
+			return req, false
+		}
+	}
+	err = json.Unmarshal(body, &req)
+	if err != nil {
+		decodeFailed(s, ctx, err)
+		return req, false
+	}
+	return req, true
+}
+
+// decodeFailed reports a request body that could not be read or decoded as an Error,
+// pointing out where in the body decoding failed when known
+func decodeFailed(s *Strut, ctx context.Context, err error) {
+	decodeErr := decodeError(err)
+	if decodeErr.StatusCode == http.StatusBadRequest {
+		s.log.Error("error decoding request", "error", err)
+	}
+	createResponse(s, ctx, RespondError[any](decodeErr.StatusCode, decodeErr.Message))
+}
+
+// decodeError describes why a request body could not be read or decoded, as the client is told
+func decodeError(err error) *HTTPError {
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr
+	}
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return &HTTPError{StatusCode: http.StatusRequestEntityTooLarge, Message: "request body too large"}
+	}
+
+	message := "could not decode request: " + err.Error()
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxErr):
+		message = fmt.Sprintf("%s at offset %d", message, syntaxErr.Offset)
+	case errors.As(err, &typeErr):
+		message = fmt.Sprintf("%s at offset %d", message, typeErr.Offset)
+	}
+	return &HTTPError{StatusCode: http.StatusBadRequest, Message: message}
+}
+
+// violationsMessage joins the violations into the message of a 400 response
+func violationsMessage(violations []schema.ValidationError) string {
+	messages := make([]string, len(violations))
+	for i, v := range violations {
+		messages[i] = v.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+// checkRequest enforces the constraints of the operation that are checked before decoding the request.
+// If one is violated, an error has already been written to the client
+func checkRequest(op *swag.Operation, w http.ResponseWriter, r *http.Request) bool {
+	return limitRequest(op, w, r) && exclusiveParams(op, w, r)
+}
+
+// exclusiveParams rejects requests with more than one of a group of mutually exclusive query parameters,
+// if the operation validates requests
+func exclusiveParams(op *swag.Operation, w http.ResponseWriter, r *http.Request) bool {
+	if !op.Validate || len(op.ExclusiveParams) == 0 {
+		return true
+	}
+
+	query := r.URL.Query()
+	for _, group := range op.ExclusiveParams {
+		var given []string
+		for _, name := range group {
+			if query.Has(name) {
+				given = append(given, name)
+			}
+		}
+		if len(given) > 1 {
+			message := fmt.Sprintf("query parameters %s are mutually exclusive", strings.Join(given, ", "))
+			_ = RespondError[any](http.StatusBadRequest, message).Respond(w, r)
+			return false
+		}
+	}
+	return true
+}
+
+// limitRequest enforces the request body size limit of the operation for the content type of the request.
+// If the limit is exceeded, an error has already been written to the client
+func limitRequest(op *swag.Operation, w http.ResponseWriter, r *http.Request) bool {
+	if len(op.MaxRequestBytes) == 0 {
+		return true
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	limit, ok := op.MaxRequestBytes[mediaType]
+	if !ok {
+		limit, ok = op.MaxRequestBytes[""]
+	}
+	if !ok {
+		return true
+	}
+
+	if r.ContentLength > limit {
+		_ = RespondError[any](http.StatusRequestEntityTooLarge, "request body too large").Respond(w, r)
+		return false
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, limit)
+	return true
+}
+
 // HandlerOut Handler for a GET and DELETE request
 type HandlerOut[RES any] func(ctx context.Context) Response[RES]
 
@@ -203,33 +1423,23 @@ type HandlerInOut[REQ any, RES any] func(ctx context.Context, req REQ) Response[
 func Post[REQ any, RES any](s *Strut, path string, handler HandlerInOut[REQ, RES], ops ...OpConfig) {
 
 	op := assignOperation(ops...)
-	getPath(s.Definition, path).Post = op
-	assignRequest[REQ](s, op)
-	assignResponse[RES](s, op)
+	setOperation(s, path, http.MethodPost, op)
+	reqSchema := assignRequest[REQ](s, op)
+	resSchema := assignResponse[RES](s, op)
 
-	s.mux.With(s.middleware...).Post(path, func(w http.ResponseWriter, r *http.Request) {
-		var err error
-		ctx := decorateContext(r, w)
-		reader := r.Body
-		if r.Header.Get("Content-Encoding") == "gzip" {
-			reader, err = gzip.NewReader(r.Body)
-			if err != nil {
-				s.log.Error("error decoding gzip", "error", err)
-				http.Error(w, "could not decode request", http.StatusBadRequest)
-				return
-			}
+	s.handle(http.MethodPost, path, func(w http.ResponseWriter, r *http.Request) {
+		if !checkRequest(op, w, r) {
+			return
 		}
-
-		var req REQ
-		err = json.NewDecoder(reader).Decode(&req)
-		if err != nil {
-			s.log.Error("error decoding request", "error", err)
-			http.Error(w, "could not decode request", http.StatusBadRequest)
+		ctx := decorateContext(s, op, r, w)
+		defer removeForm(ctx)
+		req, ok := decodeRequest[REQ](s, ctx, op, reqSchema)
+		if !ok {
 			return
 		}
 
 		res := handler(ctx, req)
-		createResponse(s, ctx, res)
+		createResponse(s, ctx, operationResponse(s, op, resSchema, res))
 
 	})
 
@@ -238,14 +1448,17 @@ func Post[REQ any, RES any](s *Strut, path string, handler HandlerInOut[REQ, RES
 func Get[RES any](s *Strut, path string, handler HandlerOut[RES], ops ...OpConfig) {
 
 	op := assignOperation(ops...)
-	getPath(s.Definition, path).Get = op
-	assignResponse[RES](s, op)
+	setOperation(s, path, http.MethodGet, op)
+	resSchema := assignResponse[RES](s, op)
 
-	s.mux.With(s.middleware...).Get(path, func(w http.ResponseWriter, r *http.Request) {
-		ctx := decorateContext(r, w)
+	s.handle(http.MethodGet, path, func(w http.ResponseWriter, r *http.Request) {
+		if !checkRequest(op, w, r) {
+			return
+		}
+		ctx := decorateContext(s, op, r, w)
 
 		res := handler(ctx)
-		createResponse(s, ctx, res)
+		createResponse(s, ctx, operationResponse(s, op, resSchema, res))
 	})
 
 }
@@ -253,91 +1466,156 @@ func Get[RES any](s *Strut, path string, handler HandlerOut[RES], ops ...OpConfi
 func Put[REQ any, RES any](s *Strut, path string, handler HandlerInOut[REQ, RES], ops ...OpConfig) {
 
 	op := assignOperation(ops...)
-	getPath(s.Definition, path).Put = op
-	assignRequest[REQ](s, op)
-	assignResponse[RES](s, op)
+	setOperation(s, path, http.MethodPut, op)
+	reqSchema := assignRequest[REQ](s, op)
+	resSchema := assignResponse[RES](s, op)
 
-	s.mux.With(s.middleware...).Put(path, func(w http.ResponseWriter, r *http.Request) {
-		var err error
-		ctx := decorateContext(r, w)
-		reader := r.Body
-		if r.Header.Get("Content-Encoding") == "gzip" {
-			reader, err = gzip.NewReader(r.Body)
-			if err != nil {
-				s.log.Error("error decoding gzip", "error", err)
-				http.Error(w, "could not decode request", http.StatusBadRequest)
-				return
-			}
+	s.handle(http.MethodPut, path, func(w http.ResponseWriter, r *http.Request) {
+		if !checkRequest(op, w, r) {
+			return
 		}
-
-		var req REQ
-		err = json.NewDecoder(reader).Decode(&req)
-		if err != nil {
-
-			s.log.Error("error decoding request", "error", err)
-			http.Error(w, "could not decode request", http.StatusBadRequest)
+		ctx := decorateContext(s, op, r, w)
+		defer removeForm(ctx)
+		req, ok := decodeRequest[REQ](s, ctx, op, reqSchema)
+		if !ok {
 			return
 		}
 
 		res := handler(ctx, req)
-		createResponse(s, ctx, res)
+		createResponse(s, ctx, operationResponse(s, op, resSchema, res))
 	})
 }
 
 func Delete[RES any](s *Strut, path string, handler HandlerOut[RES], ops ...OpConfig) {
 	op := assignOperation(ops...)
-	getPath(s.Definition, path).Delete = op
-	assignResponse[RES](s, op)
+	setOperation(s, path, http.MethodDelete, op)
+	resSchema := assignResponse[RES](s, op)
 
-	s.mux.With(s.middleware...).Delete(path, func(w http.ResponseWriter, r *http.Request) {
-		ctx := decorateContext(r, w)
+	s.handle(http.MethodDelete, path, func(w http.ResponseWriter, r *http.Request) {
+		if !checkRequest(op, w, r) {
+			return
+		}
+		ctx := decorateContext(s, op, r, w)
 
 		res := handler(ctx)
-		createResponse(s, ctx, res)
+		createResponse(s, ctx, operationResponse(s, op, resSchema, res))
 	})
 }
 
+// HandlerOutE Handler for a GET and DELETE request, returning the response body and an error
+type HandlerOutE[RES any] func(ctx context.Context) (RES, error)
+
+// HandlerInOutE Handler for a POST and PUT request, returning the response body and an error
+type HandlerInOutE[REQ any, RES any] func(ctx context.Context, req REQ) (RES, error)
+
+// PostE registers a POST handler returning (RES, error), see HTTPError for how errors are responded
+func PostE[REQ any, RES any](s *Strut, path string, handler HandlerInOutE[REQ, RES], ops ...OpConfig) {
+	Post(s, path, func(ctx context.Context, req REQ) Response[RES] {
+		res, err := handler(ctx, req)
+		return respondE(s, ctx, res, err)
+	}, ops...)
+}
+
+// GetE registers a GET handler returning (RES, error), see HTTPError for how errors are responded
+func GetE[RES any](s *Strut, path string, handler HandlerOutE[RES], ops ...OpConfig) {
+	Get(s, path, func(ctx context.Context) Response[RES] {
+		res, err := handler(ctx)
+		return respondE(s, ctx, res, err)
+	}, ops...)
+}
+
+// PutE registers a PUT handler returning (RES, error), see HTTPError for how errors are responded
+func PutE[REQ any, RES any](s *Strut, path string, handler HandlerInOutE[REQ, RES], ops ...OpConfig) {
+	Put(s, path, func(ctx context.Context, req REQ) Response[RES] {
+		res, err := handler(ctx, req)
+		return respondE(s, ctx, res, err)
+	}, ops...)
+}
+
+// DeleteE registers a DELETE handler returning (RES, error), see HTTPError for how errors are responded
+func DeleteE[RES any](s *Strut, path string, handler HandlerOutE[RES], ops ...OpConfig) {
+	Delete(s, path, func(ctx context.Context) Response[RES] {
+		res, err := handler(ctx)
+		return respondE(s, ctx, res, err)
+	}, ops...)
+}
+
 func RawPost[REQ any, RES any](s *Strut, path string, handler http.HandlerFunc, ops ...OpConfig) {
 	op := assignOperation(ops...)
-	getPath(s.Definition, path).Post = op
+	setOperation(s, path, http.MethodPost, op)
 	assignRequest[REQ](s, op)
 	assignResponse[RES](s, op)
 
-	s.mux.With(s.middleware...).Post(path, func(w http.ResponseWriter, r *http.Request) {
-		r = r.WithContext(decorateContext(r, w))
+	s.handle(http.MethodPost, path, func(w http.ResponseWriter, r *http.Request) {
+		if !checkRequest(op, w, r) {
+			return
+		}
+		r = r.WithContext(decorateContext(s, op, r, w))
 		handler(w, r)
 	})
 }
 func RawPut[REQ any, RES any](s *Strut, path string, handler http.HandlerFunc, ops ...OpConfig) {
 	op := assignOperation(ops...)
-	getPath(s.Definition, path).Put = op
+	setOperation(s, path, http.MethodPut, op)
 	assignRequest[REQ](s, op)
 	assignResponse[RES](s, op)
 
-	s.mux.With(s.middleware...).Put(path, func(w http.ResponseWriter, r *http.Request) {
-		r = r.WithContext(decorateContext(r, w))
+	s.handle(http.MethodPut, path, func(w http.ResponseWriter, r *http.Request) {
+		if !checkRequest(op, w, r) {
+			return
+		}
+		r = r.WithContext(decorateContext(s, op, r, w))
 		handler(w, r)
 	})
 }
 func RawGet[RES any](s *Strut, path string, handler http.HandlerFunc, ops ...OpConfig) {
 	op := assignOperation(ops...)
-	getPath(s.Definition, path).Get = op
+	setOperation(s, path, http.MethodGet, op)
 	assignResponse[RES](s, op)
 
-	s.mux.With(s.middleware...).Get(path, func(w http.ResponseWriter, r *http.Request) {
-		r = r.WithContext(decorateContext(r, w))
+	s.handle(http.MethodGet, path, func(w http.ResponseWriter, r *http.Request) {
+		if !checkRequest(op, w, r) {
+			return
+		}
+		r = r.WithContext(decorateContext(s, op, r, w))
 		handler(w, r)
 
 	})
 }
 func RawDelete[RES any](s *Strut, path string, handler http.HandlerFunc, ops ...OpConfig) {
 	op := assignOperation(ops...)
-	getPath(s.Definition, path).Delete = op
+	setOperation(s, path, http.MethodDelete, op)
 	assignResponse[RES](s, op)
 
-	s.mux.With(s.middleware...).Delete(path, func(w http.ResponseWriter, r *http.Request) {
-		r = r.WithContext(decorateContext(r, w))
+	s.handle(http.MethodDelete, path, func(w http.ResponseWriter, r *http.Request) {
+		if !checkRequest(op, w, r) {
+			return
+		}
+		r = r.WithContext(decorateContext(s, op, r, w))
 		handler(w, r)
 
 	})
 }
+
+// Options registers an OPTIONS handler on path, answering 204 with an Allow header listing the methods
+// registered for the path in the spec. The methods are looked up per request, so Options may be
+// registered before the endpoints of the path
+func Options(s *Strut, path string) {
+	s.handle(http.MethodOptions, path, func(w http.ResponseWriter, r *http.Request) {
+		specMu.RLock()
+		methods := allowedMethods(s.Definition.Paths[joinPath(s.prefix, path)])
+		specMu.RUnlock()
+		w.Header().Set("Allow", strings.Join(methods, ", "))
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+func allowedMethods(p *swag.Path) []string {
+	var methods []string
+	if p != nil {
+		for method := range p.All() {
+			methods = append(methods, method)
+		}
+	}
+	return append(methods, http.MethodOptions)
+}
@@ -0,0 +1,248 @@
+package swag
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/modfin/strut/schema"
+)
+
+// Changelog renders the changes from old to new as markdown, e.g. for release notes: endpoints added,
+// removed and changed, by their parameters, responses and deprecation, and the fields of component
+// schemas added, removed, retyped or made required, in request bodies as well
+func Changelog(old, new *Definition) string {
+	var added, removed, changed []string
+
+	oldOps, newOps := endpoints(old), endpoints(new)
+	for _, name := range slices.Sorted(maps.Keys(newOps)) {
+		op := newOps[name]
+		prev, ok := oldOps[name]
+		if !ok {
+			added = append(added, entry(name, op.Summary))
+			continue
+		}
+		for _, change := range operationChanges(old, new, prev, op) {
+			changed = append(changed, entry(name, change))
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(oldOps)) {
+		if _, ok := newOps[name]; !ok {
+			removed = append(removed, entry(name, ""))
+		}
+	}
+
+	oldSchemas, newSchemas := componentSchemas(old), componentSchemas(new)
+	for _, name := range slices.Sorted(maps.Keys(newSchemas)) {
+		prev, ok := oldSchemas[name]
+		if !ok {
+			added = append(added, entry("schema "+name, ""))
+			continue
+		}
+		for _, change := range schemaChanges(prev, newSchemas[name], "") {
+			changed = append(changed, entry("schema "+name, change))
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(oldSchemas)) {
+		if _, ok := newSchemas[name]; !ok {
+			removed = append(removed, entry("schema "+name, ""))
+		}
+	}
+
+	var b strings.Builder
+	for _, section := range []struct {
+		title   string
+		entries []string
+	}{{"Added", added}, {"Removed", removed}, {"Changed", changed}} {
+		if len(section.entries) == 0 {
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "## %s\n\n", section.title)
+		for _, e := range section.entries {
+			fmt.Fprintf(&b, "- %s\n", e)
+		}
+	}
+	if b.Len() == 0 {
+		return "No changes\n"
+	}
+	return b.String()
+}
+
+func entry(name string, detail string) string {
+	if detail == "" {
+		return "`" + name + "`"
+	}
+	return "`" + name + "`: " + detail
+}
+
+// endpoints returns the operations of d keyed by method and path, e.g. "GET /orders"
+func endpoints(d *Definition) map[string]*Operation {
+	ops := map[string]*Operation{}
+	if d == nil {
+		return ops
+	}
+	for path, item := range d.Paths {
+		for method, op := range item.All() {
+			ops[method+" "+path] = op
+		}
+	}
+	return ops
+}
+
+func componentSchemas(d *Definition) map[string]*schema.JSON {
+	if d == nil || d.Components == nil {
+		return nil
+	}
+	return d.Components.Schemas
+}
+
+// operationChanges compares the operations, oldDef and newDef resolving the component schemas of their request bodies
+func operationChanges(oldDef, newDef *Definition, old, new *Operation) []string {
+	var changes []string
+	if new.Deprecated && !old.Deprecated {
+		changes = append(changes, "deprecated")
+	}
+
+	paramName := func(p Param) string {
+		if p.Ref != "" {
+			return p.Ref
+		}
+		return p.In + " parameter " + p.Name
+	}
+	oldParams, newParams := map[string]bool{}, map[string]bool{}
+	for _, p := range old.Parameters {
+		oldParams[paramName(p)] = true
+	}
+	for _, p := range new.Parameters {
+		newParams[paramName(p)] = true
+	}
+	changes = append(changes, setChanges(oldParams, newParams, "`%s` added", "`%s` removed")...)
+
+	oldStatuses, newStatuses := map[string]bool{}, map[string]bool{}
+	for status := range old.Responses {
+		oldStatuses[status] = true
+	}
+	for status := range new.Responses {
+		newStatuses[status] = true
+	}
+	changes = append(changes, setChanges(oldStatuses, newStatuses, "response %s added", "response %s removed")...)
+	return append(changes, requestBodyChanges(oldDef, newDef, old.RequestBody, new.RequestBody)...)
+}
+
+// requestBodyChanges compares the schemas of the request bodies per media type, those referring to components
+// by the fields of the components, e.g. for a required field added to the request of an endpoint
+func requestBodyChanges(oldDef, newDef *Definition, old, new *RequestBody) []string {
+	switch {
+	case old == nil && new == nil:
+		return nil
+	case old == nil:
+		return []string{"request body added"}
+	case new == nil:
+		return []string{"request body removed"}
+	}
+
+	var changes []string
+	for _, contentType := range slices.Sorted(maps.Keys(new.Content)) {
+		prev, ok := old.Content[contentType]
+		if !ok {
+			changes = append(changes, fmt.Sprintf("request body %s added", contentType))
+			continue
+		}
+		oldSchema, newSchema := resolveSchema(oldDef, prev.Schema), resolveSchema(newDef, new.Content[contentType].Schema)
+		for _, change := range schemaChanges(oldSchema, newSchema, "") {
+			changes = append(changes, "request body "+change)
+		}
+	}
+	for _, contentType := range slices.Sorted(maps.Keys(old.Content)) {
+		if _, ok := new.Content[contentType]; !ok {
+			changes = append(changes, fmt.Sprintf("request body %s removed", contentType))
+		}
+	}
+	return changes
+}
+
+// resolveSchema returns the component schema s refers to, or s itself if it isn't a reference to one of d
+func resolveSchema(d *Definition, s *schema.JSON) *schema.JSON {
+	if s == nil {
+		return nil
+	}
+	name, ok := strings.CutPrefix(s.Ref, "#/components/schemas/")
+	if !ok {
+		return s
+	}
+	if resolved := componentSchemas(d)[name]; resolved != nil {
+		return resolved
+	}
+	return s
+}
+
+func setChanges(old, new map[string]bool, addedFormat, removedFormat string) []string {
+	var changes []string
+	for _, name := range slices.Sorted(maps.Keys(new)) {
+		if !old[name] {
+			changes = append(changes, fmt.Sprintf(addedFormat, name))
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(old)) {
+		if !new[name] {
+			changes = append(changes, fmt.Sprintf(removedFormat, name))
+		}
+	}
+	return changes
+}
+
+// schemaChanges compares the fields of the schemas, and those of the objects within them, path naming the field
+func schemaChanges(old, new *schema.JSON, path string) []string {
+	if old == nil || new == nil {
+		return nil
+	}
+	if old.Type != new.Type || old.Ref != new.Ref {
+		if path == "" {
+			return []string{"type changed"}
+		}
+		return []string{fmt.Sprintf("field `%s` changed type", path)}
+	}
+	if new.Type == schema.Array {
+		return schemaChanges(old.Items, new.Items, path+"[]")
+	}
+
+	var changes []string
+	field := func(name string) string {
+		if path == "" {
+			return name
+		}
+		return path + "." + name
+	}
+	for _, name := range slices.Sorted(maps.Keys(new.Properties)) {
+		prev, ok := old.Properties[name]
+		if !ok && slices.Contains(new.Required, name) {
+			changes = append(changes, fmt.Sprintf("required field `%s` added", field(name)))
+			continue
+		}
+		if !ok {
+			changes = append(changes, fmt.Sprintf("field `%s` added", field(name)))
+			continue
+		}
+		changes = append(changes, schemaChanges(prev, new.Properties[name], field(name))...)
+	}
+	for _, name := range slices.Sorted(maps.Keys(old.Properties)) {
+		if _, ok := new.Properties[name]; !ok {
+			changes = append(changes, fmt.Sprintf("field `%s` removed", field(name)))
+		}
+	}
+	for _, name := range new.Required {
+		if _, existed := old.Properties[name]; existed && !slices.Contains(old.Required, name) {
+			changes = append(changes, fmt.Sprintf("field `%s` is now required", field(name)))
+		}
+	}
+	for _, name := range old.Required {
+		if _, exists := new.Properties[name]; exists && !slices.Contains(new.Required, name) {
+			changes = append(changes, fmt.Sprintf("field `%s` is no longer required", field(name)))
+		}
+	}
+	return changes
+}
@@ -0,0 +1,50 @@
+package swag
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/modfin/strut/schema"
+)
+
+// jsonSchemaDialect is the JSON Schema version of the documents of ComponentsAsJSONSchema
+const jsonSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// ComponentsAsJSONSchema encodes the component schemas of d as a single JSON Schema 2020-12 document,
+// with the components under $defs and their references pointing there, e.g. for validation outside OpenAPI.
+// d itself is left untouched
+func ComponentsAsJSONSchema(d *Definition) ([]byte, error) {
+	defs := map[string]*schema.JSON{}
+	if d.Components != nil {
+		for name, s := range d.Components.Schemas {
+			def := s.Clone()
+			schema.SetDialect(def, schema.JSONSchema)
+			rewriteRefs(def)
+			defs[name] = def
+		}
+	}
+
+	return json.Marshal(struct {
+		Schema string                  `json:"$schema"`
+		Defs   map[string]*schema.JSON `json:"$defs"`
+	}{jsonSchemaDialect, defs})
+}
+
+// rewriteRefs points the references to components of s, and every schema within it, to $defs
+func rewriteRefs(s *schema.JSON) {
+	schema.Walk(s, func(s *schema.JSON) {
+		s.Ref = defsRef(s.Ref)
+		if s.Discriminator != nil {
+			for value, ref := range s.Discriminator.Mapping {
+				s.Discriminator.Mapping[value] = defsRef(ref)
+			}
+		}
+	})
+}
+
+func defsRef(ref string) string {
+	if name, ok := strings.CutPrefix(ref, "#/components/schemas/"); ok {
+		return "#/$defs/" + name
+	}
+	return ref
+}
@@ -1,26 +1,72 @@
 package swag
 
-import "github.com/modfin/strut/schema"
+import (
+	"fmt"
+	"iter"
+	"net/http"
+	"time"
+
+	"github.com/modfin/strut/schema"
+)
 
 type Definition struct {
 	OpenAPI    string           `json:"openapi,omitempty" yaml:"openapi,omitempty"`
 	Info       Info             `json:"info,omitempty" yaml:"info,omitempty"`
 	Paths      map[string]*Path `json:"paths,omitempty" yaml:"paths,omitempty"`
+	Webhooks   map[string]*Path `json:"webhooks,omitempty" yaml:"webhooks,omitempty"` // OpenAPI 3.1, keyed by webhook name
 	Components *Components      `json:"components,omitempty" yaml:"components,omitempty"`
 	Servers    []Server         `json:"servers,omitempty" yaml:"servers,omitempty"`
+	Tags       []Tag            `json:"tags,omitempty" yaml:"tags,omitempty"`
+
+	// Security is required by every operation that doesn't declare its own, any of the requirements satisfying it
+	Security []SecurityRequirement `json:"security,omitempty" yaml:"security,omitempty"`
+
+	ExternalDocs *ExternalDocs `json:"externalDocs,omitempty" yaml:"externalDocs,omitempty"`
 }
 
-type Info struct {
-	Title       string `json:"title,omitempty" yaml:"title,omitempty"`
+// Tag describes a tag operations are grouped by
+type Tag struct {
+	Name         string        `json:"name" yaml:"name"`
+	Description  string        `json:"description,omitempty" yaml:"description,omitempty"`
+	ExternalDocs *ExternalDocs `json:"externalDocs,omitempty" yaml:"externalDocs,omitempty"`
+}
+
+// ExternalDocs points to documentation outside the spec
+type ExternalDocs struct {
 	Description string `json:"description,omitempty" yaml:"description,omitempty"`
-	Version     string `json:"version,omitempty" yaml:"version,omitempty"`
+	URL         string `json:"url" yaml:"url"`
+}
+
+type Info struct {
+	Title          string   `json:"title,omitempty" yaml:"title,omitempty"`
+	Description    string   `json:"description,omitempty" yaml:"description,omitempty"`
+	TermsOfService string   `json:"termsOfService,omitempty" yaml:"termsOfService,omitempty"`
+	Contact        *Contact `json:"contact,omitempty" yaml:"contact,omitempty"`
+	License        *License `json:"license,omitempty" yaml:"license,omitempty"`
+	Version        string   `json:"version,omitempty" yaml:"version,omitempty"`
+}
+
+// Contact is who to contact about the API
+type Contact struct {
+	Name  string `json:"name,omitempty" yaml:"name,omitempty"`
+	URL   string `json:"url,omitempty" yaml:"url,omitempty"`
+	Email string `json:"email,omitempty" yaml:"email,omitempty"`
+}
+
+// License is the license the API is offered under, Identifier being an SPDX expression (OpenAPI 3.1)
+type License struct {
+	Name       string `json:"name" yaml:"name"`
+	URL        string `json:"url,omitempty" yaml:"url,omitempty"`
+	Identifier string `json:"identifier,omitempty" yaml:"identifier,omitempty"`
 }
 
 type Path struct {
 	Summary     string `json:"summary,omitempty" yaml:"summary,omitempty"`
 	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+	Deprecated  bool   `json:"x-deprecated,omitempty" yaml:"x-deprecated,omitempty"` // set when building the spec if every operation is deprecated
 
-	Parameters []Param `json:"parameters,omitempty" yaml:"parameters,omitempty"`
+	Parameters []Param  `json:"parameters,omitempty" yaml:"parameters,omitempty"`
+	Servers    []Server `json:"servers,omitempty" yaml:"servers,omitempty"`
 
 	Post *Operation `json:"post,omitempty" yaml:"post,omitempty"`
 	Get  *Operation `json:"get,omitempty" yaml:"get,omitempty"`
@@ -41,10 +87,88 @@ type Operation struct {
 	RequestBody *RequestBody           `json:"requestBody,omitempty" yaml:"requestBody,omitempty"`
 	Responses   map[string]*OpResponse `json:"responses,omitempty" yaml:"responses,omitempty"`
 	Deprecated  bool                   `json:"deprecated,omitempty" yaml:"deprecated,omitempty"`
+
+	// Security overrides that of the Definition if not nil, no security being required if empty
+	Security *[]SecurityRequirement `json:"security,omitempty" yaml:"security,omitempty"`
+	Servers  []Server               `json:"servers,omitempty" yaml:"servers,omitempty"` // override those of the path and the Definition
+
+	ExternalDocs *ExternalDocs `json:"externalDocs,omitempty" yaml:"externalDocs,omitempty"`
+	CodeSamples  []CodeSample  `json:"x-codeSamples,omitempty" yaml:"x-codeSamples,omitempty"`
+	Audiences    []string      `json:"x-audience,omitempty" yaml:"x-audience,omitempty"` // who the operation is for, e.g. public or internal, any audience if empty
+
+	// ExclusiveParams are groups of query parameters of which at most one may be given, enforced when validating
+	ExclusiveParams [][]string `json:"x-exclusive-params,omitempty" yaml:"x-exclusive-params,omitempty"`
+
+	// Runtime behaviour, not part of the spec
+	Validate       bool `json:"-" yaml:"-"` // validate request bodies against the request schema before calling the handler
+	StripWriteOnly bool `json:"-" yaml:"-"` // remove writeOnly fields from response bodies
+	ApplyDefaults  bool `json:"-" yaml:"-"` // set fields absent from request bodies to the default of their schema
+
+	MaxRequestBytes    map[string]int64 `json:"-" yaml:"-"` // request body size limit per media type, "" applying to any media type
+	RequestContentType string           `json:"-" yaml:"-"` // media type of request bodies, if not the default of the API
+	SuccessCode        int              `json:"-" yaml:"-"` // status the response body is documented under and E handlers respond, 200 if 0
+	CacheMaxAge        time.Duration    `json:"-" yaml:"-"` // max-age of the Cache-Control header of successful GET responses
+
+	ResponseContentTypes []string `json:"-" yaml:"-"` // media types of response bodies, the default of the API if empty
+	Statuses             []int    `json:"-" yaml:"-"` // responded besides the success code, documented when registered
+
+	FileLimits map[string]FileLimit `json:"-" yaml:"-"` // per field of multipart forms
+}
+
+// Operations returns the operations of the path, leaving out the methods without one
+func (p *Path) Operations() []*Operation {
+	var ops []*Operation
+	for _, op := range p.All() {
+		ops = append(ops, op)
+	}
+	return ops
+}
+
+// All iterates the operations of the path by their method, in the order GET, POST, PUT and DELETE, leaving out
+// the methods without one
+func (p *Path) All() iter.Seq2[string, *Operation] {
+	return func(yield func(string, *Operation) bool) {
+		for _, method := range []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete} {
+			if op := *p.slot(method); op != nil && !yield(method, op) {
+				return
+			}
+		}
+	}
+}
+
+// SetOperation sets the operation of the method, returning the one it replaces if any. It panics for methods
+// a path has no operation of, e.g. PATCH
+func (p *Path) SetOperation(method string, op *Operation) *Operation {
+	slot := p.slot(method)
+	replaced := *slot
+	*slot = op
+	return replaced
+}
+
+func (p *Path) slot(method string) **Operation {
+	switch method {
+	case http.MethodGet:
+		return &p.Get
+	case http.MethodPost:
+		return &p.Post
+	case http.MethodPut:
+		return &p.Put
+	case http.MethodDelete:
+		return &p.Delete
+	}
+	panic(fmt.Sprintf("swag: unsupported method %s", method))
+}
+
+// CodeSample is an example of calling an operation, rendered by e.g. ReDoc from the x-codeSamples extension
+type CodeSample struct {
+	Lang   string `json:"lang" yaml:"lang"`
+	Label  string `json:"label,omitempty" yaml:"label,omitempty"`
+	Source string `json:"source" yaml:"source"`
 }
 
 // Param represents a parameter for an operation
 type Param struct {
+	Ref             string       `json:"$ref,omitempty" yaml:"$ref,omitempty"` // a parameter of the components, leaving the rest empty
 	Name            string       `json:"name,omitempty" yaml:"name,omitempty"`
 	In              string       `json:"in,omitempty" yaml:"in,omitempty"` // e.g., "query", "header", "path", "cookie"
 	Description     string       `json:"description,omitempty" yaml:"description,omitempty"`
@@ -63,9 +187,10 @@ type RequestBody struct {
 
 // OpResponse represents a response from an API operation
 type OpResponse struct {
+	Ref         string               `json:"$ref,omitempty" yaml:"$ref,omitempty"` // a response of the components, leaving the rest empty
 	Description string               `json:"description,omitempty" yaml:"description,omitempty"`
 	Content     map[string]MediaType `json:"content,omitempty" yaml:"content,omitempty"`
-	//Headers     map[string]Header    `json:"headers,omitempty" yaml:"headers,omitempty"`
+	Headers     map[string]Header    `json:"headers,omitempty" yaml:"headers,omitempty"`
 	//Links       map[string]Link      `json:"links,omitempty" yaml:"links,omitempty"`
 }
 
@@ -117,6 +242,13 @@ type Encoding struct {
 	Style         string            `json:"style,omitempty" yaml:"style,omitempty"`
 	Explode       bool              `json:"explode,omitempty" yaml:"explode,omitempty"`
 	AllowReserved bool              `json:"allowReserved,omitempty" yaml:"allowReserved,omitempty"`
+	MaxSize       int64             `json:"x-max-size,omitempty" yaml:"x-max-size,omitempty"` // bytes, of files uploaded as the property
+}
+
+// FileLimit restricts the files uploaded as a field of multipart forms, empty values not restricting anything
+type FileLimit struct {
+	MaxBytes     int64
+	ContentTypes []string // e.g. image/png or image/*
 }
 
 // Link represents a link object
@@ -129,14 +261,43 @@ type Link struct {
 	//Server       *Server                `json:"server,omitempty" yaml:"server,omitempty"`
 }
 
-// SecurityRequirement represents a security requirement
+// SecurityRequirement represents a security requirement, the scopes required of each security scheme by name,
+// all of which are to be satisfied
 type SecurityRequirement map[string][]string
 
+// SecurityScheme describes how requests are authenticated, e.g. Type http with Scheme bearer
+type SecurityScheme struct {
+	Type             string      `json:"type" yaml:"type"` // apiKey, http, oauth2 or openIdConnect
+	Description      string      `json:"description,omitempty" yaml:"description,omitempty"`
+	Name             string      `json:"name,omitempty" yaml:"name,omitempty"` // of the header, query parameter or cookie of apiKey
+	In               string      `json:"in,omitempty" yaml:"in,omitempty"`     // header, query or cookie of apiKey
+	Scheme           string      `json:"scheme,omitempty" yaml:"scheme,omitempty"`
+	BearerFormat     string      `json:"bearerFormat,omitempty" yaml:"bearerFormat,omitempty"`
+	Flows            *OAuthFlows `json:"flows,omitempty" yaml:"flows,omitempty"`
+	OpenIDConnectURL string      `json:"openIdConnectUrl,omitempty" yaml:"openIdConnectUrl,omitempty"`
+}
+
+// OAuthFlows are the flows of an oauth2 security scheme
+type OAuthFlows struct {
+	Implicit          *OAuthFlow `json:"implicit,omitempty" yaml:"implicit,omitempty"`
+	Password          *OAuthFlow `json:"password,omitempty" yaml:"password,omitempty"`
+	ClientCredentials *OAuthFlow `json:"clientCredentials,omitempty" yaml:"clientCredentials,omitempty"`
+	AuthorizationCode *OAuthFlow `json:"authorizationCode,omitempty" yaml:"authorizationCode,omitempty"`
+}
+
+// OAuthFlow describes an oauth2 flow, Scopes being the descriptions of the scopes by name
+type OAuthFlow struct {
+	AuthorizationURL string            `json:"authorizationUrl,omitempty" yaml:"authorizationUrl,omitempty"`
+	TokenURL         string            `json:"tokenUrl,omitempty" yaml:"tokenUrl,omitempty"`
+	RefreshURL       string            `json:"refreshUrl,omitempty" yaml:"refreshUrl,omitempty"`
+	Scopes           map[string]string `json:"scopes" yaml:"scopes"`
+}
+
 type Components struct {
-	//SecuritySchemes map[string]SecurityScheme `json:"securitySchemes" yaml:"securitySchemes"`
-	Schemas map[string]*schema.JSON `json:"schemas,omitempty" yaml:"schemas,omitempty"`
-	//Responses  map[string]OpResponse     `json:"responses" yaml:"responses"`
-	//Parameters map[string]Param        `json:"parameters" yaml:"parameters"`
+	Schemas         map[string]*schema.JSON    `json:"schemas,omitempty" yaml:"schemas,omitempty"`
+	Responses       map[string]*OpResponse     `json:"responses,omitempty" yaml:"responses,omitempty"`
+	Parameters      map[string]*Param          `json:"parameters,omitempty" yaml:"parameters,omitempty"`
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes,omitempty" yaml:"securitySchemes,omitempty"`
 	//Examples   map[string]Example      `json:"examples" yaml:"examples"`
 }
 
@@ -151,3 +312,63 @@ type ServerVariable struct {
 	Default     string   `json:"default" yaml:"default"`
 	Description string   `json:"description,omitempty" yaml:"description,omitempty"`
 }
+
+// EachSchema calls fn with every top level schema of the definition, i.e. components and
+// the schemas of parameters, request bodies and responses
+func (d *Definition) EachSchema(fn func(s *schema.JSON)) {
+	if d.Components != nil {
+		for _, s := range d.Components.Schemas {
+			fn(s)
+		}
+		for _, res := range d.Components.Responses {
+			eachResponseSchema(res, fn)
+		}
+		for _, param := range d.Components.Parameters {
+			fn(param.Schema)
+		}
+	}
+	for _, path := range d.Paths {
+		eachPathSchema(path, fn)
+	}
+	for _, path := range d.Webhooks {
+		eachPathSchema(path, fn)
+	}
+}
+
+func eachPathSchema(path *Path, fn func(s *schema.JSON)) {
+	for _, param := range path.Parameters {
+		fn(param.Schema)
+	}
+	for _, op := range path.Operations() {
+		for _, param := range op.Parameters {
+			fn(param.Schema)
+		}
+		if op.RequestBody != nil {
+			eachContentSchema(op.RequestBody.Content, fn)
+		}
+		for _, res := range op.Responses {
+			eachResponseSchema(res, fn)
+		}
+	}
+}
+
+func eachResponseSchema(res *OpResponse, fn func(s *schema.JSON)) {
+	if res == nil {
+		return
+	}
+	eachContentSchema(res.Content, fn)
+	for _, header := range res.Headers {
+		fn(header.Schema)
+	}
+}
+
+func eachContentSchema(content map[string]MediaType, fn func(s *schema.JSON)) {
+	for _, mediaType := range content {
+		fn(mediaType.Schema)
+		for _, encoding := range mediaType.Encoding {
+			for _, header := range encoding.Headers {
+				fn(header.Schema)
+			}
+		}
+	}
+}
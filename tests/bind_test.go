@@ -0,0 +1,216 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/modfin/strut"
+	"github.com/modfin/strut/schema"
+	"github.com/modfin/strut/swag"
+	"github.com/modfin/strut/with"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type ListOrdersParams struct {
+	CustomerID int      `path:"customer_id"`
+	Page       int      `query:"page" json-minimum:"1"`
+	Status     []string `query:"status"`
+	Tenant     string   `header:"X-Tenant,required"`
+	Session    *string  `cookie:"session"`
+}
+
+type ListedOrders struct {
+	CustomerID int      `json:"customer_id"`
+	Page       int      `json:"page"`
+	Status     []string `json:"status"`
+	Tenant     string   `json:"tenant"`
+	Session    string   `json:"session"`
+}
+
+// TestBindParams tests that parameters are bound to the fields of a struct, converted and validated
+func TestBindParams(t *testing.T) {
+	r := chi.NewRouter()
+	s := strut.New(slog.Default(), r)
+
+	strut.GetE(s, "/customers/{customer_id}/orders", func(ctx context.Context) (ListedOrders, error) {
+		params, err := strut.BindParams[ListOrdersParams](ctx)
+		if err != nil {
+			return ListedOrders{}, err
+		}
+		res := ListedOrders{CustomerID: params.CustomerID, Page: params.Page, Status: params.Status, Tenant: params.Tenant}
+		if params.Session != nil {
+			res.Session = *params.Session
+		}
+		return res, nil
+	},
+		with.OperationId("list-orders"),
+		with.PathParam[int]("customer_id", "The customer"),
+		with.QueryParam[int]("page", "The page"),
+		with.QueryParam[[]string]("status", "Statuses of the orders"),
+		with.HeaderParam[string]("X-Tenant", "The tenant"),
+		with.CookieParam[string]("session", "The session"),
+	)
+
+	get := func(target string, tenant string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		if tenant != "" {
+			req.Header.Set("X-Tenant", tenant)
+		}
+		req.AddCookie(&http.Cookie{Name: "session", Value: "abc"})
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("binds parameters", func(t *testing.T) {
+		w := get("/customers/42/orders?page=2&status=placed&status=shipped", "acme")
+
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var result ListedOrders
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&result))
+		assert.Equal(t, ListedOrders{CustomerID: 42, Page: 2, Status: []string{"placed", "shipped"}, Tenant: "acme", Session: "abc"}, result)
+	})
+
+	t.Run("leaves optional parameters untouched", func(t *testing.T) {
+		w := get("/customers/42/orders", "acme")
+
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var result ListedOrders
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&result))
+		assert.Equal(t, 0, result.Page)
+		assert.Nil(t, result.Status)
+	})
+
+	t.Run("rejects malformed parameters", func(t *testing.T) {
+		w := get("/customers/42/orders?page=two", "acme")
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "query parameter page")
+	})
+
+	t.Run("rejects invalid and missing parameters", func(t *testing.T) {
+		w := get("/customers/42/orders?page=0", "")
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "page: minimum 1")
+		assert.Contains(t, w.Body.String(), "X-Tenant: required")
+	})
+}
+
+type PageParams struct {
+	Limit int `query:"limit"`
+	Page  int `query:"page"`
+}
+
+// TestBindParams_Defaults tests that the documented defaults of missing parameters are applied at runtime
+func TestBindParams_Defaults(t *testing.T) {
+	r := chi.NewRouter()
+	s := strut.New(slog.Default(), r).
+		AddParam("Page", swag.Param{Name: "page", In: "query", Schema: &schema.JSON{Type: schema.Integer, Default: 1}})
+
+	strut.GetE(s, "/orders", func(ctx context.Context) ([]int, error) {
+		params, err := strut.BindParams[PageParams](ctx)
+		if err != nil {
+			return nil, err
+		}
+		return []int{params.Limit, params.Page}, nil
+	},
+		with.OperationId("list-orders"),
+		with.QueryParamDefault("limit", "Page size", 20),
+		with.ParamRef("Page"),
+		with.ResponseDescription(http.StatusOK, "The limit and page"),
+	)
+	strut.Get(s, "/products", func(ctx context.Context) strut.Response[string] {
+		return strut.RespondOk(strut.QueryParam(ctx, "limit"))
+	},
+		with.OperationId("list-products"),
+		with.QueryParamDefault("limit", "Page size", 50),
+		with.ResponseDescription(http.StatusOK, "The limit"),
+	)
+
+	get := func(target string) string {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		return strings.TrimSpace(w.Body.String())
+	}
+
+	assert.Equal(t, "[20,1]", get("/orders"))
+	assert.Equal(t, "[5,3]", get("/orders?limit=5&page=3"))
+	assert.Equal(t, `"50"`, get("/products"))
+	assert.Equal(t, `"5"`, get("/products?limit=5"))
+
+	doc := loadSpec(t, s)
+	assert.EqualValues(t, 20, doc.Paths.Find("/orders").Get.Parameters.GetByInAndName("query", "limit").Schema.Value.Default)
+}
+
+type SinceParams struct {
+	Since  time.Time  `query:"since"`
+	Before *time.Time `query:"before"`
+	Region RegionCode `query:"region"`
+}
+
+type RegionCode struct {
+	Name string
+}
+
+func (k *RegionCode) UnmarshalText(text []byte) error {
+	k.Name = strings.ToUpper(string(text))
+	return nil
+}
+
+type UnbindableParams struct {
+	Filter struct{ Name string } `query:"filter"`
+}
+
+// TestBindParams_TextUnmarshaler tests that time.Time and other encoding.TextUnmarshaler fields are bound, and that
+// fields of types that can't be bound fail as programming errors rather than as bad requests
+func TestBindParams_TextUnmarshaler(t *testing.T) {
+	r := chi.NewRouter()
+	s := strut.New(slog.Default(), r)
+
+	var bound SinceParams
+	var bindErr error
+	strut.GetE(s, "/orders", func(ctx context.Context) (string, error) {
+		bound, bindErr = strut.BindParams[SinceParams](ctx)
+		return "", bindErr
+	}, with.OperationId("list-orders"))
+	strut.GetE(s, "/products", func(ctx context.Context) (string, error) {
+		_, bindErr = strut.BindParams[UnbindableParams](ctx)
+		return "", bindErr
+	}, with.OperationId("list-products"))
+
+	get := func(target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		return w
+	}
+
+	w := get("/orders?since=2024-05-01T10:00:00Z&before=2024-06-01T00:00:00Z&region=eu")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC), bound.Since)
+	require.NotNil(t, bound.Before)
+	assert.Equal(t, time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), *bound.Before)
+	assert.Equal(t, "EU", bound.Region.Name)
+
+	w = get("/orders?since=yesterday")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "query parameter since")
+
+	w = get("/products")
+	var httpErr *strut.HTTPError
+	require.Error(t, bindErr)
+	assert.False(t, errors.As(bindErr, &httpErr), "an unsupported field is not the fault of the client")
+	assert.Contains(t, bindErr.Error(), "query parameter filter")
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
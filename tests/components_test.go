@@ -0,0 +1,125 @@
+package tests
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/modfin/strut"
+	"github.com/modfin/strut/with"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type NamedInvoice struct {
+	Number string `json:"number" json-description:"Invoice number"`
+}
+
+func (NamedInvoice) SchemaName() string {
+	return "Invoice"
+}
+
+// TestComponents_SchemaName tests that a type implementing SchemaName controls its component key and $ref
+func TestComponents_SchemaName(t *testing.T) {
+	s := strut.New(slog.Default(), chi.NewRouter())
+
+	strut.Post(s, "/invoices", func(ctx context.Context, req NamedInvoice) strut.Response[NamedInvoice] {
+		return strut.RespondOk(req)
+	},
+		with.OperationId("create-invoice"),
+	)
+
+	require.Contains(t, s.Definition.Components.Schemas, "Invoice")
+	assert.NotContains(t, s.Definition.Components.Schemas, "tests_NamedInvoice")
+
+	op := s.Definition.Paths["/invoices"].Post
+	assert.Equal(t, "#/components/schemas/Invoice", op.RequestBody.Content["application/json"].Schema.Ref)
+	assert.Equal(t, "#/components/schemas/Invoice", op.Responses["200"].Content["application/json"].Schema.Ref)
+}
+
+type Receipt struct {
+	Number string `json:"number"`
+}
+
+// TestComponents_SchemaNamer tests that WithSchemaNamer names the components, warning about names colliding
+func TestComponents_SchemaNamer(t *testing.T) {
+	var logs strings.Builder
+	s := strut.New(slog.New(slog.NewTextHandler(&logs, nil)), chi.NewRouter(),
+		strut.WithSchemaNamer(func(t reflect.Type) string { return "Document" }))
+
+	strut.Post(s, "/receipts", func(ctx context.Context, req Receipt) strut.Response[Receipt] {
+		return strut.RespondOk(req)
+	},
+		with.OperationId("create-receipt"),
+	)
+	require.Contains(t, s.Definition.Components.Schemas, "Document")
+	assert.NotContains(t, s.Definition.Components.Schemas, "tests_Receipt")
+	assert.Equal(t, "#/components/schemas/Document", s.Definition.Paths["/receipts"].Post.RequestBody.Content["application/json"].Schema.Ref)
+	assert.Empty(t, logs.String(), "the same type named twice doesn't collide")
+
+	// Types implementing SchemaName still choose their own name
+	strut.Post(s, "/invoices", func(ctx context.Context, req NamedInvoice) strut.Response[NamedInvoice] {
+		return strut.RespondOk(req)
+	},
+		with.OperationId("create-invoice"),
+	)
+	assert.Contains(t, s.Definition.Components.Schemas, "Invoice")
+	assert.Empty(t, logs.String())
+
+	strut.Post(s, "/tickets", func(ctx context.Context, req TestRequest) strut.Response[Receipt] {
+		return strut.RespondOk(Receipt{})
+	},
+		with.OperationId("create-ticket"),
+	)
+	assert.Contains(t, logs.String(), "schema name collides")
+	assert.Contains(t, logs.String(), "name=Document")
+}
+
+// TestComponents_Reused tests that a type used by several endpoints is stored once, also after the spec is served
+func TestComponents_Reused(t *testing.T) {
+	var logs strings.Builder
+	s := strut.New(slog.New(slog.NewTextHandler(&logs, nil)), chi.NewRouter())
+
+	createReceipt := func(ctx context.Context, req Receipt) strut.Response[Receipt] {
+		return strut.RespondOk(req)
+	}
+	strut.Post(s, "/receipts", createReceipt, with.OperationId("create-receipt"))
+	stored := s.Definition.Components.Schemas["tests_Receipt"]
+
+	s.SchemaHandlerJSON(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/.well-known/openapi.json", nil))
+	strut.Put(s, "/receipts/{id}", createReceipt, with.OperationId("update-receipt"))
+	strut.Post(s, "/receipts/import", createReceipt, with.OperationId("import-receipt"))
+
+	assert.Same(t, stored, s.Definition.Components.Schemas["tests_Receipt"])
+	assert.Empty(t, logs.String())
+}
+
+type Page[T any] struct {
+	Items []T  `json:"items"`
+	Next  *int `json:"next"`
+}
+
+// TestComponents_GenericNames tests that instantiations of generic types get valid component names of their own
+func TestComponents_GenericNames(t *testing.T) {
+	s := strut.New(slog.Default(), chi.NewRouter())
+	strut.Get(s, "/receipts", func(ctx context.Context) strut.Response[Page[Receipt]] {
+		return strut.RespondOk(Page[Receipt]{})
+	}, with.OperationId("list-receipts"), with.ResponseDescription(http.StatusOK, "A page of receipts"))
+	strut.Get(s, "/receipts/by-number", func(ctx context.Context) strut.Response[Page[map[string]*Receipt]] {
+		return strut.RespondOk(Page[map[string]*Receipt]{})
+	}, with.OperationId("list-receipts-by-number"), with.ResponseDescription(http.StatusOK, "Receipts by number"))
+
+	require.Contains(t, s.Definition.Components.Schemas, "tests_Page_Receipt")
+	require.Contains(t, s.Definition.Components.Schemas, "tests_Page_map_string_Receipt")
+	assert.Equal(t, "#/components/schemas/tests_Page_Receipt",
+		s.Definition.Paths["/receipts"].Get.Responses["200"].Content["application/json"].Schema.Ref)
+
+	doc := loadSpec(t, s)
+	items := doc.Components.Schemas["tests_Page_Receipt"].Value.Properties["items"].Value
+	assert.Contains(t, items.Items.Value.Properties, "number")
+}
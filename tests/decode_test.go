@@ -0,0 +1,152 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/modfin/strut"
+	"github.com/modfin/strut/schema"
+	"github.com/modfin/strut/with"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestDecodeInto tests that a body is decoded, coerced and validated against a schema built at runtime
+func TestDecodeInto(t *testing.T) {
+	target := &schema.JSON{
+		Type: schema.Object,
+		Properties: map[string]*schema.JSON{
+			"name":     {Type: schema.String},
+			"quantity": {Type: schema.Integer},
+			"gift":     {Type: schema.Boolean},
+		},
+		Required: []string{"name", "quantity"},
+	}
+
+	r := chi.NewRouter()
+	s := strut.New(slog.Default(), r)
+	strut.RawPost[map[string]any, map[string]any](s, "/records", func(w http.ResponseWriter, r *http.Request) {
+		record, err := strut.DecodeInto(r.Context(), target)
+		var httpErr *strut.HTTPError
+		if errors.As(err, &httpErr) {
+			_ = strut.RespondError[any](httpErr.StatusCode, httpErr.Message).Respond(w, r)
+			return
+		}
+		require.NoError(t, err)
+		_ = strut.RespondOk(record).Respond(w, r)
+	},
+		with.OperationId("create-record"),
+	)
+
+	post := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/records", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("coerced", func(t *testing.T) {
+		w := post(`{"name": "mug", "quantity": "2", "gift": "true", "note": "fragile"}`)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var record map[string]any
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&record))
+		assert.Equal(t, map[string]any{"name": "mug", "quantity": 2.0, "gift": true, "note": "fragile"}, record)
+	})
+
+	t.Run("missing required", func(t *testing.T) {
+		w := post(`{"name": "mug"}`)
+		require.Equal(t, http.StatusBadRequest, w.Code)
+
+		var result strut.Error
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&result))
+		assert.Equal(t, "quantity: required", result.Error)
+	})
+
+	t.Run("wrong type", func(t *testing.T) {
+		w := post(`{"name": "mug", "quantity": "two"}`)
+		require.Equal(t, http.StatusBadRequest, w.Code)
+
+		var result strut.Error
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&result))
+		assert.Equal(t, "quantity: type integer", result.Error)
+	})
+
+	t.Run("not an object", func(t *testing.T) {
+		w := post(`[1, 2]`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("malformed", func(t *testing.T) {
+		w := post(`{"name": `)
+		require.Equal(t, http.StatusBadRequest, w.Code)
+
+		var result strut.Error
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&result))
+		assert.Contains(t, result.Error, "could not decode request")
+	})
+}
+
+type PatchProductRequest struct {
+	Name        strut.Optional[string]  `json:"name,omitzero" json-min-length:"1"`
+	Description strut.Optional[string]  `json:"description,omitzero"`
+	Price       strut.Optional[float64] `json:"price,omitzero"`
+}
+
+// TestOptional tests that Optional fields tell absent, null and zero values apart, and are documented as nullable
+func TestOptional(t *testing.T) {
+	r := chi.NewRouter()
+	s := strut.New(slog.Default(), r)
+	var got PatchProductRequest
+	strut.Put(s, "/products/{id}", func(ctx context.Context, req PatchProductRequest) strut.Response[PatchProductRequest] {
+		got = req
+		return strut.RespondOk(req)
+	},
+		with.OperationId("update-product"),
+		with.PathParam[int]("id", "Product id"),
+		with.ResponseDescription(http.StatusOK, "The product as patched"),
+		with.Validate(),
+	)
+
+	req := httptest.NewRequest(http.MethodPut, "/products/1", strings.NewReader(`{"description": null, "price": 0}`))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	assert.Equal(t, strut.Optional[string]{}, got.Name)
+	assert.Equal(t, strut.Optional[string]{Set: true, Null: true}, got.Description)
+	assert.Equal(t, strut.Some(0.0), got.Price)
+	assert.JSONEq(t, `{"description": null, "price": 0}`, w.Body.String())
+
+	doc := loadSpec(t, s)
+	body := doc.Paths.Find("/products/{id}").Put.RequestBody.Value.Content.Get("application/json").Schema.Value
+	assert.Empty(t, body.Required)
+	assert.True(t, body.Properties["name"].Value.Nullable)
+	assert.Equal(t, "string", body.Properties["name"].Value.Type.Slice()[0])
+	assert.Equal(t, uint64(1), body.Properties["name"].Value.MinLength)
+	assert.True(t, body.Properties["price"].Value.Nullable)
+}
+
+type Dimensions struct {
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+}
+
+// TestOptional_Options tests that the value of an Optional is converted with the options of the schema it is in
+func TestOptional_Options(t *testing.T) {
+	s := schema.From(struct {
+		Dimensions strut.Optional[Dimensions] `json:"dimensions,omitzero"`
+	}{}, schema.Strict())
+
+	dimensions := s.Properties["dimensions"]
+	assert.True(t, dimensions.Nullable)
+	require.NotNil(t, dimensions.AdditionalPropertiesBool)
+	assert.False(t, *dimensions.AdditionalPropertiesBool)
+}
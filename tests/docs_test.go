@@ -0,0 +1,67 @@
+package tests
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/modfin/strut"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSwaggerUIHandler tests that the Swagger UI page points at the spec and uses the API title
+func TestSwaggerUIHandler(t *testing.T) {
+	r := chi.NewRouter()
+	s := strut.New(slog.Default(), r).Title("Docs <API>")
+
+	r.Get("/.well-known/openapi.json", s.SchemaHandlerJSON)
+	r.Get("/docs", s.SwaggerUIHandler("/.well-known/openapi.json"))
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/docs")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/html; charset=utf-8", resp.Header.Get("Content-Type"))
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), "<title>Docs &lt;API&gt;</title>")
+	assert.Contains(t, string(body), "swagger-ui-bundle.js")
+	assert.Contains(t, string(body), `url: "/.well-known/openapi.json"`)
+}
+
+// TestRedocHandler tests that the ReDoc page points at the spec and that the script location can be overridden
+func TestRedocHandler(t *testing.T) {
+	r := chi.NewRouter()
+	s := strut.New(slog.Default(), r).Title("Reference API")
+
+	r.Get("/.well-known/openapi.yaml", s.SchemaHandlerYAML)
+	r.Get("/reference", s.RedocHandler("/.well-known/openapi.yaml"))
+
+	req := httptest.NewRequest(http.MethodGet, "/reference", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/html; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Body.String(), "<title>Reference API</title>")
+	assert.Contains(t, w.Body.String(), `<redoc spec-url="/.well-known/openapi.yaml"></redoc>`)
+	assert.Contains(t, w.Body.String(), "redoc.standalone.js")
+
+	s.RedocScript("/assets/redoc.standalone.js")
+	r.Get("/reference-local", s.RedocHandler("/.well-known/openapi.yaml"))
+
+	req = httptest.NewRequest(http.MethodGet, "/reference-local", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Contains(t, w.Body.String(), `<script src="/assets/redoc.standalone.js"></script>`)
+}
@@ -0,0 +1,180 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/modfin/strut"
+	"github.com/modfin/strut/with"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errDatabase = errors.New("database is down")
+
+// TestHandlersE tests handlers returning (RES, error), errors mapping to their status code
+func TestHandlersE(t *testing.T) {
+	r := chi.NewRouter()
+	s := strut.New(slog.Default(), r)
+
+	strut.GetE(s, "/messages/{id}", func(ctx context.Context) (TestResponse, error) {
+		switch id := strut.PathParam(ctx, "id"); id {
+		case "missing":
+			return TestResponse{}, strut.NewError(http.StatusNotFound, "message not found")
+		case "wrapped":
+			return TestResponse{}, fmt.Errorf("loading message: %w", strut.NewError(http.StatusGone, "message deleted"))
+		case "broken":
+			return TestResponse{}, errDatabase
+		default:
+			return TestResponse{Echo: id}, nil
+		}
+	}, with.OperationId("get-message"))
+
+	strut.PostE(s, "/messages", func(ctx context.Context, req TestRequest) (TestResponse, error) {
+		if req.Message == "" {
+			return TestResponse{}, strut.NewError(http.StatusBadRequest, "message is required")
+		}
+		return TestResponse{Echo: req.Message}, nil
+	}, with.OperationId("post-message"))
+
+	strut.PutE(s, "/messages/{id}", func(ctx context.Context, req TestRequest) (TestResponse, error) {
+		return TestResponse{Echo: strut.PathParam(ctx, "id") + ":" + req.Message}, nil
+	}, with.OperationId("put-message"))
+
+	strut.DeleteE(s, "/messages/{id}", func(ctx context.Context) (TestResponse, error) {
+		return TestResponse{}, errDatabase
+	}, with.OperationId("delete-message"))
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		status int
+		echo   string
+		error  string
+	}{
+		{name: "get ok", method: http.MethodGet, path: "/messages/42", status: http.StatusOK, echo: "42"},
+		{name: "get not found", method: http.MethodGet, path: "/messages/missing", status: http.StatusNotFound, error: "message not found"},
+		{name: "get wrapped", method: http.MethodGet, path: "/messages/wrapped", status: http.StatusGone, error: "message deleted"},
+		{name: "get internal", method: http.MethodGet, path: "/messages/broken", status: http.StatusInternalServerError, error: "Internal Server Error"},
+		{name: "post ok", method: http.MethodPost, path: "/messages", body: `{"message": "hello"}`, status: http.StatusOK, echo: "hello"},
+		{name: "post bad request", method: http.MethodPost, path: "/messages", body: `{}`, status: http.StatusBadRequest, error: "message is required"},
+		{name: "put ok", method: http.MethodPut, path: "/messages/42", body: `{"message": "hello"}`, status: http.StatusOK, echo: "42:hello"},
+		{name: "delete internal", method: http.MethodDelete, path: "/messages/42", status: http.StatusInternalServerError, error: "Internal Server Error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.status, w.Code)
+			assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+			if tt.error != "" {
+				var result strut.Error
+				require.NoError(t, json.NewDecoder(w.Body).Decode(&result))
+				assert.Equal(t, strut.Error{StatusCode: tt.status, Error: tt.error}, result)
+				return
+			}
+			var result TestResponse
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&result))
+			assert.Equal(t, tt.echo, result.Echo)
+		})
+	}
+
+	// The handlers are documented like their Response returning counterparts
+	assert.Equal(t, "get-message", s.Definition.Paths["/messages/{id}"].Get.OperationID)
+	assert.NotNil(t, s.Definition.Paths["/messages"].Post.RequestBody)
+}
+
+// TestDecodeError tests that undecodable request bodies are responded as a JSON Error pointing out the offset
+func TestDecodeError(t *testing.T) {
+	r := chi.NewRouter()
+	s := strut.New(slog.Default(), r)
+
+	handler := func(ctx context.Context, req TestRequest) strut.Response[TestResponse] {
+		return strut.RespondOk(TestResponse{Echo: req.Message})
+	}
+	strut.Post(s, "/messages", handler, with.OperationId("post-message"))
+	strut.Post(s, "/validated", handler, with.OperationId("post-validated"), with.Validate())
+
+	tests := []struct {
+		name    string
+		path    string
+		body    string
+		message string
+	}{
+		{name: "syntax", path: "/messages", body: `{"message": "hello",}`, message: "could not decode request: invalid character '}' looking for beginning of object key string at offset 21"},
+		{name: "type", path: "/messages", body: `{"message": 42}`, message: "could not decode request: json: cannot unmarshal number into Go struct field TestRequest.message of type string at offset 14"},
+		{name: "empty", path: "/messages", body: ``, message: "could not decode request: EOF"},
+		{name: "validated syntax", path: "/validated", body: `{"message" "hello"}`, message: "could not decode request: invalid character '\"' after object key at offset 12"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+			var result strut.Error
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&result))
+			assert.Equal(t, strut.Error{StatusCode: http.StatusBadRequest, Error: tt.message}, result)
+		})
+	}
+}
+
+// TestWithRecover tests that panicking handlers are responded as a 500 Error rather than crashing the server
+func TestWithRecover(t *testing.T) {
+	var logs bytes.Buffer
+	r := chi.NewRouter()
+	s := strut.New(slog.New(slog.NewTextHandler(&logs, nil)), r, strut.WithRecover())
+
+	strut.Get(s, "/panic", func(ctx context.Context) strut.Response[TestResponse] {
+		panic("something went wrong")
+	}, with.OperationId("panic"))
+	strut.Get(s, "/abort", func(ctx context.Context) strut.Response[TestResponse] {
+		panic(http.ErrAbortHandler)
+	}, with.OperationId("abort"))
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/panic")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+	assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+	var result strut.Error
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	assert.Equal(t, strut.Error{StatusCode: http.StatusInternalServerError, Error: "internal error"}, result)
+
+	assert.Contains(t, logs.String(), "panic handling request")
+	assert.Contains(t, logs.String(), "something went wrong")
+	assert.Contains(t, logs.String(), "stack=")
+
+	// The server is still serving
+	resp, err = http.Get(server.URL + "/panic")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+
+	// http.ErrAbortHandler is not swallowed
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/abort", nil))
+	})
+}
@@ -0,0 +1,251 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/modfin/strut"
+	"github.com/modfin/strut/with"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type AvatarUpload struct {
+	Title       string                  `json:"title" json-description:"Title of the avatar"`
+	Public      bool                    `json:"public"`
+	Tags        []string                `json:"tags"`
+	Avatar      *multipart.FileHeader   `json:"avatar" json-description:"The image"`
+	Attachments []*multipart.FileHeader `json:"attachments"`
+}
+
+type AvatarUploaded struct {
+	Title       string   `json:"title"`
+	Public      bool     `json:"public"`
+	Tags        []string `json:"tags"`
+	Content     string   `json:"content"`
+	Attachments []string `json:"attachments"`
+}
+
+// TestMultipartRequest tests that multipart/form-data requests are documented and decoded, including files
+func TestMultipartRequest(t *testing.T) {
+	r := chi.NewRouter()
+	s := strut.New(slog.Default(), r)
+
+	strut.Post(s, "/avatars", func(ctx context.Context, req AvatarUpload) strut.Response[AvatarUploaded] {
+		file, _, err := strut.FormFile(ctx, "avatar")
+		if err != nil {
+			return strut.RespondError[AvatarUploaded](http.StatusBadRequest, err.Error())
+		}
+		defer file.Close()
+		content, _ := io.ReadAll(file)
+
+		res := AvatarUploaded{Title: req.Title, Public: req.Public, Tags: req.Tags, Content: string(content)}
+		for _, attachment := range req.Attachments {
+			res.Attachments = append(res.Attachments, attachment.Filename)
+		}
+		return strut.RespondOk(res)
+	},
+		with.OperationId("upload-avatar"),
+		with.RequestContentType("multipart/form-data"),
+		with.ResponseDescription(http.StatusOK, "The uploaded avatar"),
+	)
+
+	t.Run("decodes fields and files", func(t *testing.T) {
+		body := &bytes.Buffer{}
+		writer := multipart.NewWriter(body)
+		require.NoError(t, writer.WriteField("title", "Me"))
+		require.NoError(t, writer.WriteField("public", "true"))
+		require.NoError(t, writer.WriteField("tags", "blue"))
+		require.NoError(t, writer.WriteField("tags", "round"))
+		for name, content := range map[string]string{"avatar": "png bytes", "attachments": "a"} {
+			part, err := writer.CreateFormFile(name, name+".png")
+			require.NoError(t, err)
+			_, err = part.Write([]byte(content))
+			require.NoError(t, err)
+		}
+		part, err := writer.CreateFormFile("attachments", "b.png")
+		require.NoError(t, err)
+		_, err = part.Write([]byte("b"))
+		require.NoError(t, err)
+		require.NoError(t, writer.Close())
+
+		req := httptest.NewRequest(http.MethodPost, "/avatars", body)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var result AvatarUploaded
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&result))
+		assert.Equal(t, AvatarUploaded{
+			Title:       "Me",
+			Public:      true,
+			Tags:        []string{"blue", "round"},
+			Content:     "png bytes",
+			Attachments: []string{"attachments.png", "b.png"},
+		}, result)
+	})
+
+	t.Run("rejects malformed fields", func(t *testing.T) {
+		body := &bytes.Buffer{}
+		writer := multipart.NewWriter(body)
+		require.NoError(t, writer.WriteField("public", "perhaps"))
+		require.NoError(t, writer.Close())
+
+		req := httptest.NewRequest(http.MethodPost, "/avatars", body)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "field public")
+	})
+
+	t.Run("documents the form", func(t *testing.T) {
+		doc := loadSpec(t, s)
+
+		content := doc.Paths.Find("/avatars").Post.RequestBody.Value.Content
+		require.Contains(t, content, "multipart/form-data")
+		assert.NotContains(t, content, "application/json")
+
+		upload := doc.Components.Schemas["tests_AvatarUpload"].Value
+		require.NotNil(t, upload)
+		assert.Equal(t, "binary", upload.Properties["avatar"].Value.Format)
+		assert.Equal(t, "The image", upload.Properties["avatar"].Value.Description)
+		assert.Equal(t, "binary", upload.Properties["attachments"].Value.Items.Value.Format)
+	})
+}
+
+type DocumentUpload struct {
+	Document *multipart.FileHeader `json:"document"`
+}
+
+type DocumentUploaded struct {
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+}
+
+// TestMultipartRequest_FileLimits tests that the size and content types of uploaded files are documented and enforced
+func TestMultipartRequest_FileLimits(t *testing.T) {
+	r := chi.NewRouter()
+	s := strut.New(slog.Default(), r)
+
+	strut.Post(s, "/documents", func(ctx context.Context, req DocumentUpload) strut.Response[DocumentUploaded] {
+		file, header, err := strut.FormFile(ctx, "document")
+		if err != nil {
+			return strut.RespondError[DocumentUploaded](http.StatusBadRequest, err.Error())
+		}
+		defer file.Close()
+		return strut.RespondOk(DocumentUploaded{
+			Filename:    header.Filename,
+			ContentType: header.Header.Get("Content-Type"),
+			Size:        header.Size,
+		})
+	},
+		with.OperationId("upload-document"),
+		with.FormFile("document", 8, "application/pdf", "image/*"),
+		with.ResponseDescription(http.StatusOK, "The uploaded document"),
+	)
+
+	upload := func(contentType string, content string) *httptest.ResponseRecorder {
+		body := &bytes.Buffer{}
+		writer := multipart.NewWriter(body)
+		header := textproto.MIMEHeader{}
+		header.Set("Content-Disposition", `form-data; name="document"; filename="doc"`)
+		header.Set("Content-Type", contentType)
+		part, err := writer.CreatePart(header)
+		require.NoError(t, err)
+		_, err = part.Write([]byte(content))
+		require.NoError(t, err)
+		require.NoError(t, writer.Close())
+
+		req := httptest.NewRequest(http.MethodPost, "/documents", body)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("accepts files within the limits", func(t *testing.T) {
+		w := upload("image/png", "png")
+
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var result DocumentUploaded
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&result))
+		assert.Equal(t, DocumentUploaded{Filename: "doc", ContentType: "image/png", Size: 3}, result)
+	})
+
+	t.Run("rejects too large files", func(t *testing.T) {
+		w := upload("application/pdf", "too many bytes")
+
+		assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+		assert.Contains(t, w.Body.String(), "larger than 8 bytes")
+	})
+
+	t.Run("rejects other content types", func(t *testing.T) {
+		w := upload("text/plain", "text")
+
+		assert.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+	})
+
+	t.Run("documents the limits", func(t *testing.T) {
+		doc := loadSpec(t, s)
+
+		content := doc.Paths.Find("/documents").Post.RequestBody.Value.Content
+		require.Contains(t, content, "multipart/form-data")
+		encoding := content["multipart/form-data"].Encoding["document"]
+		require.NotNil(t, encoding)
+		assert.Equal(t, "application/pdf, image/*", encoding.ContentType)
+		assert.EqualValues(t, 8, encoding.Extensions["x-max-size"])
+	})
+}
+
+// TestMultipartRequest_RemovesFiles tests that the files of uploads too large to be kept in memory are removed
+// once the handler has responded
+func TestMultipartRequest_RemovesFiles(t *testing.T) {
+	r := chi.NewRouter()
+	s := strut.New(slog.Default(), r)
+
+	var uploaded *multipart.FileHeader
+	strut.Post(s, "/documents", func(ctx context.Context, req DocumentUpload) strut.Response[DocumentUploaded] {
+		uploaded = req.Document
+		file, err := req.Document.Open()
+		if err != nil {
+			return strut.RespondError[DocumentUploaded](http.StatusInternalServerError, err.Error())
+		}
+		defer file.Close()
+		return strut.RespondOk(DocumentUploaded{Filename: req.Document.Filename, Size: req.Document.Size})
+	},
+		with.OperationId("upload-document"),
+		with.RequestContentType("multipart/form-data"),
+		with.ResponseDescription(http.StatusOK, "The uploaded document"),
+	)
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, err := writer.CreateFormFile("document", "large.bin")
+	require.NoError(t, err)
+	_, err = part.Write(make([]byte, 33<<20))
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+
+	req := httptest.NewRequest(http.MethodPost, "/documents", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.NotNil(t, uploaded)
+	_, err = uploaded.Open()
+	assert.Error(t, err, "the file stored on disk should be removed")
+}
@@ -0,0 +1,97 @@
+package tests
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/modfin/strut"
+	"github.com/modfin/strut/with"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// multipartBody builds a multipart form with a single file of size bytes
+func multipartBody(t *testing.T, size int) (*bytes.Buffer, string) {
+	t.Helper()
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, err := writer.CreateFormFile("file", "upload.bin")
+	require.NoError(t, err)
+	_, err = part.Write(bytes.Repeat([]byte("x"), size))
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+	return body, writer.FormDataContentType()
+}
+
+// TestMaxRequestBytes_PerContentType tests that the limit matching the request's Content-Type is enforced
+func TestMaxRequestBytes_PerContentType(t *testing.T) {
+	r := chi.NewRouter()
+	s := strut.New(slog.Default(), r)
+
+	strut.RawPost[MiddlewareTestRequest, MiddlewareTestResponse](s, "/upload", func(w http.ResponseWriter, r *http.Request) {
+		if _, err := io.ReadAll(r.Body); err != nil {
+			http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	},
+		with.OperationId("upload"),
+		with.MaxRequestBytes(32, "application/json"),
+		with.MaxRequestBytes(4096, "multipart/form-data"),
+	)
+	strut.Post(s, "/messages", middlewareTestPostHandler,
+		with.OperationId("post-message"),
+		with.MaxRequestBytes(32),
+	)
+
+	t.Run("small JSON is accepted", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader(`{"message":"hi"}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("large JSON is rejected", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader(`{"message":"`+strings.Repeat("a", 64)+`"}`))
+		req.Header.Set("Content-Type", "application/json; charset=utf-8")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	})
+
+	t.Run("multipart above the JSON limit is accepted", func(t *testing.T) {
+		body, contentType := multipartBody(t, 1024)
+		req := httptest.NewRequest(http.MethodPost, "/upload", body)
+		req.Header.Set("Content-Type", contentType)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("large multipart is rejected", func(t *testing.T) {
+		body, contentType := multipartBody(t, 8192)
+		req := httptest.NewRequest(http.MethodPost, "/upload", body)
+		req.Header.Set("Content-Type", contentType)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	})
+
+	t.Run("default limit applies to streamed bodies", func(t *testing.T) {
+		body := io.MultiReader(strings.NewReader(`{"message":"` + strings.Repeat("a", 64) + `"}`))
+		req := httptest.NewRequest(http.MethodPost, "/messages", body)
+		req.Header.Set("Content-Type", "application/json")
+		require.Equal(t, int64(-1), req.ContentLength)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	})
+}
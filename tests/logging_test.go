@@ -0,0 +1,86 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/modfin/strut"
+	"github.com/modfin/strut/with"
+	"github.com/stretchr/testify/assert"
+)
+
+type LoginRequest struct {
+	User     string `json:"user"`
+	Password string `json:"password"`
+	Devices  []struct {
+		Name  string `json:"name"`
+		Token string `json:"token"`
+	} `json:"devices"`
+}
+
+// TestRequestLogger_RedactFields tests that logged request bodies have the redacted fields masked
+func TestRequestLogger_RedactFields(t *testing.T) {
+	var logs bytes.Buffer
+	r := chi.NewRouter()
+	s := strut.New(slog.New(slog.NewTextHandler(&logs, nil)), r).RedactFields("password", "Token")
+	s.Use(s.RequestLogger)
+
+	var received LoginRequest
+	strut.Post(s, "/login", func(ctx context.Context, req LoginRequest) strut.Response[TestResponse] {
+		received = req
+		return strut.RespondOk(TestResponse{Echo: req.User})
+	}, with.OperationId("login"))
+
+	body := `{"user": "jane", "password": "hunter2", "devices": [{"name": "phone", "token": "s3cr3t"}]}`
+	req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// The handler still gets the full body
+	assert.Equal(t, "hunter2", received.Password)
+	assert.Equal(t, "s3cr3t", received.Devices[0].Token)
+
+	out := logs.String()
+	assert.Contains(t, out, "msg=request")
+	assert.Contains(t, out, "method=POST")
+	assert.Contains(t, out, "path=/login")
+	assert.Contains(t, out, "status=200")
+	assert.Contains(t, out, `\"password\":\"[REDACTED]\"`)
+	assert.Contains(t, out, `\"token\":\"[REDACTED]\"`)
+	assert.Contains(t, out, `\"user\":\"jane\"`)
+	assert.NotContains(t, out, "hunter2")
+	assert.NotContains(t, out, "s3cr3t")
+}
+
+// TestDuplicateRegistration tests that registering a path and method twice is warned about, naming both operations
+func TestDuplicateRegistration(t *testing.T) {
+	var logs bytes.Buffer
+	s := strut.New(slog.New(slog.NewTextHandler(&logs, nil)), chi.NewRouter())
+
+	get := func(ctx context.Context) strut.Response[TestResponse] {
+		return strut.RespondOk(TestResponse{})
+	}
+	strut.Get(s, "/x", get, with.OperationId("get-x"))
+	strut.Post(s, "/x", func(ctx context.Context, req TestResponse) strut.Response[TestResponse] {
+		return strut.RespondOk(req)
+	}, with.OperationId("create-x"))
+	assert.Empty(t, logs.String(), "other methods of the same path are no duplicates")
+
+	strut.Get(s, "/x", get, with.OperationId("get-x-again"))
+
+	out := logs.String()
+	assert.Contains(t, out, "level=WARN")
+	assert.Contains(t, out, "method=GET")
+	assert.Contains(t, out, "path=/x")
+	assert.Contains(t, out, "operationId=get-x-again")
+	assert.Contains(t, out, "replacedOperationId=get-x")
+	assert.Equal(t, "get-x-again", s.Definition.Paths["/x"].Get.OperationID)
+}
@@ -11,10 +11,14 @@ import (
 
 	"log/slog"
 
+	"github.com/getkin/kin-openapi/openapi3"
 	"github.com/go-chi/chi/v5"
 	"github.com/modfin/strut"
+	"github.com/modfin/strut/schema"
+	"github.com/modfin/strut/swag"
 	"github.com/modfin/strut/with"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // Test data structures for middleware tests
@@ -532,6 +536,89 @@ func TestMiddlewareIsolationWith(t *testing.T) {
 	})
 }
 
+func TestUseRouter(t *testing.T) {
+	r := chi.NewRouter()
+	s := strut.New(slog.Default(), r)
+	s.Use(headerMiddleware("Global"))
+
+	strut.Get(s, "/strut", middlewareTestGetHandler, with.OperationId("strut-get"))
+	s.UseRouter(func(r chi.Router) {
+		r.Get("/raw", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("raw response"))
+		})
+	})
+	assert.Same(t, r, s.Router())
+
+	t.Run("Strut endpoint", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/strut", nil)
+		w := httptest.NewRecorder()
+
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "executed", w.Header().Get("X-Middleware-Global"))
+	})
+
+	t.Run("Raw endpoint", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/raw", nil)
+		w := httptest.NewRecorder()
+
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "raw response", w.Body.String())
+		assert.Equal(t, "executed", w.Header().Get("X-Middleware-Global"))
+	})
+
+	// Only strut endpoints are part of the spec
+	assert.Contains(t, s.Definition.Paths, "/strut")
+	assert.NotContains(t, s.Definition.Paths, "/raw")
+}
+
+// TestContextValuesThroughMiddleware tests that the request and response writer are available to handlers,
+// even when middleware stores values of its own under the same string keys
+func TestContextValuesThroughMiddleware(t *testing.T) {
+	r := chi.NewRouter()
+	s := strut.New(slog.Default(), r)
+
+	s.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), "http-request", "not a request")
+			ctx = context.WithValue(ctx, "http-response-writer", "not a writer")
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	})
+
+	strut.Get(s, "/context/{id}", func(ctx context.Context) strut.Response[MiddlewareTestResponse] {
+		req := strut.HTTPRequest(ctx)
+		w := strut.HTTPResponseWriter(ctx)
+		if req == nil || w == nil {
+			return strut.RespondError[MiddlewareTestResponse](http.StatusInternalServerError, "missing request or writer")
+		}
+		w.Header().Set("X-Path", req.URL.Path)
+		return strut.RespondOk(MiddlewareTestResponse{
+			Message: strut.PathParam(ctx, "id"),
+			Headers: map[string]string{"middleware": fmt.Sprint(ctx.Value("http-request"))},
+		})
+	}, with.OperationId("get-context"))
+
+	req := httptest.NewRequest(http.MethodGet, "/context/42?q=1", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "/context/42", w.Header().Get("X-Path"))
+
+	var result MiddlewareTestResponse
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&result))
+	assert.Equal(t, "42", result.Message)
+	assert.Equal(t, "not a request", result.Headers["middleware"])
+
+	// Outside of a strut handler there is nothing to return
+	assert.Nil(t, strut.HTTPRequest(context.Background()))
+	assert.Nil(t, strut.HTTPResponseWriter(context.Background()))
+}
+
 // Benchmark tests for middleware performance
 func BenchmarkMiddlewareOverhead(b *testing.B) {
 	r := chi.NewRouter()
@@ -553,3 +640,137 @@ func BenchmarkMiddlewareOverhead(b *testing.B) {
 		r.ServeHTTP(w, req)
 	}
 }
+
+// TestMount tests that the endpoints of a mounted Strut are served under the prefix, through the middleware of the
+// parent, and documented in its spec
+func TestMount(t *testing.T) {
+	r := chi.NewRouter()
+	s := strut.New(slog.Default(), r)
+	s.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Parent", "applied")
+			next.ServeHTTP(w, r)
+		})
+	})
+
+	sub := strut.New(slog.Default(), chi.NewRouter())
+	strut.Get(sub, "/orders/{id}", func(ctx context.Context) strut.Response[TestResponse] {
+		if strut.PathParam(ctx, "id") == "0" {
+			return strut.RespondError[TestResponse](http.StatusNotFound, "no such order")
+		}
+		return strut.RespondOk(TestResponse{Echo: strut.PathParam(ctx, "id")})
+	},
+		with.OperationId("get-order"),
+		with.PathParam[string]("id", "Order ID"),
+		with.ResponseDescription(http.StatusOK, "The order"),
+		with.Responds(http.StatusNotFound),
+	)
+	sub.AddTag("orders", "Orders placed")
+	s.Mount("/v1", sub)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/orders/1", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"echo": "1", "time": ""}`, w.Body.String())
+	assert.Equal(t, "applied", w.Header().Get("X-Parent"))
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/orders/0", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	doc := loadSpec(t, s)
+	assert.Nil(t, doc.Paths.Find("/orders/{id}"))
+	responses := doc.Paths.Find("/v1/orders/{id}").Get.Responses
+	assert.Equal(t, "#/components/schemas/tests_TestResponse", responses.Value("200").Value.Content.Get("application/json").Schema.Ref)
+	assert.Equal(t, "#/components/schemas/strut_Error", responses.Value("404").Value.Content.Get("application/json").Schema.Ref)
+	assert.Contains(t, doc.Components.Schemas, "tests_TestResponse")
+	assert.Contains(t, doc.Components.Schemas, "strut_Error")
+	assert.Equal(t, "Orders placed", doc.Tags.Get("orders").Description)
+}
+
+// TestRoute tests that endpoints registered in routes are served and documented under their prefix, nested routes
+// adding to it, with the middleware of the route applied
+func TestRoute(t *testing.T) {
+	r := chi.NewRouter()
+	s := strut.New(slog.Default(), r)
+	s.Route("/orders", func(orders *strut.Strut) {
+		orders.Use(func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("X-Route", "orders")
+				next.ServeHTTP(w, r)
+			})
+		})
+		strut.Get(orders, "/", func(ctx context.Context) strut.Response[[]TestResponse] {
+			return strut.RespondOk([]TestResponse{{Echo: "all"}})
+		}, with.OperationId("list-orders"), with.ResponseDescription(http.StatusOK, "The orders"))
+		orders.Route("/{id}", func(order *strut.Strut) {
+			strut.Get(order, "/items", func(ctx context.Context) strut.Response[TestResponse] {
+				return strut.RespondOk(TestResponse{Echo: strut.PathParam(ctx, "id")})
+			},
+				with.OperationId("list-order-items"),
+				with.PathParam[string]("id", "Order ID"),
+				with.ResponseDescription(http.StatusOK, "The items"),
+			)
+		})
+	})
+	strut.Get(s, "/health", func(ctx context.Context) strut.Response[TestResponse] {
+		return strut.RespondOk(TestResponse{Echo: "ok"})
+	}, with.OperationId("health"), with.ResponseDescription(http.StatusOK, "Healthy"))
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+	w := get("/orders")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "orders", w.Header().Get("X-Route"))
+	w = get("/orders/7/items")
+	assert.JSONEq(t, `{"echo": "7", "time": ""}`, w.Body.String())
+	assert.Equal(t, "orders", w.Header().Get("X-Route"))
+	w = get("/health")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get("X-Route"))
+
+	doc := loadSpec(t, s)
+	assert.NotNil(t, doc.Paths.Find("/orders").Get)
+	assert.NotNil(t, doc.Paths.Find("/orders/{id}/items").Get)
+	assert.NotNil(t, doc.Paths.Find("/health").Get)
+	assert.Nil(t, doc.Paths.Find("/items"))
+}
+
+// TestMount_Security tests that mounted operations keep the security of the mounted Strut, and that neither spec
+// takes over the components of the other
+func TestMount_Security(t *testing.T) {
+	get := func(ctx context.Context) strut.Response[TestResponse] {
+		return strut.RespondOk(TestResponse{})
+	}
+	s := strut.New(slog.Default(), chi.NewRouter()).
+		AddSecurityScheme("bearer", swag.SecurityScheme{Type: "http", Scheme: "bearer"}).
+		Security("bearer")
+	s.Definition.Components.Schemas["Shared"] = &schema.JSON{Type: schema.String}
+
+	partners := strut.New(slog.Default(), chi.NewRouter()).
+		AddSecurityScheme("apiKey", swag.SecurityScheme{Type: "apiKey", Name: "X-API-Key", In: "header"}).
+		Security("apiKey")
+	strut.Get(partners, "/orders", get, with.OperationId("list-partner-orders"), with.ResponseDescription(http.StatusOK, "The orders"))
+	strut.Get(partners, "/health", get, with.OperationId("partner-health"), with.ResponseDescription(http.StatusOK, "Healthy"),
+		with.NoSecurity())
+	partners.Definition.Components.Schemas["Shared"] = &schema.JSON{Type: schema.Integer}
+
+	public := strut.New(slog.Default(), chi.NewRouter())
+	strut.Get(public, "/catalog", get, with.OperationId("get-catalog"), with.ResponseDescription(http.StatusOK, "The catalog"))
+
+	s.Mount("/partners", partners)
+	s.Mount("/public", public)
+
+	doc := loadSpec(t, s)
+	assert.Equal(t, openapi3.SecurityRequirements{{"apiKey": []string{}}}, *doc.Paths.Find("/partners/orders").Get.Security)
+	assert.Empty(t, *doc.Paths.Find("/partners/health").Get.Security)
+	require.NotNil(t, doc.Paths.Find("/public/catalog").Get.Security, "the public module requires no security")
+	assert.Empty(t, *doc.Paths.Find("/public/catalog").Get.Security)
+	assert.Equal(t, &openapi3.Types{"integer"}, doc.Components.Schemas["Shared"].Value.Type)
+
+	assert.NotContains(t, partners.Definition.Components.SecuritySchemes, "bearer", "the spec of the module is its own")
+	assert.Equal(t, schema.Integer, partners.Definition.Components.Schemas["Shared"].Type)
+}
@@ -0,0 +1,470 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/modfin/strut"
+	"github.com/modfin/strut/with"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRespondTyped tests that RespondTyped keeps the body typed to T and writes the given status
+func TestRespondTyped(t *testing.T) {
+	// Compile-time check, the body type is inferred as the response type
+	var _ strut.Response[TestResponse] = strut.RespondTyped(http.StatusCreated, TestResponse{})
+
+	r := chi.NewRouter()
+	s := strut.New(slog.Default(), r)
+
+	strut.Post(s, "/typed", func(ctx context.Context, req TestRequest) strut.Response[TestResponse] {
+		return strut.RespondTyped(http.StatusCreated, TestResponse{Echo: req.Message})
+	},
+		with.OperationId("typed"),
+	)
+
+	req := httptest.NewRequest(http.MethodPost, "/typed", strings.NewReader(`{"message": "hello"}`))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+	var result TestResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&result))
+	assert.Equal(t, "hello", result.Echo)
+}
+
+type WriteOnlyAccount struct {
+	User     string `json:"user" json-description:"User name"`
+	Password string `json:"password" json-description:"User password" json-write-only:"true"`
+}
+
+// TestStripWriteOnly tests that writeOnly fields are removed from the response body when asked to
+func TestStripWriteOnly(t *testing.T) {
+	r := chi.NewRouter()
+	s := strut.New(slog.Default(), r)
+
+	handler := func(ctx context.Context, req WriteOnlyAccount) strut.Response[WriteOnlyAccount] {
+		strut.HTTPResponseWriter(ctx).Header().Set("X-Account", req.User)
+		return strut.RespondOk(req)
+	}
+	strut.Post(s, "/accounts", handler,
+		with.OperationId("create-account"),
+		with.StripWriteOnly(),
+	)
+	strut.Post(s, "/accounts/echo", handler,
+		with.OperationId("echo-account"),
+	)
+
+	req := httptest.NewRequest(http.MethodPost, "/accounts", strings.NewReader(`{"user": "jane", "password": "hunter2"}`))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "jane", w.Header().Get("X-Account"))
+
+	var result map[string]any
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&result))
+	assert.Equal(t, "jane", result["user"])
+	assert.NotContains(t, result, "password")
+
+	// Without the option the body is left untouched
+	req = httptest.NewRequest(http.MethodPost, "/accounts/echo", strings.NewReader(`{"user": "jane", "password": "hunter2"}`))
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	result = map[string]any{}
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&result))
+	assert.Equal(t, "hunter2", result["password"])
+}
+
+// TestPrefer tests that handlers can choose a minimal representation through the Prefer header
+func TestPrefer(t *testing.T) {
+	r := chi.NewRouter()
+	s := strut.New(slog.Default(), r)
+
+	strut.Post(s, "/messages", func(ctx context.Context, req TestRequest) strut.Response[TestResponse] {
+		if strut.Prefer(ctx)["return"] == "minimal" {
+			return strut.RespondFunc[TestResponse](func(w http.ResponseWriter, r *http.Request) error {
+				w.Header().Set("Preference-Applied", "return=minimal")
+				w.WriteHeader(http.StatusNoContent)
+				return nil
+			})
+		}
+		return strut.RespondOk(TestResponse{Echo: req.Message})
+	},
+		with.OperationId("post-message"),
+		with.Prefer("return=minimal omits the response body"),
+	)
+
+	t.Run("parses preferences", func(t *testing.T) {
+		var prefs map[string]string
+		strut.Get(s, "/preferences", func(ctx context.Context) strut.Response[TestResponse] {
+			prefs = strut.Prefer(ctx)
+			return strut.RespondOk(TestResponse{})
+		}, with.OperationId("get-preferences"))
+
+		req := httptest.NewRequest(http.MethodGet, "/preferences", nil)
+		req.Header.Add("Prefer", `return=minimal; foo="bar", respond-async`)
+		req.Header.Add("Prefer", "Wait=10, return=representation")
+		r.ServeHTTP(httptest.NewRecorder(), req)
+
+		assert.Equal(t, map[string]string{"return": "minimal", "respond-async": "", "wait": "10"}, prefs)
+	})
+
+	t.Run("minimal", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/messages", strings.NewReader(`{"message": "hello"}`))
+		req.Header.Set("Prefer", "return=minimal")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		assert.Equal(t, "return=minimal", w.Header().Get("Preference-Applied"))
+		assert.Empty(t, w.Body.String())
+	})
+
+	t.Run("full", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/messages", strings.NewReader(`{"message": "hello"}`))
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"echo":"hello"`)
+	})
+
+	op := s.Definition.Paths["/messages"].Post
+	require.Len(t, op.Parameters, 1)
+	assert.Equal(t, "Prefer", op.Parameters[0].Name)
+	assert.Equal(t, "header", op.Parameters[0].In)
+}
+
+// TestDefaultContentType tests that the configured content type is used both in the spec and in responses
+func TestDefaultContentType(t *testing.T) {
+	r := chi.NewRouter()
+	s := strut.New(slog.Default(), r).DefaultContentType("application/vnd.api+json")
+
+	strut.Post(s, "/messages", func(ctx context.Context, req TestRequest) strut.Response[TestResponse] {
+		if req.Message == "" {
+			return strut.RespondError[TestResponse](http.StatusBadRequest, "message is required")
+		}
+		return strut.RespondOk(TestResponse{Echo: req.Message})
+	},
+		with.OperationId("post-message"),
+		with.Example(http.StatusOK, TestResponse{Echo: "hello"}),
+	)
+
+	op := s.Definition.Paths["/messages"].Post
+	require.Contains(t, op.RequestBody.Content, "application/vnd.api+json")
+	assert.NotContains(t, op.RequestBody.Content, "application/json")
+	response := op.Responses["200"].Content
+	require.Contains(t, response, "application/vnd.api+json")
+	assert.NotContains(t, response, "application/json")
+	assert.Equal(t, "#/components/schemas/tests_TestResponse", response["application/vnd.api+json"].Schema.Ref)
+	assert.Equal(t, TestResponse{Echo: "hello"}, response["application/vnd.api+json"].Example)
+
+	req := httptest.NewRequest(http.MethodPost, "/messages", strings.NewReader(`{"message": "hello"}`))
+	req.Header.Set("Content-Type", "application/vnd.api+json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/vnd.api+json", w.Header().Get("Content-Type"))
+
+	req = httptest.NewRequest(http.MethodPost, "/messages", strings.NewReader(`{}`))
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "application/vnd.api+json", w.Header().Get("Content-Type"))
+}
+
+// TestRespondBatchErrors tests that per item results of a batch are written as 207 Multi-Status
+func TestRespondBatchErrors(t *testing.T) {
+	r := chi.NewRouter()
+	s := strut.New(slog.Default(), r)
+
+	strut.Post(s, "/messages/batch", func(ctx context.Context, req []TestRequest) strut.Response[[]TestResponse] {
+		results := make([]strut.BatchResult, len(req))
+		for i, item := range req {
+			results[i] = strut.BatchResult{Index: i, StatusCode: http.StatusCreated}
+			if item.Message == "" {
+				results[i] = strut.BatchResult{Index: i, StatusCode: http.StatusBadRequest, Error: "message is required"}
+			}
+		}
+		return strut.RespondBatchErrors[[]TestResponse](results)
+	},
+		with.OperationId("post-messages"),
+		with.BatchResponse("Outcome per message"),
+	)
+
+	req := httptest.NewRequest(http.MethodPost, "/messages/batch", strings.NewReader(`[{"message": "hello"}, {"message": ""}]`))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusMultiStatus, w.Code)
+	var result strut.BatchResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&result))
+	assert.Equal(t, strut.BatchResponse{Results: []strut.BatchResult{
+		{Index: 0, StatusCode: http.StatusCreated},
+		{Index: 1, StatusCode: http.StatusBadRequest, Error: "message is required"},
+	}}, result)
+
+	res := s.Definition.Paths["/messages/batch"].Post.Responses["207"]
+	require.NotNil(t, res)
+	assert.Equal(t, "Outcome per message", res.Description)
+	assert.Contains(t, res.Content["application/json"].Schema.Properties, "results")
+}
+
+// TestRespondStream tests that streamed responses get their content type, documented as binary in the spec
+func TestRespondStream(t *testing.T) {
+	r := chi.NewRouter()
+	s := strut.New(slog.Default(), r)
+
+	strut.Get(s, "/report", func(ctx context.Context) strut.Response[[]byte] {
+		return strut.RespondStream[[]byte]("text/csv", func(w io.Writer) error {
+			_, err := io.WriteString(w, "id,name\n1,strut\n")
+			return err
+		})
+	},
+		with.OperationId("report"),
+		with.ResponseDescription(http.StatusOK, "The report"),
+		with.ResponseContentType(http.StatusOK, "text/csv"),
+	)
+
+	req := httptest.NewRequest(http.MethodGet, "/report", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/csv", w.Header().Get("Content-Type"))
+	assert.Equal(t, "id,name\n1,strut\n", w.Body.String())
+
+	content := s.Definition.Paths["/report"].Get.Responses["200"].Content
+	require.Contains(t, content, "text/csv")
+	assert.NotContains(t, content, "application/json")
+	assert.Equal(t, "string", string(content["text/csv"].Schema.Type))
+	assert.Equal(t, "binary", *content["text/csv"].Schema.Format)
+
+	loadSpec(t, s)
+}
+
+// TestCacheable tests that successful GET responses of cacheable operations get a documented Cache-Control header
+func TestCacheable(t *testing.T) {
+	r := chi.NewRouter()
+	s := strut.New(slog.Default(), r)
+
+	strut.Get(s, "/products/{id}", func(ctx context.Context) strut.Response[TestResponse] {
+		switch strut.PathParam(ctx, "id") {
+		case "missing":
+			return strut.RespondError[TestResponse](http.StatusNotFound, "no such product")
+		case "private":
+			strut.HTTPResponseWriter(ctx).Header().Set("Cache-Control", "no-store")
+		}
+		return strut.RespondOk(TestResponse{Echo: "mug"})
+	},
+		with.OperationId("get-product"),
+		with.PathParam[string]("id", "Product ID"),
+		with.ResponseDescription(http.StatusOK, "The product"),
+		with.Cacheable(5*time.Minute),
+	)
+	strut.GetE(s, "/catalog", func(ctx context.Context) (TestResponse, error) {
+		return TestResponse{Echo: "catalog"}, nil
+	},
+		with.OperationId("get-catalog"),
+		with.Cacheable(time.Hour),
+		with.SuccessCode(http.StatusNonAuthoritativeInfo),
+		with.ResponseDescription(http.StatusNonAuthoritativeInfo, "The catalog, as cached"),
+	)
+
+	get := func(id string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/products/"+id, nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("1")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "max-age=300", w.Header().Get("Cache-Control"))
+
+	w = get("missing")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Empty(t, w.Header().Get("Cache-Control"))
+
+	assert.Equal(t, "no-store", get("private").Header().Get("Cache-Control"))
+
+	doc := loadSpec(t, s)
+	header := doc.Paths.Find("/products/{id}").Get.Responses.Status(http.StatusOK).Value.Headers["Cache-Control"]
+	require.NotNil(t, header)
+	assert.Equal(t, "max-age=300", header.Value.Example)
+	assert.True(t, header.Value.Schema.Value.Type.Is("string"))
+
+	catalog := doc.Paths.Find("/catalog").Get.Responses
+	assert.Nil(t, catalog.Status(http.StatusOK), "the header is documented on the success code, whatever the order")
+	require.Contains(t, catalog.Status(http.StatusNonAuthoritativeInfo).Value.Headers, "Cache-Control")
+	assert.Equal(t, "max-age=3600", catalog.Status(http.StatusNonAuthoritativeInfo).Value.Headers["Cache-Control"].Value.Example)
+}
+
+// TestSuccessCode tests that the response body is documented under the success code, which E handlers respond
+func TestSuccessCode(t *testing.T) {
+	r := chi.NewRouter()
+	s := strut.New(slog.Default(), r)
+	strut.PostE(s, "/orders", func(ctx context.Context, req TestRequest) (TestResponse, error) {
+		return TestResponse{Echo: req.Message}, nil
+	},
+		with.OperationId("create-order"),
+		with.SuccessCode(http.StatusCreated),
+		with.ResponseDescription(http.StatusCreated, "The created order"),
+	)
+	strut.Post(s, "/orders/{id}/ship", func(ctx context.Context, req TestRequest) strut.Response[TestResponse] {
+		return strut.RespondAccepted(TestResponse{Echo: "shipping"})
+	},
+		with.OperationId("ship-order"),
+		with.PathParam[string]("id", "Order ID"),
+		with.SuccessCode(http.StatusAccepted),
+		with.ResponseDescription(http.StatusAccepted, "Shipping has started"),
+	)
+	strut.DeleteE(s, "/orders/{id}", func(ctx context.Context) (TestResponse, error) {
+		return TestResponse{}, nil
+	},
+		with.OperationId("delete-order"),
+		with.PathParam[string]("id", "Order ID"),
+		with.SuccessCode(http.StatusNoContent),
+	)
+
+	post := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, strings.NewReader(`{"message": "mug"}`)))
+		return w
+	}
+	w := post("/orders")
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.JSONEq(t, `{"echo": "mug", "time": ""}`, w.Body.String())
+	assert.Equal(t, http.StatusAccepted, post("/orders/1/ship").Code)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/orders/1", nil))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Empty(t, w.Body.String())
+
+	doc := loadSpec(t, s)
+	created := doc.Paths.Find("/orders").Post.Responses
+	assert.Nil(t, created.Value("200"))
+	assert.Equal(t, "#/components/schemas/tests_TestResponse", created.Value("201").Value.Content.Get("application/json").Schema.Ref)
+	assert.NotNil(t, doc.Paths.Find("/orders/{id}/ship").Post.Responses.Value("202").Value.Content.Get("application/json"))
+	deleted := doc.Paths.Find("/orders/{id}").Delete.Responses.Value("204").Value
+	assert.Equal(t, "No Content", *deleted.Description)
+	assert.Empty(t, deleted.Content)
+}
+
+// TestNoBody tests that NoBody responses are documented and responded without content, under 204 by default
+func TestNoBody(t *testing.T) {
+	r := chi.NewRouter()
+	s := strut.New(slog.Default(), r)
+	strut.DeleteE(s, "/orders/{id}", func(ctx context.Context) (strut.NoBody, error) {
+		return strut.NoBody{}, nil
+	},
+		with.OperationId("delete-order"),
+		with.PathParam[string]("id", "Order ID"),
+	)
+	strut.PostE(s, "/orders/{id}/ship", func(ctx context.Context, req TestRequest) (strut.NoBody, error) {
+		return strut.NoBody{}, nil
+	},
+		with.OperationId("ship-order"),
+		with.PathParam[string]("id", "Order ID"),
+		with.SuccessCode(http.StatusAccepted),
+	)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/orders/1", nil))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Empty(t, w.Body.String())
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/orders/1/ship", strings.NewReader(`{"message": "mug"}`)))
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	assert.Empty(t, w.Body.String())
+
+	doc := loadSpec(t, s)
+	deleted := doc.Paths.Find("/orders/{id}").Delete.Responses
+	assert.Nil(t, deleted.Value("200"))
+	assert.Equal(t, "No Content", *deleted.Value("204").Value.Description)
+	assert.Empty(t, deleted.Value("204").Value.Content)
+	shipped := doc.Paths.Find("/orders/{id}/ship").Post.Responses.Value("202").Value
+	assert.Equal(t, "Accepted", *shipped.Description)
+	assert.Empty(t, shipped.Content)
+	assert.NotContains(t, doc.Components.Schemas, "strut_NoBody")
+}
+
+type XMLInvoice struct {
+	XMLName xml.Name `json:"-" xml:"invoice"`
+	ID      string   `json:"id" xml:"id,attr"`
+	Lines   []string `json:"lines" xml:"lines>line"`
+}
+
+// TestXML tests that XML responses are served and documented, E handlers responding the format the client accepts
+func TestXML(t *testing.T) {
+	r := chi.NewRouter()
+	s := strut.New(slog.Default(), r)
+	strut.GetE(s, "/invoices/{id}", func(ctx context.Context) (XMLInvoice, error) {
+		return XMLInvoice{ID: strut.PathParam(ctx, "id"), Lines: []string{"mug"}}, nil
+	},
+		with.OperationId("get-invoice"),
+		with.PathParam[string]("id", "Invoice ID"),
+		with.ResponseDescription(http.StatusOK, "The invoice"),
+		with.ResponseContentTypes("application/json", "application/xml"),
+	)
+	strut.Get(s, "/legacy/invoices/{id}", func(ctx context.Context) strut.Response[XMLInvoice] {
+		return strut.RespondXML(XMLInvoice{ID: strut.PathParam(ctx, "id")})
+	},
+		with.OperationId("get-legacy-invoice"),
+		with.PathParam[string]("id", "Invoice ID"),
+		with.ResponseDescription(http.StatusOK, "The invoice"),
+		with.ResponseContentTypes("application/xml"),
+	)
+
+	get := func(path string, accept string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Accept", accept)
+		r.ServeHTTP(w, req)
+		return w
+	}
+	w := get("/invoices/7", "application/xml")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/xml", w.Header().Get("Content-Type"))
+	assert.Equal(t, xml.Header+`<invoice id="7"><lines><line>mug</line></lines></invoice>`, w.Body.String())
+
+	w = get("/invoices/7", "application/xml;q=0.5, application/json")
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"id": "7", "lines": ["mug"]}`, w.Body.String())
+	assert.Equal(t, "application/json", get("/invoices/7", "").Header().Get("Content-Type"))
+	assert.Equal(t, "application/xml", get("/invoices/7", "application/*;q=1, application/json;q=0.5").Header().Get("Content-Type"))
+	assert.Equal(t, "application/json", get("/invoices/7", "*/*").Header().Get("Content-Type"))
+
+	w = get("/legacy/invoices/7", "")
+	assert.Equal(t, "application/xml", w.Header().Get("Content-Type"))
+	assert.Equal(t, xml.Header+`<invoice id="7"><lines></lines></invoice>`, w.Body.String())
+
+	doc := loadSpec(t, s)
+	content := doc.Paths.Find("/invoices/{id}").Get.Responses.Value("200").Value.Content
+	assert.Equal(t, "#/components/schemas/tests_XMLInvoice", content.Get("application/json").Schema.Ref)
+	assert.Equal(t, "#/components/schemas/tests_XMLInvoice", content.Get("application/xml").Schema.Ref)
+	legacy := doc.Paths.Find("/legacy/invoices/{id}").Get.Responses.Value("200").Value.Content
+	assert.Nil(t, legacy.Get("application/json"))
+	assert.NotNil(t, legacy.Get("application/xml"))
+
+	invoice := doc.Components.Schemas["tests_XMLInvoice"].Value
+	assert.Equal(t, "invoice", invoice.XML.Name)
+	assert.True(t, invoice.Properties["id"].Value.XML.Attribute)
+	assert.True(t, invoice.Properties["lines"].Value.XML.Wrapped)
+	assert.Equal(t, "line", invoice.Properties["lines"].Value.Items.Value.XML.Name)
+}
@@ -0,0 +1,69 @@
+package tests
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/modfin/strut"
+	"github.com/modfin/strut/with"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestServeMux tests that endpoints are served by an http.ServeMux, with their path parameters, middleware,
+// routes and mounts, as they are by chi
+func TestServeMux(t *testing.T) {
+	mux := http.NewServeMux()
+	s := strut.New(slog.Default(), strut.ServeMux(mux))
+	s.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Strut", "applied")
+			next.ServeHTTP(w, r)
+		})
+	})
+
+	strut.Get(s, "/people/{id}", func(ctx context.Context) strut.Response[TestResponse] {
+		return strut.RespondOk(TestResponse{Echo: strut.PathParam(ctx, "id")})
+	},
+		with.OperationId("get-person"),
+		with.PathParam[string]("id", "Person ID"),
+		with.ResponseDescription(http.StatusOK, "The person"),
+	)
+	s.Route("/orders", func(orders *strut.Strut) {
+		strut.PostE(orders, "/", func(ctx context.Context, req TestRequest) (TestResponse, error) {
+			return TestResponse{Echo: req.Message}, nil
+		}, with.OperationId("create-order"), with.ResponseDescription(http.StatusOK, "The order"))
+	})
+	admin := strut.New(slog.Default(), strut.ServeMux(http.NewServeMux()))
+	strut.Get(admin, "/stats", func(ctx context.Context) strut.Response[TestResponse] {
+		return strut.RespondOk(TestResponse{Echo: "stats"})
+	}, with.OperationId("get-stats"), with.ResponseDescription(http.StatusOK, "The stats"))
+	s.Mount("/admin", admin)
+
+	serve := func(method string, path string, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return w
+	}
+	w := serve(http.MethodGet, "/people/42", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"echo": "42", "time": ""}`, w.Body.String())
+	assert.Equal(t, "applied", w.Header().Get("X-Strut"))
+
+	w = serve(http.MethodPost, "/orders", `{"message": "mug"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"echo": "mug", "time": ""}`, w.Body.String())
+	assert.Equal(t, http.StatusMethodNotAllowed, serve(http.MethodGet, "/orders", "").Code)
+
+	w = serve(http.MethodGet, "/admin/stats", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "applied", w.Header().Get("X-Strut"))
+
+	doc := loadSpec(t, s)
+	assert.NotNil(t, doc.Paths.Find("/people/{id}").Get)
+	assert.NotNil(t, doc.Paths.Find("/orders").Post)
+	assert.NotNil(t, doc.Paths.Find("/admin/stats").Get)
+}
@@ -0,0 +1,1295 @@
+package tests
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/go-chi/chi/v5"
+	"github.com/modfin/strut"
+	"github.com/modfin/strut/schema"
+	"github.com/modfin/strut/swag"
+	"github.com/modfin/strut/with"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// loadSpec serves the JSON spec of s and parses and validates it using kin-openapi
+func loadSpec(t *testing.T, s *strut.Strut) *openapi3.T {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodGet, "/.well-known/openapi.json", nil)
+	w := httptest.NewRecorder()
+	s.SchemaHandlerJSON(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	doc, err := openapi3.NewLoader().LoadFromData(w.Body.Bytes())
+	require.NoError(t, err, "OpenAPI schema should be parsable")
+	require.NoError(t, doc.Validate(context.Background()), "OpenAPI schema should be valid")
+	return doc
+}
+
+type ExampleProduct struct {
+	Name  string  `json:"name" json-description:"Product name" json-example:"Coffee mug"`
+	Price float64 `json:"price" json-description:"Price in USD" json-example:"9.95"`
+}
+
+// TestSpec_Examples tests that field, request and response examples end up in a valid spec
+func TestSpec_Examples(t *testing.T) {
+	s := strut.New(slog.Default(), chi.NewRouter())
+
+	strut.Post(s, "/products", func(ctx context.Context, req ExampleProduct) strut.Response[ExampleProduct] {
+		return strut.RespondOk(req)
+	},
+		with.OperationId("create-product"),
+		with.RequestExample(ExampleProduct{Name: "Tea pot", Price: 24.5}),
+		with.Example(http.StatusOK, ExampleProduct{Name: "Tea pot", Price: 24.5}),
+		with.ResponseDescription(http.StatusOK, "The created product"),
+	)
+
+	doc := loadSpec(t, s)
+
+	product := doc.Components.Schemas["tests_ExampleProduct"].Value
+	require.NotNil(t, product)
+	assert.Equal(t, "Coffee mug", product.Properties["name"].Value.Example)
+	assert.Equal(t, 9.95, product.Properties["price"].Value.Example)
+
+	op := doc.Paths.Find("/products").Post
+	request := op.RequestBody.Value.Content["application/json"]
+	require.NotNil(t, request.Schema)
+	assert.Equal(t, map[string]any{"name": "Tea pot", "price": 24.5}, request.Example)
+
+	response := op.Responses.Status(http.StatusOK).Value.Content["application/json"]
+	require.NotNil(t, response.Schema)
+	assert.Equal(t, map[string]any{"name": "Tea pot", "price": 24.5}, response.Example)
+}
+
+// TestSpec_ResponseExamples tests that named examples of a response coexist with its schema
+func TestSpec_ResponseExamples(t *testing.T) {
+	s := strut.New(slog.Default(), chi.NewRouter())
+
+	strut.Get(s, "/products", func(ctx context.Context) strut.Response[[]ExampleProduct] {
+		return strut.RespondOk([]ExampleProduct{})
+	},
+		with.OperationId("list-products"),
+		with.ResponseDescription(http.StatusOK, "The products"),
+		with.ResponseExamples(http.StatusOK, map[string]swag.Example{
+			"success": {Summary: "Some products", Value: []ExampleProduct{{Name: "Tea pot", Price: 24.5}}},
+			"empty":   {Summary: "No products", Value: []ExampleProduct{}},
+		}),
+		with.ResponseExamples(http.StatusOK, map[string]swag.Example{
+			"large": {Summary: "Many products", Value: []ExampleProduct{{Name: "Tea pot", Price: 24.5}, {Name: "Coffee mug", Price: 9.95}}},
+		}),
+	)
+
+	doc := loadSpec(t, s)
+
+	response := doc.Paths.Find("/products").Get.Responses.Status(http.StatusOK).Value.Content["application/json"]
+	require.NotNil(t, response.Schema)
+	assert.Equal(t, "array", response.Schema.Value.Type.Slice()[0])
+
+	require.Len(t, response.Examples, 3)
+	assert.Equal(t, "Some products", response.Examples["success"].Value.Summary)
+	assert.Equal(t, []any{map[string]any{"name": "Tea pot", "price": 24.5}}, response.Examples["success"].Value.Value)
+	assert.Equal(t, "No products", response.Examples["empty"].Value.Summary)
+	assert.Len(t, response.Examples["large"].Value.Value, 2)
+}
+
+// TestSpec_RequestExamples tests that named examples of a request body are documented, replacing a single example
+// as media types can't have both
+func TestSpec_RequestExamples(t *testing.T) {
+	s := strut.New(slog.Default(), chi.NewRouter())
+
+	strut.Post(s, "/products", func(ctx context.Context, req ExampleProduct) strut.Response[ExampleProduct] {
+		return strut.RespondOk(req)
+	},
+		with.OperationId("create-product"),
+		with.ResponseDescription(http.StatusOK, "The created product"),
+		with.RequestExample(ExampleProduct{Name: "Tea pot", Price: 24.5}),
+		with.RequestExamples(map[string]swag.Example{
+			"minimal": {Summary: "Unpriced", Value: ExampleProduct{Name: "Tea pot"}},
+			"full":    {Summary: "Every field", Description: "A priced product", Value: ExampleProduct{Name: "Tea pot", Price: 24.5}},
+		}),
+		with.ResponseExamples(http.StatusOK, map[string]swag.Example{
+			"created": {Summary: "The product", Value: ExampleProduct{Name: "Tea pot", Price: 24.5}},
+		}),
+		with.Example(http.StatusOK, ExampleProduct{Name: "Tea pot", Price: 24.5}),
+	)
+
+	doc := loadSpec(t, s)
+
+	op := doc.Paths.Find("/products").Post
+	request := op.RequestBody.Value.Content["application/json"]
+	require.NotNil(t, request.Schema)
+	assert.Nil(t, request.Example)
+	require.Len(t, request.Examples, 2)
+	assert.Equal(t, "Unpriced", request.Examples["minimal"].Value.Summary)
+	assert.Equal(t, map[string]any{"name": "Tea pot", "price": 0.0}, request.Examples["minimal"].Value.Value)
+	assert.Equal(t, "A priced product", request.Examples["full"].Value.Description)
+	assert.Equal(t, map[string]any{"name": "Tea pot", "price": 24.5}, request.Examples["full"].Value.Value)
+
+	response := op.Responses.Status(http.StatusOK).Value.Content["application/json"]
+	assert.Empty(t, response.Examples, "the example set last replaces the named ones")
+	assert.Equal(t, map[string]any{"name": "Tea pot", "price": 24.5}, response.Example)
+}
+
+type RatedProduct struct {
+	Name  string  `json:"name"`
+	Rate  float64 `json:"rate" json-exclusive-minimum:"0.0" json-exclusive-maximum:"5.0"`
+	Stock int     `json:"stock" json-minimum:"1" json-exclusive-minimum:"0"`
+}
+
+// TestSpec_ExclusiveBounds tests that exclusive bounds are encoded in the form of the OpenAPI version
+func TestSpec_ExclusiveBounds(t *testing.T) {
+	s := strut.New(slog.Default(), chi.NewRouter())
+	strut.Get(s, "/products", func(ctx context.Context) strut.Response[RatedProduct] {
+		return strut.RespondOk(RatedProduct{})
+	}, with.OperationId("get-product"), with.ResponseDescription(http.StatusOK, "The product"))
+
+	t.Run("3.0", func(t *testing.T) {
+		doc := loadSpec(t, s)
+
+		product := doc.Components.Schemas["tests_RatedProduct"].Value
+		require.NotNil(t, product)
+		rate := product.Properties["rate"].Value
+		require.NotNil(t, rate.Min)
+		require.NotNil(t, rate.Max)
+		assert.Equal(t, 0.0, *rate.Min)
+		assert.Equal(t, 5.0, *rate.Max)
+		assert.True(t, rate.ExclusiveMin)
+		assert.True(t, rate.ExclusiveMax)
+
+		// The inclusive minimum is the stricter bound
+		stock := product.Properties["stock"].Value
+		require.NotNil(t, stock.Min)
+		assert.Equal(t, 1.0, *stock.Min)
+		assert.False(t, stock.ExclusiveMin)
+
+		require.NoError(t, product.VisitJSON(map[string]any{"name": "mug", "rate": 4.5, "stock": 1.0}))
+		assert.Error(t, product.VisitJSON(map[string]any{"name": "mug", "rate": 5.0, "stock": 1.0}))
+		assert.Error(t, product.VisitJSON(map[string]any{"name": "mug", "rate": 0.0, "stock": 1.0}))
+	})
+
+	t.Run("3.1", func(t *testing.T) {
+		s.Definition.OpenAPI = "3.1.0"
+		defer func() { s.Definition.OpenAPI = "3.0.3" }()
+
+		req := httptest.NewRequest(http.MethodGet, "/.well-known/openapi.json", nil)
+		w := httptest.NewRecorder()
+		s.SchemaHandlerJSON(w, req)
+
+		var spec struct {
+			Components struct {
+				Schemas map[string]struct {
+					Properties map[string]map[string]any `json:"properties"`
+				} `json:"schemas"`
+			} `json:"components"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &spec))
+		rate := spec.Components.Schemas["tests_RatedProduct"].Properties["rate"]
+		assert.Equal(t, map[string]any{"type": "number", "format": "double", "exclusiveMinimum": 0.0, "exclusiveMaximum": 5.0}, rate)
+	})
+}
+
+// TestSpec_Freeze tests that a frozen spec is served from its cached encoding, honoring If-None-Match
+func TestSpec_Freeze(t *testing.T) {
+	s := strut.New(slog.Default(), chi.NewRouter()).Title("Frozen")
+	strut.Get(s, "/products", func(ctx context.Context) strut.Response[ExampleProduct] {
+		return strut.RespondOk(ExampleProduct{})
+	}, with.OperationId("get-product"))
+
+	serve := func(handler http.HandlerFunc, ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/.well-known/openapi", nil)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		w := httptest.NewRecorder()
+		handler(w, req)
+		return w
+	}
+
+	before := serve(s.SchemaHandlerJSON, "")
+	require.Equal(t, http.StatusOK, before.Code)
+	require.NotEmpty(t, before.Header().Get("ETag"))
+
+	require.NoError(t, s.Freeze())
+
+	for name, handler := range map[string]http.HandlerFunc{"json": s.SchemaHandlerJSON, "yaml": s.SchemaHandlerYAML} {
+		t.Run(name, func(t *testing.T) {
+			w := serve(handler, "")
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Contains(t, w.Body.String(), "Frozen")
+			etag := w.Header().Get("ETag")
+			require.NotEmpty(t, etag)
+
+			notModified := serve(handler, `"other", `+etag)
+			assert.Equal(t, http.StatusNotModified, notModified.Code)
+			assert.Empty(t, notModified.Body.String())
+			assert.Equal(t, etag, notModified.Header().Get("ETag"))
+
+			assert.Equal(t, http.StatusOK, serve(handler, `"other"`).Code)
+		})
+	}
+
+	// The frozen spec is the same as the one encoded per request
+	after := serve(s.SchemaHandlerJSON, "")
+	assert.Equal(t, before.Body.String(), after.Body.String())
+	assert.Equal(t, before.Header().Get("ETag"), after.Header().Get("ETag"))
+
+	assert.PanicsWithValue(t, "strut: the spec can't be changed after Freeze", func() {
+		strut.Get(s, "/late", func(ctx context.Context) strut.Response[ExampleProduct] {
+			return strut.RespondOk(ExampleProduct{})
+		})
+	})
+	assert.Panics(t, func() { s.Title("Thawed") })
+	assert.Panics(t, func() { s.DefaultContentType("application/vnd.api+json") })
+}
+
+// TestSpec_ParamSchema tests that parameters can be declared with a custom schema
+func TestSpec_ParamSchema(t *testing.T) {
+	s := strut.New(slog.Default(), chi.NewRouter())
+
+	skuPattern, uuid := "^[A-Z]{3}-[0-9]{4}$", "uuid"
+	strut.Get(s, "/products/{sku}", func(ctx context.Context) strut.Response[ExampleProduct] {
+		return strut.RespondOk(ExampleProduct{})
+	},
+		with.OperationId("get-product"),
+		with.ResponseDescription(http.StatusOK, "The product"),
+		with.PathParamSchema("sku", "Stock keeping unit", &schema.JSON{Type: schema.String, Pattern: &skuPattern}),
+		with.QueryParamSchema("sort", "Sort order", &schema.JSON{Type: schema.String, Enum: []interface{}{"asc", "desc"}}),
+		with.HeaderParamSchema("X-Request-Id", "Request ID", &schema.JSON{Type: schema.String, Format: &uuid}),
+	)
+
+	doc := loadSpec(t, s)
+	op := doc.Paths.Find("/products/{sku}").Get
+
+	sku := op.Parameters.GetByInAndName("path", "sku")
+	require.NotNil(t, sku)
+	assert.True(t, sku.Required)
+	assert.Equal(t, skuPattern, sku.Schema.Value.Pattern)
+	assert.NoError(t, sku.Schema.Value.VisitJSON("ABC-1234"))
+	assert.Error(t, sku.Schema.Value.VisitJSON("abc"))
+
+	sort := op.Parameters.GetByInAndName("query", "sort")
+	require.NotNil(t, sort)
+	assert.Equal(t, []any{"asc", "desc"}, sort.Schema.Value.Enum)
+
+	requestID := op.Parameters.GetByInAndName("header", "X-Request-Id")
+	require.NotNil(t, requestID)
+	assert.Equal(t, "uuid", requestID.Schema.Value.Format)
+}
+
+// TestSpec_FreezeGzip tests that a frozen spec is served compressed to clients accepting gzip, with its own ETag
+func TestSpec_FreezeGzip(t *testing.T) {
+	s := strut.New(slog.Default(), chi.NewRouter()).Title("Frozen")
+	strut.Get(s, "/products", func(ctx context.Context) strut.Response[ExampleProduct] {
+		return strut.RespondOk(ExampleProduct{})
+	}, with.OperationId("get-product"))
+	require.NoError(t, s.Freeze())
+
+	serve := func(acceptEncoding string, ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/.well-known/openapi.json", nil)
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		w := httptest.NewRecorder()
+		s.SchemaHandlerJSON(w, req)
+		return w
+	}
+
+	plain := serve("", "")
+	require.Equal(t, http.StatusOK, plain.Code)
+	assert.Empty(t, plain.Header().Get("Content-Encoding"))
+	assert.Equal(t, "Accept-Encoding", plain.Header().Get("Vary"))
+
+	compressed := serve("br, gzip;q=0.8", "")
+	require.Equal(t, http.StatusOK, compressed.Code)
+	assert.Equal(t, "gzip", compressed.Header().Get("Content-Encoding"))
+	assert.Equal(t, "application/json", compressed.Header().Get("Content-Type"))
+	assert.NotEqual(t, plain.Header().Get("ETag"), compressed.Header().Get("ETag"))
+
+	gz, err := gzip.NewReader(compressed.Body)
+	require.NoError(t, err)
+	body, err := io.ReadAll(gz)
+	require.NoError(t, err)
+	assert.Equal(t, plain.Body.String(), string(body))
+
+	notModified := serve("gzip", compressed.Header().Get("ETag"))
+	assert.Equal(t, http.StatusNotModified, notModified.Code)
+	assert.Equal(t, http.StatusOK, serve("", compressed.Header().Get("ETag")).Code)
+
+	assert.Empty(t, serve("gzip;q=0", "").Header().Get("Content-Encoding"))
+}
+
+func BenchmarkSchemaHandlerJSON_FrozenGzip(b *testing.B) {
+	s := strut.New(slog.Default(), chi.NewRouter()).Title("Frozen")
+	strut.Get(s, "/products", func(ctx context.Context) strut.Response[ExampleProduct] {
+		return strut.RespondOk(ExampleProduct{})
+	}, with.OperationId("get-product"))
+	require.NoError(b, s.Freeze())
+
+	req := httptest.NewRequest(http.MethodGet, "/.well-known/openapi.json", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.SchemaHandlerJSON(httptest.NewRecorder(), req)
+	}
+}
+
+// TestSpec_Tags tests that tags are described, with external docs, and referenced by operations
+func TestSpec_Tags(t *testing.T) {
+	s := strut.New(slog.Default(), chi.NewRouter()).
+		AddTag("products", "Everything about products").
+		TagExternalDocs("products", "https://example.com/docs/products", "Product guide").
+		AddTag("admin", "Administration")
+
+	strut.Get(s, "/products", func(ctx context.Context) strut.Response[ExampleProduct] {
+		return strut.RespondOk(ExampleProduct{})
+	},
+		with.OperationId("get-product"),
+		with.ResponseDescription(http.StatusOK, "The product"),
+		with.Tags("products", "admin"),
+	)
+
+	doc := loadSpec(t, s)
+
+	require.Len(t, doc.Tags, 2)
+	products := doc.Tags.Get("products")
+	require.NotNil(t, products)
+	assert.Equal(t, "Everything about products", products.Description)
+	require.NotNil(t, products.ExternalDocs)
+	assert.Equal(t, "https://example.com/docs/products", products.ExternalDocs.URL)
+	assert.Equal(t, "Product guide", products.ExternalDocs.Description)
+
+	admin := doc.Tags.Get("admin")
+	require.NotNil(t, admin)
+	assert.Nil(t, admin.ExternalDocs)
+
+	assert.Equal(t, []string{"products", "admin"}, doc.Paths.Find("/products").Get.Tags)
+}
+
+// TestSpec_UndeclaredTags tests that tags used by operations but never declared are still listed, after the declared ones
+func TestSpec_UndeclaredTags(t *testing.T) {
+	s := strut.New(slog.Default(), chi.NewRouter()).
+		AddTag("products", "Everything about products")
+
+	strut.Get(s, "/orders", func(ctx context.Context) strut.Response[ExampleProduct] {
+		return strut.RespondOk(ExampleProduct{})
+	},
+		with.OperationId("get-orders"),
+		with.ResponseDescription(http.StatusOK, "The orders"),
+		with.Tags("orders", "billing", "products"),
+	)
+
+	doc := loadSpec(t, s)
+
+	names := make([]string, len(doc.Tags))
+	for i, tag := range doc.Tags {
+		names[i] = tag.Name
+	}
+	assert.Equal(t, []string{"products", "billing", "orders"}, names)
+	assert.Equal(t, "Everything about products", doc.Tags.Get("products").Description)
+	assert.Empty(t, doc.Tags.Get("orders").Description)
+
+	// Declaring a tag afterwards keeps its position
+	s.AddTag("orders", "Placing orders")
+	doc = loadSpec(t, s)
+	assert.Len(t, doc.Tags, 3)
+	assert.Equal(t, "Placing orders", doc.Tags.Get("orders").Description)
+}
+
+type OrderCreated struct {
+	OrderID string `json:"order_id" json-description:"The created order"`
+}
+
+// TestSpec_Webhooks tests that webhooks are documented, with their payload schema, in the webhooks of a 3.1 spec
+func TestSpec_Webhooks(t *testing.T) {
+	s := strut.New(slog.Default(), chi.NewRouter()).
+		Webhook("orderCreated", http.MethodPost, "/hooks/orders", OrderCreated{},
+			with.Description("Sent when an order is created"),
+		)
+	s.Definition.OpenAPI = "3.1.0"
+
+	req := httptest.NewRequest(http.MethodGet, "/.well-known/openapi.json", nil)
+	w := httptest.NewRecorder()
+	s.SchemaHandlerJSON(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var spec struct {
+		Webhooks map[string]struct {
+			Post *struct {
+				OperationID string `json:"operationId"`
+				Description string `json:"description"`
+				RequestBody struct {
+					Content map[string]struct {
+						Schema map[string]any `json:"schema"`
+					} `json:"content"`
+				} `json:"requestBody"`
+			} `json:"post"`
+		} `json:"webhooks"`
+		Components struct {
+			Schemas map[string]any `json:"schemas"`
+		} `json:"components"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &spec))
+
+	require.Contains(t, spec.Webhooks, "orderCreated")
+	hook := spec.Webhooks["orderCreated"].Post
+	require.NotNil(t, hook)
+	assert.Equal(t, "orderCreated", hook.OperationID)
+	assert.Equal(t, "Sent when an order is created", hook.Description)
+	assert.Equal(t, map[string]any{"$ref": "#/components/schemas/tests_OrderCreated"}, hook.RequestBody.Content["application/json"].Schema)
+	assert.Contains(t, spec.Components.Schemas, "tests_OrderCreated")
+}
+
+// TestSpec_Webhooks30 tests that webhooks are left out of 3.0 specs, which have no place for them, and reported
+// by Validate
+func TestSpec_Webhooks30(t *testing.T) {
+	s := strut.New(slog.Default(), chi.NewRouter()).
+		Webhook("orderCreated", http.MethodPost, "/hooks/orders", OrderCreated{})
+
+	w := httptest.NewRecorder()
+	s.SchemaHandlerJSON(w, httptest.NewRequest(http.MethodGet, "/.well-known/openapi.json", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), `"webhooks"`)
+	assert.Contains(t, s.Definition.Webhooks, "orderCreated", "the definition itself keeps them")
+
+	err := s.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "webhooks are part of OpenAPI 3.1")
+}
+
+// TestSpec_WebhookTwice tests that registering the same webhook operation twice is warned about, as for paths
+func TestSpec_WebhookTwice(t *testing.T) {
+	var logs strings.Builder
+	s := strut.New(slog.New(slog.NewTextHandler(&logs, nil)), chi.NewRouter()).
+		Webhook("orderCreated", http.MethodPost, "/hooks/orders", OrderCreated{}).
+		Webhook("orderCreated", http.MethodPost, "/hooks/orders", OrderCreated{}, with.Description("Replacing"))
+
+	assert.Contains(t, logs.String(), "operation registered twice")
+	assert.Contains(t, logs.String(), "webhook=orderCreated")
+	assert.Equal(t, "Replacing", s.Definition.Webhooks["orderCreated"].Post.Description)
+}
+
+// TestSpec_SchemaHandler tests that the spec is served as YAML or JSON depending on the Accept header
+func TestSpec_SchemaHandler(t *testing.T) {
+	s := strut.New(slog.Default(), chi.NewRouter())
+	strut.Get(s, "/products", func(ctx context.Context) strut.Response[ExampleProduct] {
+		return strut.RespondOk(ExampleProduct{})
+	},
+		with.OperationId("get-product"),
+		with.ResponseDescription(http.StatusOK, "The product"),
+	)
+	require.NoError(t, s.Freeze())
+
+	serve := func(handler http.HandlerFunc, accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/.well-known/openapi", nil)
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		w := httptest.NewRecorder()
+		handler(w, req)
+		return w
+	}
+
+	tests := []struct {
+		accept      string
+		status      int
+		contentType string
+	}{
+		{"", http.StatusOK, "application/json"},
+		{"*/*", http.StatusOK, "application/json"},
+		{"application/json", http.StatusOK, "application/json"},
+		{"application/yaml", http.StatusOK, "application/yaml"},
+		{"text/yaml", http.StatusOK, "application/yaml"},
+		{"application/json;q=0.5, application/yaml", http.StatusOK, "application/yaml"},
+		{"text/html, */*;q=0.1", http.StatusOK, "application/json"},
+		{"text/*", http.StatusOK, "application/yaml"},
+		{"application/*, application/json;q=0.1", http.StatusOK, "application/yaml"},
+		{"text/html", http.StatusNotAcceptable, ""},
+		{"application/yaml;q=0", http.StatusNotAcceptable, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.accept, func(t *testing.T) {
+			w := serve(s.SchemaHandler, tt.accept)
+			assert.Equal(t, tt.status, w.Code)
+			assert.Equal(t, "Accept", w.Header().Get("Vary"))
+			if tt.status == http.StatusOK {
+				assert.Equal(t, tt.contentType, w.Header().Get("Content-Type"))
+			}
+		})
+	}
+
+	// The frozen encodings are served, as by the dedicated handlers
+	assert.Equal(t, serve(s.SchemaHandlerJSON, "").Body.String(), serve(s.SchemaHandler, "application/json").Body.String())
+	assert.Equal(t, serve(s.SchemaHandlerYAML, "").Header().Get("ETag"), serve(s.SchemaHandler, "text/yaml").Header().Get("ETag"))
+}
+
+// TestSpec_PathServers tests that servers set on a path end up on its path item in a valid spec
+func TestSpec_PathServers(t *testing.T) {
+	s := strut.New(slog.Default(), chi.NewRouter()).
+		AddServer("https://api.example.com", "API")
+
+	strut.Get(s, "/uploads", func(ctx context.Context) strut.Response[ExampleProduct] {
+		return strut.RespondOk(ExampleProduct{})
+	},
+		with.OperationId("get-upload"),
+		with.ResponseDescription(http.StatusOK, "The upload"),
+	)
+	s.PathServers("/uploads",
+		swag.Server{URL: "https://uploads.example.com", Description: "Uploads"},
+		swag.Server{
+			URL:       "https://{region}.uploads.example.com",
+			Variables: map[string]swag.ServerVariable{"region": {Default: "eu", Enum: []string{"eu", "us"}}},
+		},
+	)
+
+	doc := loadSpec(t, s)
+
+	require.Len(t, doc.Servers, 1)
+	servers := doc.Paths.Find("/uploads").Servers
+	require.Len(t, servers, 2)
+	assert.Equal(t, "https://uploads.example.com", servers[0].URL)
+	assert.Equal(t, "Uploads", servers[0].Description)
+	assert.Equal(t, "https://{region}.uploads.example.com", servers[1].URL)
+	assert.Equal(t, "eu", servers[1].Variables["region"].Default)
+	assert.Equal(t, []string{"eu", "us"}, servers[1].Variables["region"].Enum)
+}
+
+// TestSpec_OperationServers tests that servers of an operation are documented under it, curl samples calling them
+func TestSpec_OperationServers(t *testing.T) {
+	s := strut.New(slog.Default(), chi.NewRouter()).
+		AddServer("https://api.example.com", "API").
+		GenerateCurlSamples(true)
+
+	get := func(ctx context.Context) strut.Response[ExampleProduct] {
+		return strut.RespondOk(ExampleProduct{})
+	}
+	strut.Get(s, "/invoices", get,
+		with.OperationId("get-invoices"),
+		with.ResponseDescription(http.StatusOK, "The invoices"),
+		with.Server("https://billing.example.com", "Billing"),
+	)
+	strut.Get(s, "/products", get, with.OperationId("get-products"), with.ResponseDescription(http.StatusOK, "The products"))
+
+	doc := loadSpec(t, s)
+
+	op := doc.Paths.Find("/invoices").Get
+	require.NotNil(t, op.Servers)
+	require.Len(t, *op.Servers, 1)
+	assert.Equal(t, "https://billing.example.com", (*op.Servers)[0].URL)
+	assert.Equal(t, "Billing", (*op.Servers)[0].Description)
+	assert.Nil(t, doc.Paths.Find("/products").Get.Servers)
+
+	sample := op.Extensions["x-codeSamples"].([]any)[0].(map[string]any)
+	assert.Equal(t, "curl -X GET 'https://billing.example.com/invoices'", sample["source"])
+}
+
+// TestSpec_ResponseHeaders tests that documented response headers end up in a valid spec
+func TestSpec_ResponseHeaders(t *testing.T) {
+	r := chi.NewRouter()
+	s := strut.New(slog.Default(), r)
+
+	strut.Post(s, "/products", func(ctx context.Context, req ExampleProduct) strut.Response[ExampleProduct] {
+		strut.HTTPResponseWriter(ctx).Header().Set("Location", "/products/1")
+		return strut.RespondTyped(http.StatusCreated, req)
+	},
+		with.OperationId("create-product"),
+		with.ResponseDescription(http.StatusOK, "The product"),
+		with.ResponseDescription(http.StatusCreated, "The product was created"),
+		with.ResponseHeader(http.StatusCreated, "Location", "", "Where the created product is found"),
+		with.ResponseHeader(http.StatusCreated, "X-Rate-Limit-Remaining", 0, "Requests left in the window"),
+	)
+
+	doc := loadSpec(t, s)
+
+	created := doc.Paths.Find("/products").Post.Responses.Status(http.StatusCreated)
+	require.NotNil(t, created)
+	require.Len(t, created.Value.Headers, 2)
+	location := created.Value.Headers["Location"].Value
+	assert.Equal(t, "Where the created product is found", location.Description)
+	assert.True(t, location.Schema.Value.Type.Is("string"))
+	assert.True(t, created.Value.Headers["X-Rate-Limit-Remaining"].Value.Schema.Value.Type.Is("integer"))
+
+	req := httptest.NewRequest(http.MethodPost, "/products", strings.NewReader(`{"name": "mug", "price": 9.95}`))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "/products/1", w.Header().Get("Location"))
+}
+
+// TestSpec_CurlSamples tests that operations get a curl sample calling the server with example values
+func TestSpec_CurlSamples(t *testing.T) {
+	s := strut.New(slog.Default(), chi.NewRouter()).
+		AddServer("https://api.example.com/", "API").
+		GenerateCurlSamples(true)
+
+	strut.Put(s, "/products/{id}", func(ctx context.Context, req ExampleProduct) strut.Response[ExampleProduct] {
+		return strut.RespondOk(req)
+	},
+		with.OperationId("update-product"),
+		with.ResponseDescription(http.StatusOK, "The product"),
+		with.PathParamSchema("id", "Product ID", &schema.JSON{Type: schema.String, Example: "42"}),
+	)
+
+	doc := loadSpec(t, s)
+
+	op := doc.Paths.Find("/products/{id}").Put
+	require.Contains(t, op.Extensions, "x-codeSamples")
+	samples, ok := op.Extensions["x-codeSamples"].([]any)
+	require.True(t, ok)
+	require.Len(t, samples, 1)
+	sample := samples[0].(map[string]any)
+	assert.Equal(t, "Shell", sample["lang"])
+	assert.Equal(t, "curl", sample["label"])
+	assert.Equal(t, "curl -X PUT 'https://api.example.com/products/42' \\\n"+
+		"  -H 'Content-Type: application/json' \\\n"+
+		`  -d '{"name":"Coffee mug","price":9.95}'`, sample["source"])
+	assert.Nil(t, s.Definition.Paths["/products/{id}"].Put.CodeSamples, "samples are generated on what is encoded")
+}
+
+// TestSpec_Info tests that the terms of service, contact and license end up under info in a valid spec
+func TestSpec_Info(t *testing.T) {
+	s := strut.New(slog.Default(), chi.NewRouter()).
+		Title("Products").
+		Version("1.0.0").
+		TermsOfService("https://example.com/terms").
+		Contact("API team", "https://example.com/support", "api@example.com").
+		License("MIT", "https://opensource.org/licenses/MIT")
+	strut.Get(s, "/products", func(ctx context.Context) strut.Response[ExampleProduct] {
+		return strut.RespondOk(ExampleProduct{})
+	},
+		with.OperationId("get-product"),
+		with.ResponseDescription(http.StatusOK, "The product"),
+	)
+
+	doc := loadSpec(t, s)
+
+	assert.Equal(t, "https://example.com/terms", doc.Info.TermsOfService)
+	require.NotNil(t, doc.Info.Contact)
+	assert.Equal(t, "API team", doc.Info.Contact.Name)
+	assert.Equal(t, "https://example.com/support", doc.Info.Contact.URL)
+	assert.Equal(t, "api@example.com", doc.Info.Contact.Email)
+	require.NotNil(t, doc.Info.License)
+	assert.Equal(t, "MIT", doc.Info.License.Name)
+	assert.Equal(t, "https://opensource.org/licenses/MIT", doc.Info.License.URL)
+
+	// Left out unless set
+	req := httptest.NewRequest(http.MethodGet, "/.well-known/openapi.json", nil)
+	w := httptest.NewRecorder()
+	strut.New(slog.Default(), chi.NewRouter()).Title("Products").SchemaHandlerJSON(w, req)
+	var spec struct {
+		Info map[string]any `json:"info"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &spec))
+	assert.NotContains(t, spec.Info, "contact")
+	assert.NotContains(t, spec.Info, "license")
+	assert.NotContains(t, spec.Info, "termsOfService")
+}
+
+// TestSpec_DeprecatedPaths tests that paths get the x-deprecated hint only when all their operations are deprecated
+func TestSpec_DeprecatedPaths(t *testing.T) {
+	s := strut.New(slog.Default(), chi.NewRouter())
+	get := func(ctx context.Context) strut.Response[ExampleProduct] {
+		return strut.RespondOk(ExampleProduct{})
+	}
+
+	strut.Get(s, "/legacy/products", get,
+		with.OperationId("get-legacy-products"),
+		with.ResponseDescription(http.StatusOK, "The products"),
+		with.Deprecated(),
+	)
+	strut.Get(s, "/products", get,
+		with.OperationId("get-products"),
+		with.ResponseDescription(http.StatusOK, "The products"),
+		with.Deprecated(),
+	)
+	strut.Delete(s, "/products", get,
+		with.OperationId("delete-products"),
+		with.ResponseDescription(http.StatusOK, "The deleted products"),
+	)
+
+	doc := loadSpec(t, s)
+
+	assert.Equal(t, true, doc.Paths.Find("/legacy/products").Extensions["x-deprecated"])
+	assert.NotContains(t, doc.Paths.Find("/products").Extensions, "x-deprecated")
+}
+
+// TestSpec_ExternalDocs tests that external docs of the document and operations end up in a valid spec
+func TestSpec_ExternalDocs(t *testing.T) {
+	s := strut.New(slog.Default(), chi.NewRouter()).
+		ExternalDocs("https://developer.example.com", "Developer portal")
+
+	get := func(ctx context.Context) strut.Response[ExampleProduct] {
+		return strut.RespondOk(ExampleProduct{})
+	}
+	strut.Get(s, "/products", get,
+		with.OperationId("get-products"),
+		with.ResponseDescription(http.StatusOK, "The products"),
+		with.ExternalDocs("https://developer.example.com/products", ""),
+	)
+	strut.Get(s, "/orders", get,
+		with.OperationId("get-orders"),
+		with.ResponseDescription(http.StatusOK, "The orders"),
+	)
+
+	doc := loadSpec(t, s)
+
+	require.NotNil(t, doc.ExternalDocs)
+	assert.Equal(t, "https://developer.example.com", doc.ExternalDocs.URL)
+	assert.Equal(t, "Developer portal", doc.ExternalDocs.Description)
+
+	docs := doc.Paths.Find("/products").Get.ExternalDocs
+	require.NotNil(t, docs)
+	assert.Equal(t, "https://developer.example.com/products", docs.URL)
+	assert.Empty(t, docs.Description)
+	assert.Nil(t, doc.Paths.Find("/orders").Get.ExternalDocs)
+}
+
+type ShippedProduct struct {
+	Name    string  `json:"name"`
+	Carrier *string `json:"carrier" json-description:"Carrier, if shipped"`
+}
+
+// TestSpec_OpenAPIVersion tests that 3.1 specs encode nullable schemas as type arrays, and 3.0 ones keep nullable
+func TestSpec_OpenAPIVersion(t *testing.T) {
+	serve := func(opts ...strut.Option) map[string]any {
+		s := strut.New(slog.Default(), chi.NewRouter(), opts...)
+		strut.Get(s, "/products", func(ctx context.Context) strut.Response[ShippedProduct] {
+			return strut.RespondOk(ShippedProduct{})
+		},
+			with.OperationId("get-product"),
+			with.ResponseDescription(http.StatusOK, "The product"),
+		)
+
+		req := httptest.NewRequest(http.MethodGet, "/.well-known/openapi.json", nil)
+		w := httptest.NewRecorder()
+		s.SchemaHandlerJSON(w, req)
+
+		var spec struct {
+			OpenAPI    string `json:"openapi"`
+			Components struct {
+				Schemas map[string]struct {
+					Properties map[string]map[string]any `json:"properties"`
+				} `json:"schemas"`
+			} `json:"components"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &spec))
+		carrier := spec.Components.Schemas["tests_ShippedProduct"].Properties["carrier"]
+		carrier["openapi"] = spec.OpenAPI
+		return carrier
+	}
+
+	assert.Equal(t, map[string]any{
+		"openapi":     "3.0.3",
+		"type":        "string",
+		"nullable":    true,
+		"description": "Carrier, if shipped",
+	}, serve())
+	assert.Equal(t, map[string]any{
+		"openapi":     "3.1.0",
+		"type":        []any{"string", "null"},
+		"description": "Carrier, if shipped",
+	}, serve(strut.WithOpenAPIVersion("3.1.0")))
+}
+
+// TestSpec_ServerVariables tests that templated servers end up in a valid spec, and that undeclared variables panic
+func TestSpec_ServerVariables(t *testing.T) {
+	s := strut.New(slog.Default(), chi.NewRouter()).
+		AddServerWithVars("https://{region}.api.example.com/{version}", "Regional", map[string]swag.ServerVariable{
+			"region":  {Default: "eu", Enum: []string{"eu", "us"}, Description: "Region of the data"},
+			"version": {Default: "v1"},
+		})
+	strut.Get(s, "/products", func(ctx context.Context) strut.Response[ExampleProduct] {
+		return strut.RespondOk(ExampleProduct{})
+	},
+		with.OperationId("get-product"),
+		with.ResponseDescription(http.StatusOK, "The product"),
+	)
+
+	doc := loadSpec(t, s)
+
+	require.Len(t, doc.Servers, 1)
+	region := doc.Servers[0].Variables["region"]
+	require.NotNil(t, region)
+	assert.Equal(t, "eu", region.Default)
+	assert.Equal(t, []string{"eu", "us"}, region.Enum)
+	assert.Equal(t, "v1", doc.Servers[0].Variables["version"].Default)
+
+	tests := []struct {
+		name string
+		vars map[string]swag.ServerVariable
+		err  string
+	}{
+		{"undeclared", map[string]swag.ServerVariable{"region": {Default: "eu"}},
+			"strut: variable version of server https://{region}.api.example.com/{version} is not declared"},
+		{"no default", map[string]swag.ServerVariable{"region": {}, "version": {Default: "v1"}},
+			"strut: variable region of server https://{region}.api.example.com/{version} has no default"},
+		{"default not in enum", map[string]swag.ServerVariable{"region": {Default: "ap", Enum: []string{"eu", "us"}}, "version": {Default: "v1"}},
+			"strut: default ap of variable region of server https://{region}.api.example.com/{version} is not one of its enum"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.PanicsWithValue(t, tt.err, func() {
+				strut.New(slog.Default(), chi.NewRouter()).
+					AddServerWithVars("https://{region}.api.example.com/{version}", "Regional", tt.vars)
+			})
+		})
+	}
+}
+
+// TestSpec_DeclaredStatuses tests that statuses declared by with.Responds are documented when registered,
+// before any request is served
+func TestSpec_DeclaredStatuses(t *testing.T) {
+	s := strut.New(slog.Default(), chi.NewRouter())
+
+	strut.Post(s, "/products", func(ctx context.Context, req ExampleProduct) strut.Response[ExampleProduct] {
+		return strut.RespondCreated(req)
+	},
+		with.OperationId("create-product"),
+		with.ResponseDescription(http.StatusOK, "The product"),
+		with.Responds(http.StatusCreated, http.StatusNoContent, http.StatusUnprocessableEntity),
+		with.Response(http.StatusConflict, swag.ResponseOf[strut.Error]("The product exists")),
+		with.Responds(http.StatusConflict),
+	)
+
+	doc := loadSpec(t, s)
+	responses := doc.Paths.Find("/products").Post.Responses
+
+	created := responses.Status(http.StatusCreated)
+	require.NotNil(t, created)
+	assert.Equal(t, "Created", *created.Value.Description)
+	assert.Equal(t, "#/components/schemas/tests_ExampleProduct", created.Value.Content.Get("application/json").Schema.Ref)
+	assert.Empty(t, responses.Status(http.StatusNoContent).Value.Content)
+	assert.Equal(t, "#/components/schemas/strut_Error",
+		responses.Status(http.StatusUnprocessableEntity).Value.Content.Get("application/json").Schema.Ref)
+	assert.Equal(t, "The product exists", *responses.Status(http.StatusConflict).Value.Description)
+	assert.Equal(t, 5, responses.Len())
+}
+
+// TestSpec_RespondedStatuses tests that statuses responded by RespondCreated and the like are documented once
+// responded with WithRespondedStatuses, and aren't without it
+func TestSpec_RespondedStatuses(t *testing.T) {
+	r := chi.NewRouter()
+	s := strut.New(slog.Default(), r, strut.WithRespondedStatuses())
+
+	strut.Post(s, "/products", func(ctx context.Context, req ExampleProduct) strut.Response[ExampleProduct] {
+		switch req.Name {
+		case "":
+			return strut.RespondError[ExampleProduct](http.StatusUnprocessableEntity, "name is required")
+		case "noop":
+			return strut.RespondNoContent[ExampleProduct]()
+		}
+		return strut.RespondCreated(req)
+	},
+		with.OperationId("create-product"),
+		with.ResponseDescription(http.StatusOK, "The product"),
+		with.Response(http.StatusConflict, swag.ResponseOf[strut.Error]("The product exists")),
+	)
+
+	for _, body := range []string{`{"name": "mug"}`, `{"name": ""}`, `{"name": "noop"}`} {
+		req := httptest.NewRequest(http.MethodPost, "/products", strings.NewReader(body))
+		r.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	doc := loadSpec(t, s)
+	responses := doc.Paths.Find("/products").Post.Responses
+
+	created := responses.Status(http.StatusCreated)
+	require.NotNil(t, created)
+	assert.Equal(t, "Created", *created.Value.Description)
+	assert.Equal(t, "#/components/schemas/tests_ExampleProduct", created.Value.Content.Get("application/json").Schema.Ref)
+
+	unprocessable := responses.Status(http.StatusUnprocessableEntity)
+	require.NotNil(t, unprocessable)
+	assert.Equal(t, "#/components/schemas/strut_Error", unprocessable.Value.Content.Get("application/json").Schema.Ref)
+
+	noContent := responses.Status(http.StatusNoContent)
+	require.NotNil(t, noContent)
+	assert.Empty(t, noContent.Value.Content)
+
+	assert.Equal(t, "The product exists", *responses.Status(http.StatusConflict).Value.Description)
+	assert.Equal(t, 5, responses.Len())
+
+	r = chi.NewRouter()
+	s = strut.New(slog.Default(), r)
+	strut.Post(s, "/products", func(ctx context.Context, req ExampleProduct) strut.Response[ExampleProduct] {
+		return strut.RespondCreated(req)
+	}, with.OperationId("create-product"), with.ResponseDescription(http.StatusOK, "The product"))
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/products", strings.NewReader(`{"name": "mug"}`)))
+	assert.Nil(t, loadSpec(t, s).Paths.Find("/products").Post.Responses.Status(http.StatusCreated), "the spec doesn't depend on traffic")
+}
+
+// TestSpec_ResponseRefs tests that responses added once are referenced by operations and resolved by kin-openapi
+func TestSpec_ResponseRefs(t *testing.T) {
+	s := strut.New(slog.Default(), chi.NewRouter()).
+		AddResponse("NotFound", swag.ResponseOf[strut.Error]("The resource was not found"))
+
+	get := func(ctx context.Context) strut.Response[ExampleProduct] {
+		return strut.RespondOk(ExampleProduct{})
+	}
+	for _, path := range []string{"/products", "/orders"} {
+		strut.Get(s, path, get,
+			with.OperationId("get"+strings.ReplaceAll(path, "/", "-")),
+			with.ResponseDescription(http.StatusOK, "The resource"),
+			with.ResponseRef(http.StatusNotFound, "NotFound"),
+		)
+	}
+
+	doc := loadSpec(t, s)
+
+	require.Contains(t, doc.Components.Responses, "NotFound")
+	for _, path := range []string{"/products", "/orders"} {
+		notFound := doc.Paths.Find(path).Get.Responses.Status(http.StatusNotFound)
+		require.NotNil(t, notFound)
+		assert.Equal(t, "#/components/responses/NotFound", notFound.Ref)
+		require.NotNil(t, notFound.Value, "the reference should be resolved")
+		assert.Equal(t, "The resource was not found", *notFound.Value.Description)
+		assert.NotNil(t, notFound.Value.Content.Get("application/json").Schema.Value.Properties["error"])
+	}
+}
+
+// TestSpec_DefaultResponse tests that the default response is documented under the default key, accepted by kin-openapi
+func TestSpec_DefaultResponse(t *testing.T) {
+	s := strut.New(slog.Default(), chi.NewRouter())
+	strut.Get(s, "/products", func(ctx context.Context) strut.Response[ExampleProduct] {
+		return strut.RespondOk(ExampleProduct{})
+	},
+		with.OperationId("get-products"),
+		with.ResponseDescription(http.StatusOK, "The products"),
+		with.DefaultResponse(swag.ResponseOf[strut.Error]("Unexpected error")),
+	)
+
+	doc := loadSpec(t, s)
+
+	responses := doc.Paths.Find("/products").Get.Responses
+	require.NotNil(t, responses.Default())
+	assert.Equal(t, "Unexpected error", *responses.Default().Value.Description)
+	assert.NotNil(t, responses.Default().Value.Content.Get("application/json").Schema.Value.Properties["error"])
+	assert.NotNil(t, responses.Status(http.StatusOK))
+}
+
+// TestSpec_Security tests that the security of the API applies to operations unless they declare their own,
+// and that requiring undeclared security schemes is found by Validate
+func TestSpec_Security(t *testing.T) {
+	get := func(ctx context.Context) strut.Response[ExampleProduct] {
+		return strut.RespondOk(ExampleProduct{})
+	}
+	s := strut.New(slog.Default(), chi.NewRouter()).
+		AddSecurityScheme("bearer", swag.SecurityScheme{Type: "http", Scheme: "bearer", BearerFormat: "JWT"}).
+		AddSecurityScheme("apiKey", swag.SecurityScheme{Type: "apiKey", Name: "X-API-Key", In: "header"}).
+		Security("bearer")
+	strut.Get(s, "/products", get, with.OperationId("get-products"), with.ResponseDescription(http.StatusOK, "The products"))
+	strut.Get(s, "/health", get, with.OperationId("health"), with.ResponseDescription(http.StatusOK, "Healthy"),
+		with.NoSecurity())
+	strut.Get(s, "/reports", get, with.OperationId("get-reports"), with.ResponseDescription(http.StatusOK, "The reports"),
+		with.Security("apiKey"), with.Security("bearer", "reports:read"))
+	require.NoError(t, s.Validate())
+
+	doc := loadSpec(t, s)
+
+	assert.Equal(t, "bearer", doc.Components.SecuritySchemes["bearer"].Value.Scheme)
+	assert.Equal(t, "X-API-Key", doc.Components.SecuritySchemes["apiKey"].Value.Name)
+	assert.Equal(t, openapi3.SecurityRequirements{{"bearer": []string{}}}, doc.Security)
+	assert.Nil(t, doc.Paths.Find("/products").Get.Security, "the security of the API applies")
+	require.NotNil(t, doc.Paths.Find("/health").Get.Security)
+	assert.Empty(t, *doc.Paths.Find("/health").Get.Security)
+	assert.Equal(t, openapi3.SecurityRequirements{{"apiKey": []string{}}, {"bearer": []string{"reports:read"}}},
+		*doc.Paths.Find("/reports").Get.Security)
+
+	strut.Get(s, "/orders", get, with.OperationId("get-orders"), with.Security("oauth"))
+	s.Security("basic")
+	assert.EqualError(t, s.Validate(), "security scheme basic is not declared\nsecurity scheme oauth is not declared")
+}
+
+// TestSpec_ParamRefs tests that parameters added once are referenced by operations and resolved by kin-openapi
+func TestSpec_ParamRefs(t *testing.T) {
+	s := strut.New(slog.Default(), chi.NewRouter()).
+		AddParam("Page", swag.Param{Name: "page", In: "query", Description: "Page number", Schema: &schema.JSON{Type: schema.Integer}}).
+		AddParam("Limit", swag.Param{Name: "limit", In: "query", Description: "Page size", Schema: &schema.JSON{Type: schema.Integer}})
+
+	get := func(ctx context.Context) strut.Response[[]ExampleProduct] {
+		return strut.RespondOk([]ExampleProduct{})
+	}
+	for _, path := range []string{"/products", "/orders"} {
+		strut.Get(s, path, get,
+			with.OperationId("list"+strings.ReplaceAll(path, "/", "-")),
+			with.ResponseDescription(http.StatusOK, "The page"),
+			with.ParamRef("Page"),
+			with.ParamRef("Limit"),
+		)
+	}
+
+	doc := loadSpec(t, s)
+
+	require.Len(t, doc.Components.Parameters, 2)
+	for _, path := range []string{"/products", "/orders"} {
+		params := doc.Paths.Find(path).Get.Parameters
+		require.Len(t, params, 2)
+		assert.Equal(t, "#/components/parameters/Page", params[0].Ref)
+		require.NotNil(t, params[0].Value, "the reference should be resolved")
+		assert.Equal(t, "page", params[0].Value.Name)
+		assert.Equal(t, "query", params[0].Value.In)
+		assert.Equal(t, "limit", params[1].Value.Name)
+	}
+}
+
+// TestComponentsAsJSONSchema tests that every component ends up under $defs, referenced there rather than in components
+func TestComponentsAsJSONSchema(t *testing.T) {
+	s := strut.New(slog.Default(), chi.NewRouter())
+	strut.Post(s, "/products", func(ctx context.Context, req ExampleProduct) strut.Response[ShippedProduct] {
+		return strut.RespondOk(ShippedProduct{})
+	}, with.OperationId("create-product"))
+	s.Definition.Components.Schemas["Order"] = &schema.JSON{
+		Type:       schema.Object,
+		Properties: map[string]*schema.JSON{"product": {Ref: "#/components/schemas/tests_ExampleProduct"}},
+	}
+
+	b, err := swag.ComponentsAsJSONSchema(s.Definition)
+	require.NoError(t, err)
+
+	var bundle struct {
+		Schema string                    `json:"$schema"`
+		Defs   map[string]map[string]any `json:"$defs"`
+	}
+	require.NoError(t, json.Unmarshal(b, &bundle))
+
+	assert.Equal(t, "https://json-schema.org/draft/2020-12/schema", bundle.Schema)
+	names := make([]string, 0, len(bundle.Defs))
+	for name := range bundle.Defs {
+		names = append(names, name)
+	}
+	assert.ElementsMatch(t, []string{"tests_ExampleProduct", "tests_ShippedProduct", "Order"}, names)
+
+	order := bundle.Defs["Order"]["properties"].(map[string]any)
+	assert.Equal(t, map[string]any{"$ref": "#/$defs/tests_ExampleProduct"}, order["product"])
+	carrier := bundle.Defs["tests_ShippedProduct"]["properties"].(map[string]any)["carrier"]
+	assert.Equal(t, []any{"string", "null"}, carrier.(map[string]any)["type"])
+
+	// The definition itself is left as it was
+	assert.Equal(t, "#/components/schemas/tests_ExampleProduct", s.Definition.Components.Schemas["Order"].Properties["product"].Ref)
+}
+
+// TestSpec_Audiences tests that audience views only contain the operations for the audience, and those for everyone
+func TestSpec_Audiences(t *testing.T) {
+	s := strut.New(slog.Default(), chi.NewRouter())
+	get := func(ctx context.Context) strut.Response[[]ExampleProduct] {
+		return strut.RespondOk([]ExampleProduct{})
+	}
+	strut.Get(s, "/products", get, with.OperationId("list-products"))
+	strut.Get(s, "/partner/products", get, with.OperationId("list-partner-products"), with.Audience("partner", "internal"))
+	strut.Get(s, "/internal/products", get, with.OperationId("list-internal-products"), with.Audience("internal"))
+	strut.Delete(s, "/products", func(ctx context.Context) strut.Response[ExampleProduct] {
+		return strut.RespondOk(ExampleProduct{})
+	}, with.OperationId("delete-products"), with.Audience("internal"))
+	strut.Post(s, "/internal/shipments", func(ctx context.Context, req TestRequest) strut.Response[ShippedProduct] {
+		return strut.RespondOk(ShippedProduct{})
+	}, with.OperationId("ship-products"), with.Audience("internal"), with.Tags("shipping"))
+	strut.Put(s, "/products", func(ctx context.Context, req ExampleProduct) strut.Response[ExampleProduct] {
+		return strut.RespondOk(req)
+	}, with.OperationId("replace-products"), with.Tags("products"), with.Response(http.StatusAccepted, &swag.OpResponse{
+		Description: "The products are being replaced",
+		Content:     map[string]swag.MediaType{"application/json": {Schema: &schema.JSON{Ref: "#/components/schemas/Batch"}}},
+	}))
+	s.Definition.Components.Schemas["Batch"] = &schema.JSON{Type: schema.Object, Properties: map[string]*schema.JSON{
+		"products": {Type: schema.Array, Items: &schema.JSON{Ref: "#/components/schemas/Product"}},
+	}}
+	s.Definition.Components.Schemas["Product"] = &schema.JSON{Type: schema.Object}
+
+	public := s.SpecForAudience("public")
+	assert.ElementsMatch(t, []string{"/products"}, slices.Collect(maps.Keys(public.Paths)))
+	assert.NotNil(t, public.Paths["/products"].Get)
+	assert.Nil(t, public.Paths["/products"].Delete, "the internal operation of a public path should be left out")
+	assert.NotContains(t, public.Components.Schemas, "tests_ShippedProduct", "the schemas of internal operations should be left out")
+	for _, name := range []string{"tests_ExampleProduct", "Batch", "Product"} {
+		assert.Contains(t, public.Components.Schemas, name, "the schemas referenced by public operations should be kept")
+	}
+	assert.Equal(t, []swag.Tag{{Name: "products"}}, public.Tags)
+
+	internal := s.SpecForAudience("internal")
+	assert.ElementsMatch(t, []string{"/products", "/partner/products", "/internal/products", "/internal/shipments"}, slices.Collect(maps.Keys(internal.Paths)))
+	assert.NotNil(t, internal.Paths["/products"].Delete)
+	assert.Contains(t, internal.Components.Schemas, "tests_ShippedProduct")
+	assert.Len(t, internal.Tags, 2)
+
+	assert.NotNil(t, s.Definition.Paths["/products"].Delete, "the definition itself is left as it was")
+	assert.Contains(t, s.Definition.Components.Schemas, "tests_ShippedProduct")
+	assert.NotSame(t, s.Definition.Components.Schemas["Batch"], public.Components.Schemas["Batch"], "the schemas should be copies")
+	assert.NotSame(t, s.Definition.Paths["/products"].Put.Responses["202"], public.Paths["/products"].Put.Responses["202"])
+
+	// the copy is encoded while the spec is prepared again
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, err := json.Marshal(s.SpecForAudience("public"))
+		assert.NoError(t, err)
+	}()
+	s.SchemaHandlerJSON(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	<-done
+
+	b, err := json.Marshal(s.Definition.Paths["/internal/products"].Get)
+	require.NoError(t, err)
+	assert.Contains(t, string(b), `"x-audience":["internal"]`)
+}
+
+// TestStrut_Validate tests that mistakes generators fail on are found, all at once
+func TestStrut_Validate(t *testing.T) {
+	get := func(ctx context.Context) strut.Response[ExampleProduct] {
+		return strut.RespondOk(ExampleProduct{})
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		s := strut.New(slog.Default(), chi.NewRouter())
+		strut.Get(s, "/products/{id}", get, with.OperationId("get-product"), with.PathParam[string]("id", "Product ID"))
+
+		assert.NoError(t, s.Validate())
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		s := strut.New(slog.Default(), chi.NewRouter())
+		strut.Get(s, "/products/{id}", get, with.OperationId("get-product"), with.PathParam[string]("id", "Product ID"))
+		strut.Get(s, "/products/{name}", get, with.OperationId("get-product"), with.PathParam[string]("sku", "Product SKU"))
+		strut.Get(s, "/orders", get, with.ResponseRef(http.StatusNotFound, "NotFound"))
+
+		err := s.Validate()
+		require.Error(t, err)
+		assert.Equal(t, strings.Join([]string{
+			"GET /orders: missing operationId",
+			"paths /products/{id} and /products/{name} are the same but for the names of their parameters",
+			"GET /products/{name}: operationId get-product is used by GET /products/{id} as well",
+			"GET /products/{name}: path parameter sku is not in the path",
+			"GET /products/{name}: path parameter name is not declared",
+			"reference #/components/responses/NotFound does not resolve",
+		}, "\n"), err.Error())
+	})
+}
+
+type ProductV1 struct {
+	Name  string  `json:"name"`
+	Price float64 `json:"price"`
+	SKU   string  `json:"sku,omitempty"`
+}
+
+func (ProductV1) SchemaName() string { return "Product" }
+
+type ProductV2 struct {
+	Name  string `json:"name"`
+	SKU   string `json:"sku"`
+	Stock int    `json:"stock,omitempty"`
+}
+
+func (ProductV2) SchemaName() string { return "Product" }
+
+// TestChangelog tests that the changelog between two versions lists added and removed endpoints and fields,
+// of the request bodies of endpoints as well
+func TestChangelog(t *testing.T) {
+	v1 := strut.New(slog.Default(), chi.NewRouter())
+	strut.Get(v1, "/products/{id}", func(ctx context.Context) strut.Response[ProductV1] {
+		return strut.RespondOk(ProductV1{})
+	}, with.OperationId("get-product"), with.PathParam[string]("id", "Product ID"))
+	strut.Delete(v1, "/products/{id}", func(ctx context.Context) strut.Response[ProductV1] {
+		return strut.RespondOk(ProductV1{})
+	}, with.OperationId("delete-product"), with.PathParam[string]("id", "Product ID"))
+	strut.Post(v1, "/products", func(ctx context.Context, req ProductV1) strut.Response[ProductV1] {
+		return strut.RespondOk(req)
+	}, with.OperationId("create-product"))
+
+	v2 := strut.New(slog.Default(), chi.NewRouter())
+	strut.Get(v2, "/products/{id}", func(ctx context.Context) strut.Response[ProductV2] {
+		return strut.RespondOk(ProductV2{})
+	}, with.OperationId("get-product"), with.PathParam[string]("id", "Product ID"), with.QueryParam[string]("fields", "Fields"), with.Deprecated())
+	strut.Get(v2, "/products/featured", func(ctx context.Context) strut.Response[ProductV2] {
+		return strut.RespondOk(ProductV2{})
+	}, with.OperationId("get-featured-product"), with.Summary("Get the featured product"))
+	strut.Post(v2, "/products", func(ctx context.Context, req ProductV2) strut.Response[ProductV2] {
+		return strut.RespondOk(req)
+	}, with.OperationId("create-product"))
+
+	assert.Equal(t, "## Added\n\n"+
+		"- `GET /products/featured`: Get the featured product\n"+
+		"\n## Removed\n\n"+
+		"- `DELETE /products/{id}`\n"+
+		"\n## Changed\n\n"+
+		"- `GET /products/{id}`: deprecated\n"+
+		"- `GET /products/{id}`: `query parameter fields` added\n"+
+		"- `POST /products`: request body field `stock` added\n"+
+		"- `POST /products`: request body field `price` removed\n"+
+		"- `POST /products`: request body field `sku` is now required\n"+
+		"- `schema Product`: field `stock` added\n"+
+		"- `schema Product`: field `price` removed\n"+
+		"- `schema Product`: field `sku` is now required\n",
+		swag.Changelog(v1.Definition, v2.Definition))
+
+	assert.Equal(t, "No changes\n", swag.Changelog(v2.Definition, v2.Definition))
+}
+
+// TestSpec_Deterministic tests that the spec encodes to the same bytes however often, and in whatever order
+// the endpoints were registered, for golden files to be diffed
+func TestSpec_Deterministic(t *testing.T) {
+	build := func(reversed bool) *strut.Strut {
+		s := strut.New(slog.Default(), chi.NewRouter())
+		registrations := []func(){
+			func() {
+				strut.Get(s, "/products/{id}", func(ctx context.Context) strut.Response[ExampleProduct] {
+					return strut.RespondOk(ExampleProduct{})
+				},
+					with.OperationId("get-product"),
+					with.Tags("products"),
+					with.PathParam[string]("id", "Product ID"),
+					with.ResponseDescription(http.StatusOK, "The product"),
+					with.ResponseDescription(http.StatusNotFound, "No such product"),
+					with.ResponseHeader(http.StatusOK, "X-Request-Id", "", "Request ID"),
+				)
+			},
+			func() {
+				strut.Post(s, "/orders", func(ctx context.Context, req OrderCreated) strut.Response[ShippedProduct] {
+					return strut.RespondOk(ShippedProduct{})
+				}, with.OperationId("create-order"), with.Tags("orders"))
+			},
+			func() {
+				strut.Delete(s, "/orders/{id}", func(ctx context.Context) strut.Response[ShippedProduct] {
+					return strut.RespondOk(ShippedProduct{})
+				}, with.OperationId("delete-order"), with.Tags("orders"), with.PathParam[string]("id", "Order ID"))
+			},
+		}
+		if reversed {
+			slices.Reverse(registrations)
+		}
+		for _, register := range registrations {
+			register()
+		}
+		return s
+	}
+
+	encode := func(s *strut.Strut, handler func(s *strut.Strut) http.HandlerFunc) string {
+		w := httptest.NewRecorder()
+		handler(s)(w, httptest.NewRequest(http.MethodGet, "/openapi", nil))
+		require.Equal(t, http.StatusOK, w.Code)
+		return w.Body.String()
+	}
+	for name, handler := range map[string]func(s *strut.Strut) http.HandlerFunc{
+		"json": func(s *strut.Strut) http.HandlerFunc { return s.SchemaHandlerJSON },
+		"yaml": func(s *strut.Strut) http.HandlerFunc { return s.SchemaHandlerYAML },
+	} {
+		t.Run(name, func(t *testing.T) {
+			s := build(false)
+			first := encode(s, handler)
+			for i := 0; i < 10; i++ {
+				require.Equal(t, first, encode(s, handler), "encoding the same spec again")
+				require.Equal(t, first, encode(build(i%2 == 0), handler), "encoding the spec registered in another order")
+			}
+		})
+	}
+}
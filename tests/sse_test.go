@@ -0,0 +1,176 @@
+package tests
+
+import (
+	"bufio"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/modfin/strut"
+	"github.com/modfin/strut/with"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type OrderStatus struct {
+	OrderID string `json:"order_id"`
+	Status  string `json:"status"`
+}
+
+// TestSSE tests that events are streamed as text/event-stream and documented as such
+func TestSSE(t *testing.T) {
+	r := chi.NewRouter()
+	s := strut.New(slog.Default(), r)
+
+	strut.SSE(s, "/orders/status", func(ctx context.Context, send func(strut.Event[OrderStatus]) error) error {
+		for i, status := range []string{"placed", "shipped"} {
+			err := send(strut.Event[OrderStatus]{
+				ID:    strconv.Itoa(i + 1),
+				Event: "status",
+				Data:  OrderStatus{OrderID: "42", Status: status},
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+		with.OperationId("order-status"),
+	)
+
+	req := httptest.NewRequest(http.MethodGet, "/orders/status", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/event-stream", w.Header().Get("Content-Type"))
+	assert.Equal(t, "no-cache", w.Header().Get("Cache-Control"))
+	assert.True(t, w.Flushed)
+	assert.Equal(t, "id: 1\nevent: status\ndata: {\"order_id\":\"42\",\"status\":\"placed\"}\n\n"+
+		"id: 2\nevent: status\ndata: {\"order_id\":\"42\",\"status\":\"shipped\"}\n\n", w.Body.String())
+
+	doc := loadSpec(t, s)
+	res := doc.Paths.Find("/orders/status").Get.Responses.Status(http.StatusOK)
+	require.NotNil(t, res)
+	require.Contains(t, res.Value.Content, "text/event-stream")
+	assert.NotContains(t, res.Value.Content, "application/json")
+}
+
+// TestSSE_LineBreaks tests that IDs and types with line breaks are refused rather than injecting fields or events
+func TestSSE_LineBreaks(t *testing.T) {
+	r := chi.NewRouter()
+	s := strut.New(slog.Default(), r)
+
+	var errs []error
+	strut.SSE(s, "/orders/status", func(ctx context.Context, send func(strut.Event[OrderStatus]) error) error {
+		errs = append(errs, send(strut.Event[OrderStatus]{ID: "1\nevent: forged", Data: OrderStatus{Status: "placed"}}))
+		errs = append(errs, send(strut.Event[OrderStatus]{Event: "status\r\ndata: forged", Data: OrderStatus{Status: "placed"}}))
+		return nil
+	},
+		with.OperationId("order-status"),
+	)
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/orders/status", nil))
+
+	require.Len(t, errs, 2)
+	assert.ErrorContains(t, errs[0], "line break")
+	assert.ErrorContains(t, errs[1], "line break")
+}
+
+// TestSSE_Disconnect tests that events are flushed as they are sent and that the handler stops once the client is gone
+func TestSSE_Disconnect(t *testing.T) {
+	r := chi.NewRouter()
+	s := strut.New(slog.Default(), r)
+
+	done := make(chan error, 1)
+	strut.SSE(s, "/ticks", func(ctx context.Context, send func(strut.Event[int]) error) error {
+		ticker := time.NewTicker(time.Millisecond)
+		defer ticker.Stop()
+		for i := 0; ; i++ {
+			select {
+			case <-ctx.Done():
+				done <- ctx.Err()
+				return ctx.Err()
+			case <-ticker.C:
+				if err := send(strut.Event[int]{Data: i}); err != nil {
+					done <- err
+					return err
+				}
+			}
+		}
+	})
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/ticks", nil)
+	require.NoError(t, err)
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer res.Body.Close()
+
+	line, err := bufio.NewReader(res.Body).ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "data: 0\n", line)
+
+	cancel()
+	select {
+	case err := <-done:
+		assert.Error(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("handler did not stop after the client disconnected")
+	}
+}
+
+// unflushable hides the Flush of the ResponseWriter it wraps, like middleware wrapping writers naively does
+type unflushable struct {
+	http.ResponseWriter
+}
+
+// TestSSE_Checks tests that streams are refused before any event is sent, with the checks of other endpoints,
+// with 500 by writers that can't stream, and otherwise respond the success code they are documented under
+func TestSSE_Checks(t *testing.T) {
+	r := chi.NewRouter()
+	s := strut.New(slog.Default(), r)
+
+	var streamed bool
+	strut.SSE(s, "/orders/status", func(ctx context.Context, send func(strut.Event[OrderStatus]) error) error {
+		streamed = true
+		return send(strut.Event[OrderStatus]{Data: OrderStatus{Status: "placed"}})
+	},
+		with.OperationId("order-status"),
+		with.QueryParam[string]("order_id", "The order"),
+		with.QueryParam[string]("customer_id", "The customer"),
+		with.ExclusiveParams("order_id", "customer_id"),
+		with.Validate(),
+		with.SuccessCode(http.StatusAccepted),
+	)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/orders/status?order_id=1&customer_id=2", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.False(t, streamed)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(unflushable{w}, httptest.NewRequest(http.MethodGet, "/orders/status", nil))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.NotEqual(t, "text/event-stream", w.Header().Get("Content-Type"))
+	assert.False(t, streamed)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/orders/status?order_id=1", nil))
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	assert.True(t, streamed)
+
+	doc := loadSpec(t, s)
+	res := doc.Paths.Find("/orders/status").Get.Responses.Status(http.StatusAccepted)
+	require.NotNil(t, res)
+	assert.Contains(t, res.Value.Content, "text/event-stream")
+	assert.Nil(t, doc.Paths.Find("/orders/status").Get.Responses.Status(http.StatusOK))
+}
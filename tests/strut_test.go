@@ -295,6 +295,39 @@ func TestStrut_Delete(t *testing.T) {
 	assert.Equal(t, "delete-resource", path.Delete.OperationID)
 }
 
+// TestStrut_Options demonstrates how OPTIONS requests are answered with
+// the methods registered for a path.
+func TestStrut_Options(t *testing.T) {
+	r := chi.NewRouter()
+	s := strut.New(slog.Default(), r)
+
+	handler := func(ctx context.Context) strut.Response[TestResponse] {
+		return strut.RespondOk(TestResponse{Echo: strut.PathParam(ctx, "id")})
+	}
+
+	// Register OPTIONS before the endpoints, the methods are looked up per request
+	strut.Options(s, "/resource/{id}")
+	strut.Get(s, "/resource/{id}", handler, with.OperationId("get-resource"))
+	strut.Post(s, "/resource/{id}", func(ctx context.Context, req TestRequest) strut.Response[TestResponse] {
+		return strut.RespondOk(TestResponse{Echo: req.Message})
+	}, with.OperationId("post-resource"))
+	strut.Delete(s, "/resource/{id}", handler, with.OperationId("delete-resource"))
+
+	req := httptest.NewRequest(http.MethodOptions, "/resource/123", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, "GET, POST, DELETE, OPTIONS", w.Header().Get("Allow"))
+
+	// OPTIONS is not part of the spec
+	path := s.Definition.Paths["/resource/{id}"]
+	assert.NotNil(t, path.Get)
+	assert.NotNil(t, path.Post)
+	assert.NotNil(t, path.Delete)
+	assert.Nil(t, path.Put)
+}
+
 // TestStrut_ErrorHandling demonstrates how to handle errors
 // in endpoint handlers.
 func TestStrut_ErrorHandling(t *testing.T) {
@@ -545,3 +578,21 @@ func TestOpenAPISchemaJSON(t *testing.T) {
 	_, ok = schema["components"].(map[string]interface{})
 	require.True(t, ok, "Schema should have components object")
 }
+
+type JobRequest struct {
+	Name     string    `json:"name"`
+	Progress chan bool `json:"progress"`
+}
+
+// TestStrut_SchemaProblems tests that problems converting the request and response types are logged on registration
+func TestStrut_SchemaProblems(t *testing.T) {
+	var logs strings.Builder
+	s := strut.New(slog.New(slog.NewTextHandler(&logs, nil)), chi.NewRouter())
+	strut.Post(s, "/jobs", func(ctx context.Context, req JobRequest) strut.Response[TestResponse] {
+		return strut.RespondOk(TestResponse{})
+	}, with.OperationId("create-job"))
+
+	assert.Contains(t, logs.String(), "schema converted best-effort")
+	assert.Contains(t, logs.String(), "JobRequest.Progress: chan bool can't be encoded as JSON")
+	assert.NotContains(t, s.Definition.Components.Schemas["tests_JobRequest"].Properties, "progress")
+}
@@ -0,0 +1,164 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/modfin/strut"
+	"github.com/modfin/strut/with"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type ValidationOrderItem struct {
+	SKU      string `json:"sku" json-description:"Stock keeping unit"`
+	Quantity int    `json:"quantity" json-description:"Number of units"`
+}
+
+type ValidationOrder struct {
+	Items []ValidationOrderItem `json:"items" json-description:"Ordered items" json-min-items:"1"`
+}
+
+func createValidationAPI() *chi.Mux {
+	r := chi.NewRouter()
+	s := strut.New(slog.Default(), r)
+
+	strut.Post(s, "/orders", func(ctx context.Context, req ValidationOrder) strut.Response[ValidationOrder] {
+		return strut.RespondOk(req)
+	},
+		with.OperationId("create-order"),
+		with.Validate(),
+	)
+	return r
+}
+
+// TestValidation_MinItems tests that an order without items is rejected when validation is enabled
+func TestValidation_MinItems(t *testing.T) {
+	r := createValidationAPI()
+
+	req := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(`{"items": []}`))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	var result strut.Error
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&result))
+	assert.Equal(t, http.StatusBadRequest, result.StatusCode)
+	assert.Contains(t, result.Error, "items: minItems 1")
+
+	req = httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(`{"items": [{"sku": "a-1", "quantity": 2}]}`))
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+type ValidatedProduct struct {
+	SKU   string  `json:"sku" json-pattern:"^[A-Z]{3}-[0-9]{4}$"`
+	Name  string  `json:"name" json-min-length:"3" json-max-length:"20"`
+	Price float64 `json:"price" json-minimum:"0"`
+}
+
+// TestValidation_Bounds tests that the documented bounds, lengths and patterns of request bodies are enforced
+func TestValidation_Bounds(t *testing.T) {
+	r := chi.NewRouter()
+	s := strut.New(slog.Default(), r)
+	strut.Post(s, "/products", func(ctx context.Context, req ValidatedProduct) strut.Response[ValidatedProduct] {
+		return strut.RespondOk(req)
+	},
+		with.OperationId("create-product"),
+		with.Validate(),
+	)
+
+	post := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/products", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := post(`{"sku": "MUG-0001", "name": "Mug", "price": 9.5}`)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	w = post(`{"sku": "mug-1", "name": "Mu", "price": -1}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	var result strut.Error
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&result))
+	assert.Equal(t, "name: minLength 3; price: minimum 0; sku: pattern ^[A-Z]{3}-[0-9]{4}$", result.Error)
+}
+
+// TestValidation_ExclusiveParams tests that giving more than one of mutually exclusive query parameters is rejected
+func TestValidation_ExclusiveParams(t *testing.T) {
+	r := chi.NewRouter()
+	s := strut.New(slog.Default(), r)
+
+	strut.Get(s, "/customers", func(ctx context.Context) strut.Response[string] {
+		return strut.RespondOk(strut.QueryParam(ctx, "id") + strut.QueryParam(ctx, "email"))
+	},
+		with.OperationId("find-customer"),
+		with.ResponseDescription(http.StatusOK, "The customer"),
+		with.QueryParam[string]("id", "Customer ID"),
+		with.QueryParam[string]("email", "Customer email"),
+		with.ExclusiveParams("id", "email"),
+		with.Validate(),
+	)
+
+	req := httptest.NewRequest(http.MethodGet, "/customers?id=42&email=a@example.com", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	var result strut.Error
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&result))
+	assert.Equal(t, "query parameters id, email are mutually exclusive", result.Error)
+
+	for _, query := range []string{"id=42", "email=a@example.com", ""} {
+		req = httptest.NewRequest(http.MethodGet, "/customers?"+query, nil)
+		w = httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code, query)
+	}
+
+	doc := loadSpec(t, s)
+	assert.Equal(t, []any{[]any{"id", "email"}}, doc.Paths.Find("/customers").Get.Extensions["x-exclusive-params"])
+}
+
+type ExportConfig struct {
+	Format    string `json:"format" json-default:"csv"`
+	PageSize  int    `json:"page_size" json-default:"100"`
+	Compress  bool   `json:"compress" json-default:"true"`
+	Separator string `json:"separator,omitempty"`
+}
+
+// TestApplyDefaults tests that fields absent from the request body get their default, while explicit zero values are kept
+func TestApplyDefaults(t *testing.T) {
+	r := chi.NewRouter()
+	s := strut.New(slog.Default(), r)
+	strut.Post(s, "/exports", func(ctx context.Context, req ExportConfig) strut.Response[ExportConfig] {
+		return strut.RespondOk(req)
+	},
+		with.OperationId("create-export"),
+		with.ApplyDefaults(),
+	)
+
+	post := func(body string) ExportConfig {
+		req := httptest.NewRequest(http.MethodPost, "/exports", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var result ExportConfig
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&result))
+		return result
+	}
+
+	assert.Equal(t, ExportConfig{Format: "csv", PageSize: 100, Compress: true}, post(`{}`))
+	assert.Equal(t, ExportConfig{Format: "json", PageSize: 0, Compress: false, Separator: ";"},
+		post(`{"format": "json", "page_size": 0, "compress": false, "separator": ";"}`))
+}
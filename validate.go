@@ -0,0 +1,179 @@
+package strut
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/modfin/strut/schema"
+	"github.com/modfin/strut/swag"
+)
+
+// pathParamPattern matches the parameters of path templates, e.g. {id}
+var pathParamPattern = regexp.MustCompile(`\{([^}]+)\}`)
+
+// Validate checks the spec for mistakes consumers' generators fail on, e.g. missing or duplicate operationIds,
+// path parameters not matching the template and references to components or security schemes that don't exist.
+// Every problem found is returned, joined, e.g. for tests or to fail at startup
+func (s *Strut) Validate() error {
+	specMu.RLock()
+	defer specMu.RUnlock()
+
+	d := s.Definition
+	if _, err := json.Marshal(d); err != nil {
+		return fmt.Errorf("spec can't be encoded: %w", err)
+	}
+
+	var errs []error
+	operationIDs := map[string]string{} // operationId => the operation it was first seen on
+	templates := map[string]string{}    // path with anonymous parameters => the path it was first seen as
+	for _, path := range slices.Sorted(maps.Keys(d.Paths)) {
+		item := d.Paths[path]
+		template := pathParamPattern.ReplaceAllString(path, "{}")
+		if other, ok := templates[template]; ok {
+			errs = append(errs, fmt.Errorf("paths %s and %s are the same but for the names of their parameters", other, path))
+		} else {
+			templates[template] = path
+		}
+
+		for method, op := range item.All() {
+			name := method + " " + path
+			if op.OperationID == "" {
+				errs = append(errs, fmt.Errorf("%s: missing operationId", name))
+			} else if other, ok := operationIDs[op.OperationID]; ok {
+				errs = append(errs, fmt.Errorf("%s: operationId %s is used by %s as well", name, op.OperationID, other))
+			} else {
+				operationIDs[op.OperationID] = name
+			}
+			errs = append(errs, checkPathParams(d, name, path, append(append([]swag.Param{}, item.Parameters...), op.Parameters...))...)
+		}
+	}
+	if len(d.Webhooks) > 0 && strings.HasPrefix(d.OpenAPI, "3.0") {
+		errs = append(errs, fmt.Errorf("webhooks are part of OpenAPI 3.1, they are left out of specs of %s", d.OpenAPI))
+	}
+	errs = append(errs, checkRefs(d)...)
+	errs = append(errs, checkSecurity(d)...)
+	return errors.Join(errs...)
+}
+
+// checkPathParams checks that the parameters of the template are the path parameters declared, and vice versa
+func checkPathParams(d *swag.Definition, name string, path string, params []swag.Param) []error {
+	inTemplate := map[string]bool{}
+	for _, match := range pathParamPattern.FindAllStringSubmatch(path, -1) {
+		inTemplate[match[1]] = true
+	}
+
+	var errs []error
+	declared := map[string]bool{}
+	for _, param := range params {
+		if ref, ok := strings.CutPrefix(param.Ref, "#/components/parameters/"); ok && d.Components != nil && d.Components.Parameters[ref] != nil {
+			param = *d.Components.Parameters[ref]
+		}
+		if param.In != "path" {
+			continue
+		}
+		declared[param.Name] = true
+		if !inTemplate[param.Name] {
+			errs = append(errs, fmt.Errorf("%s: path parameter %s is not in the path", name, param.Name))
+		}
+	}
+	for _, match := range pathParamPattern.FindAllStringSubmatch(path, -1) {
+		if !declared[match[1]] {
+			errs = append(errs, fmt.Errorf("%s: path parameter %s is not declared", name, match[1]))
+		}
+	}
+	return errs
+}
+
+// checkRefs checks that every reference to a component, of schemas, responses and parameters, resolves
+func checkRefs(d *swag.Definition) []error {
+	components := d.Components
+	if components == nil {
+		components = &swag.Components{}
+	}
+	unresolved := map[string]bool{}
+	check := func(ref string, prefix string, exists func(name string) bool) {
+		if name, ok := strings.CutPrefix(ref, prefix); ok && !exists(name) {
+			unresolved[ref] = true
+		}
+	}
+	schemaExists := func(name string) bool { return components.Schemas[name] != nil }
+
+	d.EachSchema(func(s *schema.JSON) {
+		schema.Walk(s, func(s *schema.JSON) {
+			check(s.Ref, "#/components/schemas/", schemaExists)
+			if s.Discriminator != nil {
+				for _, ref := range s.Discriminator.Mapping {
+					check(ref, "#/components/schemas/", schemaExists)
+				}
+			}
+		})
+	})
+
+	for _, paths := range []map[string]*swag.Path{d.Paths, d.Webhooks} {
+		for _, item := range paths {
+			for _, op := range item.Operations() {
+				for _, param := range append(append([]swag.Param{}, item.Parameters...), op.Parameters...) {
+					check(param.Ref, "#/components/parameters/", func(name string) bool { return components.Parameters[name] != nil })
+				}
+				for _, res := range op.Responses {
+					if res == nil {
+						continue
+					}
+					check(res.Ref, "#/components/responses/", func(name string) bool { return components.Responses[name] != nil })
+				}
+			}
+		}
+	}
+
+	refs := make([]string, 0, len(unresolved))
+	for ref := range unresolved {
+		refs = append(refs, ref)
+	}
+	sort.Strings(refs)
+	errs := make([]error, len(refs))
+	for i, ref := range refs {
+		errs[i] = fmt.Errorf("reference %s does not resolve", ref)
+	}
+	return errs
+}
+
+// checkSecurity checks that the security schemes required by the API and its operations are declared
+func checkSecurity(d *swag.Definition) []error {
+	undeclared := map[string]bool{}
+	check := func(requirements []swag.SecurityRequirement) {
+		for _, requirement := range requirements {
+			for name := range requirement {
+				if d.Components == nil || d.Components.SecuritySchemes[name] == nil {
+					undeclared[name] = true
+				}
+			}
+		}
+	}
+	check(d.Security)
+	for _, paths := range []map[string]*swag.Path{d.Paths, d.Webhooks} {
+		for _, item := range paths {
+			for _, op := range item.Operations() {
+				if op.Security != nil {
+					check(*op.Security)
+				}
+			}
+		}
+	}
+
+	names := make([]string, 0, len(undeclared))
+	for name := range undeclared {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	errs := make([]error, len(names))
+	for i, name := range names {
+		errs[i] = fmt.Errorf("security scheme %s is not declared", name)
+	}
+	return errs
+}
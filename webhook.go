@@ -0,0 +1,41 @@
+package strut
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/modfin/strut/swag"
+)
+
+// Webhook documents a webhook the service sends, as method to the path of the subscriber with payload as its body,
+// in the webhooks section of the spec. Webhooks are part of OpenAPI 3.1, see WithOpenAPIVersion, and are left out
+// of specs of 3.0
+func (s *Strut) Webhook(name string, method string, path string, payload any, ops ...OpConfig) *Strut {
+	s.mustBeMutable()
+
+	uri := s.componentName(reflect.TypeOf(payload))
+	s.addComponent(uri, reflect.TypeOf(payload), s.schemaOf(payload))
+
+	op := &swag.Operation{
+		OperationID: name,
+		RequestBody: &swag.RequestBody{Required: true},
+		Responses: map[string]*swag.OpResponse{
+			"200": {Description: "The webhook was received"},
+		},
+	}
+	for _, opt := range ops {
+		opt(op)
+	}
+	op.RequestBody.Content = assignSchema(op.RequestBody.Content, s.contentType, "#/components/schemas/"+uri)
+
+	if s.Definition.Webhooks == nil {
+		s.Definition.Webhooks = map[string]*swag.Path{}
+	}
+	hook := s.Definition.Webhooks[name]
+	if hook == nil {
+		hook = &swag.Path{Description: fmt.Sprintf("Delivered to %s of the subscriber", path)}
+		s.Definition.Webhooks[name] = hook
+	}
+	setItemOperation(s, hook, "webhook", name, method, op)
+	return s
+}
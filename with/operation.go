@@ -5,6 +5,9 @@ import (
 	"github.com/modfin/strut"
 	"github.com/modfin/strut/schema"
 	"github.com/modfin/strut/swag"
+	"net/http"
+	"strings"
+	"time"
 )
 
 func Description(description string) strut.OpConfig {
@@ -41,6 +44,21 @@ func QueryParam[T any](name string, description string) strut.OpConfig {
 		})
 	}
 }
+
+// QueryParamDefault declares a query parameter with a default, which strut.QueryParam and strut.BindParams
+// return when the parameter is missing
+func QueryParamDefault[T any](name string, description string, def T) strut.OpConfig {
+	var ref T
+	s := schema.From(ref)
+	s.Default = def
+	return Param(swag.Param{
+		Name:        name,
+		In:          "query",
+		Description: description,
+		Schema:      s,
+	})
+}
+
 func PathParam[T any](name string, description string) strut.OpConfig {
 	var ref T
 	return func(op *swag.Operation) {
@@ -77,12 +95,188 @@ func HeaderParam[T any](name string, description string) strut.OpConfig {
 	}
 }
 
+// Prefer documents the Prefer header (RFC 7240), e.g. "return=minimal", read by the handler through strut.Prefer
+func Prefer(description string) strut.OpConfig {
+	return HeaderParam[string]("Prefer", description)
+}
+
+// ParamRef references a parameter added by Strut.AddParam, e.g. shared pagination parameters
+func ParamRef(name string) strut.OpConfig {
+	return Param(swag.Param{Ref: "#/components/parameters/" + name})
+}
+
+// ExclusiveParams declares query parameters of which at most one may be given, e.g. either id or email.
+// With Validate, requests giving more than one are answered with 400
+func ExclusiveParams(names ...string) strut.OpConfig {
+	return func(op *swag.Operation) {
+		op.ExclusiveParams = append(op.ExclusiveParams, names)
+	}
+}
+
+// QueryParamSchema declares a query parameter with a custom schema, e.g. with a pattern or format
+func QueryParamSchema(name string, description string, s *schema.JSON) strut.OpConfig {
+	return Param(swag.Param{
+		Name:        name,
+		In:          "query",
+		Description: description,
+		Schema:      s,
+	})
+}
+
+// PathParamSchema declares a path parameter with a custom schema, e.g. with a pattern or format
+func PathParamSchema(name string, description string, s *schema.JSON) strut.OpConfig {
+	return Param(swag.Param{
+		Name:        name,
+		In:          "path",
+		Description: description,
+		Schema:      s,
+		Required:    true,
+	})
+}
+
+// HeaderParamSchema declares a header parameter with a custom schema, e.g. with a pattern or format
+func HeaderParamSchema(name string, description string, s *schema.JSON) strut.OpConfig {
+	return Param(swag.Param{
+		Name:        name,
+		In:          "header",
+		Description: description,
+		Schema:      s,
+	})
+}
+
+// ExternalDocs links documentation of the operation outside the spec
+func ExternalDocs(url string, description string) strut.OpConfig {
+	return func(op *swag.Operation) {
+		op.ExternalDocs = &swag.ExternalDocs{URL: url, Description: description}
+	}
+}
+
+// Security requires the security scheme added by Strut.AddSecurityScheme, with the scopes, of the operation,
+// overriding the security of the API. Each call adds a requirement, any of which satisfies the security
+func Security(name string, scopes ...string) strut.OpConfig {
+	return func(op *swag.Operation) {
+		if op.Security == nil {
+			op.Security = &[]swag.SecurityRequirement{}
+		}
+		*op.Security = append(*op.Security, swag.SecurityRequirement{name: append([]string{}, scopes...)})
+	}
+}
+
+// NoSecurity declares that the operation requires no security, overriding the security of the API, e.g. for login
+func NoSecurity() strut.OpConfig {
+	return func(op *swag.Operation) {
+		op.Security = &[]swag.SecurityRequirement{}
+	}
+}
+
+// Server adds a server the operation is served from, overriding the servers of the path and the API,
+// e.g. for an endpoint of another service
+func Server(url string, description string) strut.OpConfig {
+	return func(op *swag.Operation) {
+		op.Servers = append(op.Servers, swag.Server{URL: url, Description: description})
+	}
+}
+
+// Audience tags the operation with the audiences it is for, e.g. public, partner or internal, see
+// Strut.SpecForAudience. Operations without an audience are for every audience
+func Audience(names ...string) strut.OpConfig {
+	return func(op *swag.Operation) {
+		op.Audiences = append(op.Audiences, names...)
+	}
+}
+
 func Deprecated() strut.OpConfig {
 	return func(op *swag.Operation) {
 		op.Deprecated = true
 	}
 }
 
+// Validate makes the request body be validated against its schema, responding 400 on violations
+func Validate() strut.OpConfig {
+	return func(op *swag.Operation) {
+		op.Validate = true
+	}
+}
+
+// ApplyDefaults sets fields absent from the request body to their json-default before calling the handler.
+// Fields given explicitly, even as their zero value, are kept
+func ApplyDefaults() strut.OpConfig {
+	return func(op *swag.Operation) {
+		op.ApplyDefaults = true
+	}
+}
+
+// StripWriteOnly removes fields marked json-write-only from the response body, so they are never echoed back
+func StripWriteOnly() strut.OpConfig {
+	return func(op *swag.Operation) {
+		op.StripWriteOnly = true
+	}
+}
+
+// ResponseContentTypes sets the media types the response body is documented as, instead of the default of the API,
+// e.g. application/xml alongside application/json. E handlers respond the one the Accept header prefers
+func ResponseContentTypes(mediaTypes ...string) strut.OpConfig {
+	return func(op *swag.Operation) {
+		op.ResponseContentTypes = append(op.ResponseContentTypes, mediaTypes...)
+	}
+}
+
+// RequestContentType sets the media type of the request body, e.g. multipart/form-data for file uploads.
+// Forms are decoded into the request type by the json names of its fields, files being read from
+// *multipart.FileHeader fields or by strut.FormFile
+func RequestContentType(mediaType string) strut.OpConfig {
+	return func(op *swag.Operation) {
+		op.RequestContentType = mediaType
+	}
+}
+
+// FormFile restricts the files uploaded as the field of a multipart/form-data request to maxBytes,
+// and to the content types if given, e.g. image/png or image/*. Larger files are answered with 413
+// and files of other content types with 415
+func FormFile(field string, maxBytes int64, contentTypes ...string) strut.OpConfig {
+	return func(op *swag.Operation) {
+		if op.RequestContentType == "" {
+			op.RequestContentType = "multipart/form-data"
+		}
+		if op.FileLimits == nil {
+			op.FileLimits = map[string]swag.FileLimit{}
+		}
+		op.FileLimits[field] = swag.FileLimit{MaxBytes: maxBytes, ContentTypes: contentTypes}
+
+		if op.RequestBody == nil {
+			op.RequestBody = &swag.RequestBody{}
+		}
+		if op.RequestBody.Content == nil {
+			op.RequestBody.Content = map[string]swag.MediaType{}
+		}
+		mediaType := op.RequestBody.Content[op.RequestContentType]
+		if mediaType.Encoding == nil {
+			mediaType.Encoding = map[string]swag.Encoding{}
+		}
+		encoding := mediaType.Encoding[field]
+		encoding.ContentType = strings.Join(contentTypes, ", ")
+		encoding.MaxSize = maxBytes
+		mediaType.Encoding[field] = encoding
+		op.RequestBody.Content[op.RequestContentType] = mediaType
+	}
+}
+
+// MaxRequestBytes limits the size of request bodies of the given media types, e.g. "application/json",
+// responding 413 when exceeded. Without media types the limit applies to requests not matching a more specific limit
+func MaxRequestBytes(limit int64, mediaTypes ...string) strut.OpConfig {
+	return func(op *swag.Operation) {
+		if op.MaxRequestBytes == nil {
+			op.MaxRequestBytes = map[string]int64{}
+		}
+		if len(mediaTypes) == 0 {
+			mediaTypes = []string{""}
+		}
+		for _, mediaType := range mediaTypes {
+			op.MaxRequestBytes[mediaType] = limit
+		}
+	}
+}
+
 func Tags(tags ...string) strut.OpConfig {
 	return func(op *swag.Operation) {
 		op.Tags = append(op.Tags, tags...)
@@ -104,6 +298,102 @@ func Response(statusCode int, res *swag.OpResponse) strut.OpConfig {
 	}
 }
 
+// DefaultResponse documents the response of the statuses not documented otherwise, under the default key,
+// e.g. a catch-all Error
+func DefaultResponse(res *swag.OpResponse) strut.OpConfig {
+	return func(op *swag.Operation) {
+		if op.Responses == nil {
+			op.Responses = map[string]*swag.OpResponse{}
+		}
+		op.Responses["default"] = res
+	}
+}
+
+// ResponseRef references a response added by Strut.AddResponse for the status code
+func ResponseRef(statusCode int, name string) strut.OpConfig {
+	return Response(statusCode, &swag.OpResponse{Ref: "#/components/responses/" + name})
+}
+
+// BatchResponse documents the 207 Multi-Status response written by strut.RespondBatchErrors
+func BatchResponse(description string) strut.OpConfig {
+	return Response(http.StatusMultiStatus, swag.ResponseOf[strut.BatchResponse](description))
+}
+
+// ResponseContentType documents the response body for the status code as binary of the media type,
+// e.g. application/pdf, instead of JSON. Pair it with strut.RespondStream
+func ResponseContentType(code int, mediaType string) strut.OpConfig {
+	return func(op *swag.Operation) {
+		if op.Responses == nil {
+			op.Responses = map[string]*swag.OpResponse{}
+		}
+
+		statusCode := fmt.Sprintf("%d", code)
+		if op.Responses[statusCode] == nil {
+			op.Responses[statusCode] = &swag.OpResponse{}
+		}
+		content := op.Responses[statusCode].Content
+		if content == nil {
+			content = map[string]swag.MediaType{}
+		}
+		media, ok := content[mediaType]
+		if def, isDefault := content["application/json"]; !ok && isDefault && def.Schema == nil {
+			media = def
+			delete(content, "application/json")
+		}
+		format := "binary"
+		media.Schema = &schema.JSON{Type: schema.String, Format: &format}
+		content[mediaType] = media
+		op.Responses[statusCode].Content = content
+	}
+}
+
+// ResponseHeader documents a header of the response for the status code, e.g. Location or X-Request-Id,
+// with the schema of schemaType. It only documents it, the handler sets it through strut.HTTPResponseWriter
+func ResponseHeader(code int, name string, schemaType any, description string) strut.OpConfig {
+	return func(op *swag.Operation) {
+		if op.Responses == nil {
+			op.Responses = map[string]*swag.OpResponse{}
+		}
+
+		statusCode := fmt.Sprintf("%d", code)
+		if op.Responses[statusCode] == nil {
+			op.Responses[statusCode] = &swag.OpResponse{}
+		}
+		if op.Responses[statusCode].Headers == nil {
+			op.Responses[statusCode].Headers = map[string]swag.Header{}
+		}
+		op.Responses[statusCode].Headers[name] = swag.Header{
+			Description: description,
+			Schema:      schema.From(schemaType),
+		}
+	}
+}
+
+// SuccessCode documents the response body under code rather than 200, e.g. 201 for operations creating resources,
+// and has handlers registered by e.g. strut.PostE respond it. With 204 the response is documented without a body
+func SuccessCode(code int) strut.OpConfig {
+	return func(op *swag.Operation) {
+		op.SuccessCode = code
+	}
+}
+
+// Responds declares statuses the handler responds besides the success code, e.g. 201 of strut.RespondCreated or
+// 404 of strut.RespondError, documented when the operation is registered. Errors are documented as strut.Error,
+// 204 and 304 without a body and other statuses like the success response
+func Responds(statusCodes ...int) strut.OpConfig {
+	return func(op *swag.Operation) {
+		op.Statuses = append(op.Statuses, statusCodes...)
+	}
+}
+
+// Cacheable sets the Cache-Control header of successful GET responses to max-age, unless set by the handler,
+// documenting it on the success response, see SuccessCode
+func Cacheable(maxAge time.Duration) strut.OpConfig {
+	return func(op *swag.Operation) {
+		op.CacheMaxAge = maxAge
+	}
+}
+
 func ResponseDescription(code int, description string) strut.OpConfig {
 	return func(op *swag.Operation) {
 		if op.Responses == nil {
@@ -126,3 +416,86 @@ func RequestDescription(description string) strut.OpConfig {
 		op.RequestBody.Description = description
 	}
 }
+
+// Example sets an example of the response body for the status code
+func Example(statusCode int, value any) strut.OpConfig {
+	return func(op *swag.Operation) {
+		if op.Responses == nil {
+			op.Responses = map[string]*swag.OpResponse{}
+		}
+
+		code := fmt.Sprintf("%d", statusCode)
+		if op.Responses[code] == nil {
+			op.Responses[code] = &swag.OpResponse{}
+		}
+		op.Responses[code].Content = withExample(op.Responses[code].Content, value)
+	}
+}
+
+// ResponseExamples sets named examples, e.g. per scenario, of the response body for the status code
+func ResponseExamples(statusCode int, examples map[string]swag.Example) strut.OpConfig {
+	return func(op *swag.Operation) {
+		if op.Responses == nil {
+			op.Responses = map[string]*swag.OpResponse{}
+		}
+
+		code := fmt.Sprintf("%d", statusCode)
+		if op.Responses[code] == nil {
+			op.Responses[code] = &swag.OpResponse{}
+		}
+		op.Responses[code].Content = withExamples(op.Responses[code].Content, examples)
+	}
+}
+
+// RequestExample sets an example of the request body
+func RequestExample(value any) strut.OpConfig {
+	return func(op *swag.Operation) {
+		if op.RequestBody == nil {
+			op.RequestBody = &swag.RequestBody{}
+		}
+		op.RequestBody.Content = withExample(op.RequestBody.Content, value)
+	}
+}
+
+// RequestExamples sets named examples, e.g. a minimal and a full one, of the request body
+func RequestExamples(examples map[string]swag.Example) strut.OpConfig {
+	return func(op *swag.Operation) {
+		if op.RequestBody == nil {
+			op.RequestBody = &swag.RequestBody{}
+		}
+		op.RequestBody.Content = withExamples(op.RequestBody.Content, examples)
+	}
+}
+
+// withExample sets the example on every media type of the content, defaulting to JSON. Named examples set before
+// are dropped, as media types can't have both
+func withExample(content map[string]swag.MediaType, value any) map[string]swag.MediaType {
+	if len(content) == 0 {
+		content = map[string]swag.MediaType{"application/json": {}}
+	}
+	for name, mediaType := range content {
+		mediaType.Example = value
+		mediaType.Examples = nil
+		content[name] = mediaType
+	}
+	return content
+}
+
+// withExamples adds the named examples to every media type of the content, defaulting to JSON. An example set
+// before is dropped, as media types can't have both
+func withExamples(content map[string]swag.MediaType, examples map[string]swag.Example) map[string]swag.MediaType {
+	if len(content) == 0 {
+		content = map[string]swag.MediaType{"application/json": {}}
+	}
+	for name, mediaType := range content {
+		if mediaType.Examples == nil {
+			mediaType.Examples = map[string]swag.Example{}
+		}
+		for key, example := range examples {
+			mediaType.Examples[key] = example
+		}
+		mediaType.Example = nil
+		content[name] = mediaType
+	}
+	return content
+}